@@ -0,0 +1,342 @@
+// Package bloom implements Bloom filters, which test whether an item is in
+// a set with no false negatives and a bounded rate of false positives.
+//
+// Space/Time Trade-offs in Hash Coding with Allowable Errors:
+// https://dl.acm.org/doi/10.1145/362686.362692
+//
+// Less Hashing, Same Performance: Building a Better Bloom Filter:
+// https://www.eecs.harvard.edu/~michaelm/postscripts/rsa2008.pdf
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*BloomFilter] = new(BloomFilter)
+	_ datasketch.CardinalityEstimator    = new(BloomFilter)
+	_ datasketch.Serializable            = new(BloomFilter)
+)
+
+// HashFunc hashes data to the two 64-bit hashes from which the K bit
+// positions of an item are derived.
+type HashFunc func(data []byte) (h1, h2 uint64)
+
+const (
+	// maxBits bounds the number of bits so that the serialized filter
+	// fits in memory.
+	maxBits = 1 << 34
+	// maxHashes bounds the number of hashes, reached by false positive
+	// rates around 1e-77.
+	maxHashes = 255
+)
+
+// BloomFilter data structure. Bits holds M bits, and every item sets the
+// K bits at positions h1+i*h2 mod M for i < K, where h1 and h2 are the
+// two halves of its 128-bit hash.
+type BloomFilter struct {
+	Bits []uint64
+	M    uint64
+	K    uint32
+
+	hasher
+}
+
+// Option configures a filter created by one of the New functions or
+// deserialized.
+type Option func(*BloomFilter)
+
+// WithHashFunc makes filters hash items with f instead of murmur3.Sum128.
+// Filters are only comparable when their items were hashed with the same
+// function.
+func WithHashFunc(f HashFunc) Option {
+	return func(b *BloomFilter) {
+		b.hash = f
+	}
+}
+
+// WithKey makes filters hash items with SipHash keyed with the secret k0
+// and k1, so an attacker who controls the items cannot choose ones that
+// are false positives. Filters are only comparable when built with the
+// same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed128(k0, k1))
+}
+
+// optimalSize returns the number of bits and hashes for which n items
+// give a false positive rate of fpRate.
+func optimalSize(n uint64, fpRate float64) (float64, float64) {
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	return m, k
+}
+
+// New returns a BloomFilter whose false positive rate is fpRate once n
+// items are added. It has -n*ln(fpRate)/ln(2)^2 bits and
+// -log2(fpRate) hashes, rounded.
+func New(n uint64, fpRate float64, opts ...Option) (*BloomFilter, error) {
+	if n == 0 {
+		return nil, ErrCapacity
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, ErrFPRate
+	}
+	m, k := optimalSize(n, fpRate)
+	if m > maxBits || k > maxHashes {
+		return nil, ErrCapacity
+	}
+	return NewWithSize(uint64(m), uint32(k), opts...)
+}
+
+// NewWithSize returns a BloomFilter of m bits setting k of them for every
+// item.
+func NewWithSize(m uint64, k uint32, opts ...Option) (*BloomFilter, error) {
+	if m == 0 || m > maxBits || k == 0 || k > maxHashes {
+		return nil, ErrSize
+	}
+	f := &BloomFilter{M: m, K: k, Bits: make([]uint64, (m+63)/64)}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
+}
+
+// Clear sets BloomFilter f back to its initial state.
+func (f *BloomFilter) Clear() {
+	for i := range f.Bits {
+		f.Bits[i] = 0
+	}
+}
+
+// hasher hashes items with the HashFunc set by WithHashFunc, or
+// murmur3.Sum128 if there is none.
+type hasher struct {
+	hash HashFunc
+}
+
+func (h hasher) sum128(data []byte) (uint64, uint64) {
+	if h.hash != nil {
+		return h.hash(data)
+	}
+	return murmur3.Sum128(data)
+}
+
+func (h hasher) sum128String(s string) (uint64, uint64) {
+	if h.hash != nil {
+		return h.hash(unsafe.Slice(unsafe.StringData(s), len(s)))
+	}
+	return murmur3.Sum128String(s, 0)
+}
+
+func (h hasher) sum128Uint64(v uint64) (uint64, uint64) {
+	if h.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return h.hash(b[:])
+	}
+	return murmur3.Sum128Uint64(v, 0)
+}
+
+// hasherOf returns the hasher configured by opts.
+func hasherOf(opts []Option) hasher {
+	var f BloomFilter
+	for _, opt := range opts {
+		opt(&f)
+	}
+	return f.hasher
+}
+
+// Add adds item to the set.
+func (f *BloomFilter) Add(item []byte) {
+	f.add(f.sum128(item))
+}
+
+// AddString adds the bytes of s to the set.
+func (f *BloomFilter) AddString(s string) {
+	f.add(f.sum128String(s))
+}
+
+// AddUint64 adds the little-endian encoding of v to the set.
+func (f *BloomFilter) AddUint64(v uint64) {
+	f.add(f.sum128Uint64(v))
+}
+
+// Test reports whether item may be in the set. It is certainly not if
+// Test returns false.
+func (f *BloomFilter) Test(item []byte) bool {
+	return f.test(f.sum128(item))
+}
+
+// TestString reports whether the bytes of s may be in the set.
+func (f *BloomFilter) TestString(s string) bool {
+	return f.test(f.sum128String(s))
+}
+
+// TestUint64 reports whether the little-endian encoding of v may be in
+// the set.
+func (f *BloomFilter) TestUint64(v uint64) bool {
+	return f.test(f.sum128Uint64(v))
+}
+
+// TestAndAdd adds item to the set and reports whether it may have been in
+// it before.
+func (f *BloomFilter) TestAndAdd(item []byte) bool {
+	return f.add(f.sum128(item))
+}
+
+// TestAndAddString adds the bytes of s to the set and reports whether
+// they may have been in it before.
+func (f *BloomFilter) TestAndAddString(s string) bool {
+	return f.add(f.sum128String(s))
+}
+
+// TestAndAddUint64 adds the little-endian encoding of v to the set and
+// reports whether it may have been in it before.
+func (f *BloomFilter) TestAndAddUint64(v uint64) bool {
+	return f.add(f.sum128Uint64(v))
+}
+
+// step returns h2 made nonzero modulo m: a multiple of m would give the
+// item one position instead of K.
+func step(h2, m uint64) uint64 {
+	if h2%m == 0 {
+		return h2 + 1
+	}
+	return h2
+}
+
+// add sets the bits of the item hashed to h1 and h2, and reports whether
+// they were all set already.
+func (f *BloomFilter) add(h1, h2 uint64) bool {
+	h2 = step(h2, f.M)
+	present := true
+	for i := uint32(0); i < f.K; i++ {
+		j := (h1 + uint64(i)*h2) % f.M
+		mask := uint64(1) << (j % 64)
+		if f.Bits[j/64]&mask == 0 {
+			present = false
+			f.Bits[j/64] |= mask
+		}
+	}
+	return present
+}
+
+func (f *BloomFilter) test(h1, h2 uint64) bool {
+	h2 = step(h2, f.M)
+	for i := uint32(0); i < f.K; i++ {
+		j := (h1 + uint64(i)*h2) % f.M
+		if f.Bits[j/64]&(1<<(j%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge takes another BloomFilter and combines it with BloomFilter f,
+// making f the union of both sets. It is the same as Union.
+func (f *BloomFilter) Merge(other *BloomFilter) error {
+	return f.Union(other)
+}
+
+// Union makes f the filter of the union of the sets of f and other, as if
+// all items had been added to f. Both must have the same M and K.
+func (f *BloomFilter) Union(other *BloomFilter) error {
+	if f.M != other.M || f.K != other.K {
+		return &SizeMismatchError{f.M, f.K, other.M, other.K}
+	}
+	for i, w := range other.Bits {
+		f.Bits[i] |= w
+	}
+	return nil
+}
+
+// Intersect makes f a filter of the intersection of the sets of f and
+// other. Both must have the same M and K. Its false positive rate may be
+// higher than that of a filter to which only the common items were
+// added.
+func (f *BloomFilter) Intersect(other *BloomFilter) error {
+	if f.M != other.M || f.K != other.K {
+		return &SizeMismatchError{f.M, f.K, other.M, other.K}
+	}
+	for i, w := range other.Bits {
+		f.Bits[i] &= w
+	}
+	return nil
+}
+
+// FillRatio returns the fraction of the bits that are set.
+func (f *BloomFilter) FillRatio() float64 {
+	var n int
+	for _, w := range f.Bits {
+		n += bits.OnesCount64(w)
+	}
+	return float64(n) / float64(f.M)
+}
+
+// FPRate returns the estimated false positive rate given the bits set.
+func (f *BloomFilter) FPRate() float64 {
+	return math.Pow(f.FillRatio(), float64(f.K))
+}
+
+// Count returns the estimated number of distinct items added, from the
+// fraction of the bits set. It is +Inf once all bits are set.
+func (f *BloomFilter) Count() float64 {
+	return -float64(f.M) / float64(f.K) * math.Log1p(-f.FillRatio())
+}
+
+// ByteSize returns the size of the BloomFilter f in bytes
+func (f *BloomFilter) ByteSize() int {
+	return 8 + 4 + 8*len(f.Bits)
+}
+
+// Serialize the BloomFilter f into bytes and store in the buffer: M, K,
+// then the bits in 64-bit little-endian words.
+func (f *BloomFilter) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer("BloomFilter", f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint64(buffer, f.M)
+	b.PutUint32(buffer[8:], f.K)
+	for i, w := range f.Bits {
+		b.PutUint64(buffer[12+8*i:], w)
+	}
+	return nil
+}
+
+// Deserialize reconstruct a BloomFilter from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*BloomFilter, error) {
+	const sketch = "BloomFilter"
+	if len(buffer) < 12 {
+		return nil, shortBuffer(sketch, 12, len(buffer))
+	}
+	b := binary.LittleEndian
+	m, k := b.Uint64(buffer), b.Uint32(buffer[8:])
+	if m == 0 || m > maxBits || k == 0 || k > maxHashes {
+		return nil, corrupt(sketch, fmt.Sprintf("size %d bits and %d "+
+			"hashes is out of range", m, k))
+	}
+	if need := 12 + 8*((m+63)/64); uint64(len(buffer)) < need {
+		return nil, shortBuffer(sketch, int(need), len(buffer))
+	}
+	f, err := NewWithSize(m, k, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range f.Bits {
+		f.Bits[i] = b.Uint64(buffer[12+8*i:])
+	}
+	if r := m % 64; r != 0 && f.Bits[len(f.Bits)-1]>>r != 0 {
+		return nil, corrupt(sketch, "bits beyond M are set")
+	}
+	return f, nil
+}
@@ -0,0 +1,217 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"testing"
+)
+
+func TestBloomFilterNew(t *testing.T) {
+	f, err := New(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.M != 9586 || f.K != 7 || len(f.Bits) != 150 {
+		t.Error(f.M, f.K, len(f.Bits))
+	}
+	if _, err := New(0, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	if _, err := New(1<<40, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	for _, p := range []float64{0, 1, math.NaN()} {
+		if _, err := New(1000, p); !errors.Is(err, ErrFPRate) {
+			t.Error(p, err)
+		}
+	}
+	if _, err := NewWithSize(0, 1); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+	if _, err := NewWithSize(64, 0); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+}
+
+func TestBloomFilterFPRate(t *testing.T) {
+	const n, p = 10000, 0.01
+	f, _ := New(n, p)
+	for i := uint64(0); i < n; i++ {
+		if f.TestAndAddUint64(i) {
+			// Early false positives are rare while the filter is sparse.
+			if i < n/10 {
+				t.Errorf("%d is a false positive", i)
+			}
+		}
+	}
+	for i := uint64(0); i < n; i++ {
+		if !f.TestUint64(i) {
+			t.Fatalf("%d is a false negative", i)
+		}
+	}
+	var fp int
+	for i := uint64(n); i < 11*n; i++ {
+		if f.TestUint64(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > 1.2*p || rate < 0.8*p {
+		t.Errorf("false positive rate %v", rate)
+	}
+	if r := f.FillRatio(); math.Abs(r-0.5) > 0.02 {
+		t.Error(r)
+	}
+	if r := f.FPRate(); math.Abs(r-p) > 0.2*p {
+		t.Error(r)
+	}
+	if c := f.Count(); math.Abs(c-n) > 0.02*n {
+		t.Error(c)
+	}
+}
+
+func TestBloomFilterTyped(t *testing.T) {
+	f, _ := New(100, 0.01)
+	f.Add([]byte("hello"))
+	if !f.TestString("hello") || !f.TestAndAddString("hello") {
+		t.Error("hello is not in the set")
+	}
+	if f.TestAndAdd([]byte("world")) || !f.Test([]byte("world")) {
+		t.Error("world was in the set")
+	}
+	f.AddUint64(42)
+	if !f.TestUint64(42) {
+		t.Error("42 is not in the set")
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		f.AddString("hello")
+		f.TestString("hello")
+		f.AddUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+	k, _ := New(100, 0.01, WithKey(1, 2))
+	k.AddString("hello")
+	k.AddUint64(42)
+	if !k.Test([]byte("hello")) || !k.TestUint64(42) {
+		t.Error("keyed filter lost items")
+	}
+	f.Clear()
+	if f.FillRatio() != 0 || f.TestString("hello") {
+		t.Error("Clear did not reset the BloomFilter")
+	}
+}
+
+func TestBloomFilterPositions(t *testing.T) {
+	// A second hash that is a multiple of M must still give the item K
+	// different bits.
+	for _, h2 := range []uint64{0, 1000, 1000 << 20} {
+		hash := func([]byte) (uint64, uint64) { return 7, h2 }
+		f, _ := NewWithSize(1000, 5, WithHashFunc(hash))
+		f.Add([]byte("a"))
+		var set int
+		for _, w := range f.Bits {
+			set += bits.OnesCount64(w)
+		}
+		if set != int(f.K) || !f.Test([]byte("a")) {
+			t.Errorf("h2 %d: %d bits set", h2, set)
+		}
+	}
+}
+
+func TestBloomFilterUnionIntersect(t *testing.T) {
+	a, _ := New(1000, 0.01)
+	b, _ := New(1000, 0.01)
+	for i := uint64(0); i < 500; i++ {
+		a.AddUint64(i)
+		b.AddUint64(i + 250)
+	}
+	u, _ := New(1000, 0.01)
+	u.Merge(a)
+	if err := u.Union(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Intersect(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 750; i++ {
+		if !u.TestUint64(i) {
+			t.Fatalf("%d is not in the union", i)
+		}
+		if i >= 250 && i < 500 && !a.TestUint64(i) {
+			t.Fatalf("%d is not in the intersection", i)
+		}
+	}
+	if c := u.Count(); math.Abs(c-750) > 30 {
+		t.Error(c)
+	}
+
+	c, _ := New(2000, 0.01)
+	var sizeErr *SizeMismatchError
+	if err := a.Union(c); !errors.As(err, &sizeErr) || sizeErr.OtherM != c.M {
+		t.Error(err)
+	}
+	if err := a.Intersect(c); !errors.Is(err, ErrSizeMismatch) {
+		t.Error(err)
+	}
+}
+
+func TestBloomFilterSerialization(t *testing.T) {
+	f, _ := NewWithSize(100, 3)
+	f.AddString("hello")
+	buf := make([]byte, f.ByteSize())
+	if err := f.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.M != f.M || g.K != f.K || !g.TestString("hello") {
+		t.Error("Did not get back the same BloomFilter")
+	}
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	bad := append([]byte(nil), buf...)
+	bad[len(bad)-1] = 0x80
+	if _, err := Deserialize(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad = append([]byte(nil), buf...)
+	bad[8] = 0
+	if _, err := Deserialize(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	b, _ := NewWithSize(100, 3)
+	b.AddString("hello")
+	buf := make([]byte, b.ByteSize())
+	b.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:12])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, b.ByteSize())
+		if err := b.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		b.AddString("hello")
+		if !b.TestString("hello") {
+			t.Error("hello is not in the set")
+		}
+	})
+}
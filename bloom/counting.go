@@ -0,0 +1,296 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*CountingBloomFilter] = new(CountingBloomFilter)
+	_ datasketch.CardinalityEstimator            = new(CountingBloomFilter)
+	_ datasketch.Serializable                    = new(CountingBloomFilter)
+)
+
+const (
+	// counterBits is the width of the counters of a CountingBloomFilter.
+	counterBits = 4
+	// maxCount is the value at which counters saturate.
+	maxCount = 1<<counterBits - 1
+	// countersPerWord is the number of counters packed in a uint64.
+	countersPerWord = 64 / counterBits
+)
+
+// CountingBloomFilter is a Bloom filter whose bits are replaced by 4-bit
+// counters, so items can be removed. Counters saturate at 15 and are
+// never decreased from then on, which keeps the filter free of false
+// negatives at the cost of some items no longer being removable.
+//
+// Summary Cache: A Scalable Wide-Area Web Cache Sharing Protocol:
+// https://pages.cs.wisc.edu/~jussara/papers/00ton.pdf
+type CountingBloomFilter struct {
+	// Counters holds M counters, 16 per word from the lowest bits up.
+	Counters []uint64
+	M        uint64
+	K        uint32
+
+	hasher
+}
+
+// NewCounting returns a CountingBloomFilter whose false positive rate is
+// fpRate once n items are added. It has as many counters as a BloomFilter
+// has bits, so it takes four times the memory.
+func NewCounting(n uint64, fpRate float64,
+	opts ...Option) (*CountingBloomFilter, error) {
+	if n == 0 {
+		return nil, ErrCapacity
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, ErrFPRate
+	}
+	m, k := optimalSize(n, fpRate)
+	if m > maxBits/counterBits || k > maxHashes {
+		return nil, ErrCapacity
+	}
+	return NewCountingWithSize(uint64(m), uint32(k), opts...)
+}
+
+// NewCountingWithSize returns a CountingBloomFilter of m counters
+// incrementing k of them for every item.
+func NewCountingWithSize(m uint64, k uint32,
+	opts ...Option) (*CountingBloomFilter, error) {
+	if m == 0 || m > maxBits/counterBits || k == 0 || k > maxHashes {
+		return nil, ErrSize
+	}
+	return &CountingBloomFilter{
+		Counters: make([]uint64, (m+countersPerWord-1)/countersPerWord),
+		M:        m,
+		K:        k,
+		hasher:   hasherOf(opts),
+	}, nil
+}
+
+// Clear sets CountingBloomFilter f back to its initial state.
+func (f *CountingBloomFilter) Clear() {
+	for i := range f.Counters {
+		f.Counters[i] = 0
+	}
+}
+
+// counter returns the value of counter j.
+func (f *CountingBloomFilter) counter(j uint64) uint64 {
+	return f.Counters[j/countersPerWord] >> (j % countersPerWord *
+		counterBits) & maxCount
+}
+
+// Add adds item to the set.
+func (f *CountingBloomFilter) Add(item []byte) {
+	f.add(f.sum128(item))
+}
+
+// AddString adds the bytes of s to the set.
+func (f *CountingBloomFilter) AddString(s string) {
+	f.add(f.sum128String(s))
+}
+
+// AddUint64 adds the little-endian encoding of v to the set.
+func (f *CountingBloomFilter) AddUint64(v uint64) {
+	f.add(f.sum128Uint64(v))
+}
+
+// Test reports whether item may be in the set. It is certainly not if
+// Test returns false.
+func (f *CountingBloomFilter) Test(item []byte) bool {
+	return f.test(f.sum128(item))
+}
+
+// TestString reports whether the bytes of s may be in the set.
+func (f *CountingBloomFilter) TestString(s string) bool {
+	return f.test(f.sum128String(s))
+}
+
+// TestUint64 reports whether the little-endian encoding of v may be in
+// the set.
+func (f *CountingBloomFilter) TestUint64(v uint64) bool {
+	return f.test(f.sum128Uint64(v))
+}
+
+// TestAndAdd adds item to the set and reports whether it may have been in
+// it before.
+func (f *CountingBloomFilter) TestAndAdd(item []byte) bool {
+	h1, h2 := f.sum128(item)
+	present := f.test(h1, h2)
+	f.add(h1, h2)
+	return present
+}
+
+// TestAndAddString adds the bytes of s to the set and reports whether
+// they may have been in it before.
+func (f *CountingBloomFilter) TestAndAddString(s string) bool {
+	h1, h2 := f.sum128String(s)
+	present := f.test(h1, h2)
+	f.add(h1, h2)
+	return present
+}
+
+// TestAndAddUint64 adds the little-endian encoding of v to the set and
+// reports whether it may have been in it before.
+func (f *CountingBloomFilter) TestAndAddUint64(v uint64) bool {
+	h1, h2 := f.sum128Uint64(v)
+	present := f.test(h1, h2)
+	f.add(h1, h2)
+	return present
+}
+
+// Remove removes item from the set, and reports whether it may have been
+// in it. Only items that were added may be removed: removing any other
+// item that is a false positive brings false negatives.
+func (f *CountingBloomFilter) Remove(item []byte) bool {
+	return f.remove(f.sum128(item))
+}
+
+// RemoveString removes the bytes of s from the set, and reports whether
+// they may have been in it.
+func (f *CountingBloomFilter) RemoveString(s string) bool {
+	return f.remove(f.sum128String(s))
+}
+
+// RemoveUint64 removes the little-endian encoding of v from the set, and
+// reports whether it may have been in it.
+func (f *CountingBloomFilter) RemoveUint64(v uint64) bool {
+	return f.remove(f.sum128Uint64(v))
+}
+
+func (f *CountingBloomFilter) add(h1, h2 uint64) {
+	h2 = step(h2, f.M)
+	for i := uint32(0); i < f.K; i++ {
+		j := (h1 + uint64(i)*h2) % f.M
+		if f.counter(j) < maxCount {
+			f.Counters[j/countersPerWord] += 1 << (j % countersPerWord *
+				counterBits)
+		}
+	}
+}
+
+func (f *CountingBloomFilter) test(h1, h2 uint64) bool {
+	h2 = step(h2, f.M)
+	for i := uint32(0); i < f.K; i++ {
+		if f.counter((h1+uint64(i)*h2)%f.M) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *CountingBloomFilter) remove(h1, h2 uint64) bool {
+	h2 = step(h2, f.M)
+	if !f.test(h1, h2) {
+		return false
+	}
+	for i := uint32(0); i < f.K; i++ {
+		j := (h1 + uint64(i)*h2) % f.M
+		if c := f.counter(j); c > 0 && c < maxCount {
+			f.Counters[j/countersPerWord] -= 1 << (j % countersPerWord *
+				counterBits)
+		}
+	}
+	return true
+}
+
+// Merge takes another CountingBloomFilter and combines it with f, making
+// f the union of both sets. Counters are added, saturating at 15. Both
+// must have the same M and K.
+func (f *CountingBloomFilter) Merge(other *CountingBloomFilter) error {
+	if f.M != other.M || f.K != other.K {
+		return &SizeMismatchError{f.M, f.K, other.M, other.K}
+	}
+	for j := uint64(0); j < f.M; j++ {
+		c := f.counter(j) + other.counter(j)
+		if c > maxCount {
+			c = maxCount
+		}
+		shift := j % countersPerWord * counterBits
+		w := &f.Counters[j/countersPerWord]
+		*w = *w&^(maxCount<<shift) | c<<shift
+	}
+	return nil
+}
+
+// BloomFilter returns the BloomFilter of the same set, setting the bits
+// of the counters that are not zero.
+func (f *CountingBloomFilter) BloomFilter() *BloomFilter {
+	b := &BloomFilter{M: f.M, K: f.K, Bits: make([]uint64, (f.M+63)/64),
+		hasher: f.hasher}
+	for j := uint64(0); j < f.M; j++ {
+		if f.counter(j) != 0 {
+			b.Bits[j/64] |= 1 << (j % 64)
+		}
+	}
+	return b
+}
+
+// Count returns the estimated number of distinct items in the set, from
+// the fraction of the counters that are not zero. It is +Inf once none
+// is.
+func (f *CountingBloomFilter) Count() float64 {
+	return f.BloomFilter().Count()
+}
+
+// FPRate returns the estimated false positive rate given the counters
+// that are not zero.
+func (f *CountingBloomFilter) FPRate() float64 {
+	return f.BloomFilter().FPRate()
+}
+
+// ByteSize returns the size of the CountingBloomFilter f in bytes
+func (f *CountingBloomFilter) ByteSize() int {
+	return 8 + 4 + 8*len(f.Counters)
+}
+
+// Serialize the CountingBloomFilter f into bytes and store in the buffer:
+// M, K, then the counters in 64-bit little-endian words.
+func (f *CountingBloomFilter) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer("CountingBloomFilter", f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint64(buffer, f.M)
+	b.PutUint32(buffer[8:], f.K)
+	for i, w := range f.Counters {
+		b.PutUint64(buffer[12+8*i:], w)
+	}
+	return nil
+}
+
+// DeserializeCounting reconstruct a CountingBloomFilter from the buffer
+func DeserializeCounting(buffer []byte,
+	opts ...Option) (*CountingBloomFilter, error) {
+	const sketch = "CountingBloomFilter"
+	if len(buffer) < 12 {
+		return nil, shortBuffer(sketch, 12, len(buffer))
+	}
+	b := binary.LittleEndian
+	m, k := b.Uint64(buffer), b.Uint32(buffer[8:])
+	if m == 0 || m > maxBits/counterBits || k == 0 || k > maxHashes {
+		return nil, corrupt(sketch, fmt.Sprintf("size %d counters and %d "+
+			"hashes is out of range", m, k))
+	}
+	words := (m + countersPerWord - 1) / countersPerWord
+	if need := 12 + 8*words; uint64(len(buffer)) < need {
+		return nil, shortBuffer(sketch, int(need), len(buffer))
+	}
+	f, err := NewCountingWithSize(m, k, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range f.Counters {
+		f.Counters[i] = b.Uint64(buffer[12+8*i:])
+	}
+	if r := m % countersPerWord; r != 0 &&
+		f.Counters[len(f.Counters)-1]>>(r*counterBits) != 0 {
+		return nil, corrupt(sketch, "counters beyond M are set")
+	}
+	return f, nil
+}
@@ -0,0 +1,196 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCountingBloomFilterNew(t *testing.T) {
+	f, err := NewCounting(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.M != 9586 || f.K != 7 || len(f.Counters) != 600 {
+		t.Error(f.M, f.K, len(f.Counters))
+	}
+	if _, err := NewCounting(0, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	if _, err := NewCounting(1<<35, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	if _, err := NewCounting(1000, 1); !errors.Is(err, ErrFPRate) {
+		t.Error(err)
+	}
+	if _, err := NewCountingWithSize(maxBits, 1); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+}
+
+func TestCountingBloomFilterRemove(t *testing.T) {
+	const n, p = 10000, 0.01
+	f, _ := NewCounting(n, p)
+	for i := uint64(0); i < 2*n; i++ {
+		f.AddUint64(i)
+	}
+	for i := uint64(n); i < 2*n; i++ {
+		if !f.RemoveUint64(i) {
+			t.Fatalf("%d was not in the set", i)
+		}
+	}
+	for i := uint64(0); i < n; i++ {
+		if !f.TestUint64(i) {
+			t.Fatalf("%d is a false negative", i)
+		}
+	}
+	var fp int
+	for i := uint64(n); i < 11*n; i++ {
+		if f.TestUint64(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > 1.2*p {
+		t.Errorf("false positive rate %v", rate)
+	}
+	if c := f.Count(); math.Abs(c-n) > 0.02*n {
+		t.Error(c)
+	}
+	if r := f.FPRate(); math.Abs(r-p) > 0.2*p {
+		t.Error(r)
+	}
+}
+
+func TestCountingBloomFilterSaturation(t *testing.T) {
+	f, _ := NewCountingWithSize(16, 1)
+	for i := 0; i < 20; i++ {
+		f.AddString("hello")
+	}
+	for i := 0; i < 20; i++ {
+		if !f.RemoveString("hello") {
+			t.Fatal("saturated counter was decremented")
+		}
+	}
+	f.Clear()
+	if f.RemoveString("hello") || f.TestString("hello") {
+		t.Error("Clear did not reset the CountingBloomFilter")
+	}
+	f.Add([]byte("hello"))
+	if !f.TestAndAdd([]byte("hello")) || !f.Remove([]byte("hello")) ||
+		!f.Test([]byte("hello")) {
+		t.Error("hello was added twice")
+	}
+	if !f.Remove([]byte("hello")) || f.Test([]byte("hello")) {
+		t.Error("hello was not removed")
+	}
+}
+
+func TestCountingBloomFilterPositions(t *testing.T) {
+	// A second hash that is a multiple of M must still give the item K
+	// different counters.
+	for _, h2 := range []uint64{0, 1000, 1000 << 20} {
+		hash := func([]byte) (uint64, uint64) { return 7, h2 }
+		f, _ := NewCountingWithSize(1000, 5, WithHashFunc(hash))
+		f.Add([]byte("a"))
+		var set int
+		for j := uint64(0); j < f.M; j++ {
+			if f.counter(j) != 0 {
+				set++
+			}
+		}
+		if set != int(f.K) || !f.Remove([]byte("a")) || f.Test([]byte("a")) {
+			t.Errorf("h2 %d: %d counters set", h2, set)
+		}
+	}
+}
+
+func TestCountingBloomFilterMerge(t *testing.T) {
+	a, _ := NewCounting(1000, 0.01)
+	b, _ := NewCounting(1000, 0.01)
+	for i := uint64(0); i < 100; i++ {
+		a.AddUint64(i)
+		b.AddUint64(i + 50)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 150; i++ {
+		if !a.TestUint64(i) {
+			t.Fatalf("%d is a false negative", i)
+		}
+	}
+	for i := uint64(50); i < 100; i++ {
+		a.RemoveUint64(i)
+	}
+	for i := uint64(0); i < 150; i++ {
+		if !a.TestUint64(i) {
+			t.Fatalf("%d was removed once but added twice", i)
+		}
+	}
+	bf := a.BloomFilter()
+	if math.Abs(bf.Count()-150) > 5 || !bf.TestUint64(42) {
+		t.Error(bf.Count())
+	}
+	c, _ := NewCounting(100, 0.01)
+	if err := a.Merge(c); !errors.Is(err, ErrSizeMismatch) {
+		t.Error(err)
+	}
+}
+
+func TestCountingBloomFilterSerialization(t *testing.T) {
+	f, _ := NewCounting(100, 0.01, WithKey(1, 2))
+	f.AddString("hello")
+	buf := make([]byte, f.ByteSize())
+	if err := f.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeCounting(buf, WithKey(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.M != f.M || d.K != f.K || !d.TestString("hello") ||
+		!d.RemoveString("hello") || d.TestString("hello") {
+		t.Error("Did not get back the same CountingBloomFilter")
+	}
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := DeserializeCounting(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[len(buf)-1] = 0xff
+	if _, err := DeserializeCounting(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[8] = 0
+	if _, err := DeserializeCounting(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserializeCounting(f *testing.F) {
+	c, _ := NewCountingWithSize(20, 2)
+	c.AddString("hello")
+	buf := make([]byte, c.ByteSize())
+	c.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := DeserializeCounting(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, c.ByteSize())
+		if err := c.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		c.AddString("hello")
+		if !c.RemoveString("hello") {
+			t.Error("hello was not in the set")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrCapacity is returned when the number of items is 0, or so large
+	// that the filter would not fit in memory.
+	ErrCapacity = errors.New("bloom: number of items is out of range")
+	// ErrFPRate is returned when the false positive rate is not between 0
+	// and 1.
+	ErrFPRate = errors.New("bloom: false positive rate must be between 0 " +
+		"and 1")
+	// ErrSize is returned when the number of bits or hashes is 0 or too
+	// large.
+	ErrSize = errors.New("bloom: bits and hashes must be positive")
+	// ErrSizeMismatch is returned when filters of different sizes are
+	// combined. It is wrapped by SizeMismatchError.
+	ErrSizeMismatch = errors.New("bloom: sizes do not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// SizeMismatchError reports the sizes of two filters that cannot be
+// combined.
+type SizeMismatchError struct {
+	M      uint64
+	K      uint32
+	OtherM uint64
+	OtherK uint32
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("bloom: sizes do not match: %d bits and %d hashes "+
+		"!= %d bits and %d hashes", e.M, e.K, e.OtherM, e.OtherK)
+}
+
+func (e *SizeMismatchError) Unwrap() error { return ErrSizeMismatch }
+
+func shortBuffer(sketch string, need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: sketch, Need: need, Have: have}
+}
+
+func corrupt(sketch, reason string) error {
+	return &datasketch.CorruptError{Sketch: sketch, Reason: reason}
+}
@@ -0,0 +1,269 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.CardinalityEstimator = new(ScalableBloomFilter)
+	_ datasketch.Serializable         = new(ScalableBloomFilter)
+)
+
+const (
+	// growth is the ratio of the capacities of successive filters of a
+	// ScalableBloomFilter.
+	growth = 2
+	// tightening is the ratio of the false positive rates of successive
+	// filters of a ScalableBloomFilter.
+	tightening = 0.9
+	// maxFilters bounds the number of filters of a ScalableBloomFilter,
+	// which stop growing long before because of maxBits.
+	maxFilters = 64
+)
+
+// ScalableBloomFilter is a Bloom filter for an unknown number of items.
+// It starts as a single BloomFilter for Capacity items and, every time
+// the last one is full, adds a filter for twice as many items with a
+// false positive rate 0.9 times as high. The false positive rate of the
+// whole stays below FPRate, while the size stays proportional to the
+// number of items, until the next filter would take more than 2^34 bits:
+// about a billion items at a rate of 1%. The filters then stop growing,
+// further items fill the last one, and the false positive rate rises
+// above FPRate towards 1.
+//
+// Scalable Bloom Filters:
+// https://gsd.di.uminho.pt/members/cbm/ps/dbloom.pdf
+type ScalableBloomFilter struct {
+	Filters  []*BloomFilter
+	Capacity uint64
+	FPRate   float64
+	// N is the number of items added that were not already in the set.
+	N uint64
+
+	hasher
+	opts []Option
+}
+
+// NewScalable returns a ScalableBloomFilter whose first filter holds n
+// items, and whose false positive rate stays below fpRate.
+func NewScalable(n uint64, fpRate float64,
+	opts ...Option) (*ScalableBloomFilter, error) {
+	f := &ScalableBloomFilter{Capacity: n, FPRate: fpRate,
+		hasher: hasherOf(opts), opts: opts}
+	first, err := f.newFilter(0)
+	if err != nil {
+		return nil, err
+	}
+	f.Filters = []*BloomFilter{first}
+	return f, nil
+}
+
+// newFilter returns filter i, for Capacity*2^i items with a false
+// positive rate of FPRate*(1-0.9)*0.9^i, so that the rates of all
+// filters sum to FPRate.
+func (f *ScalableBloomFilter) newFilter(i int) (*BloomFilter, error) {
+	m, k, err := f.filterSize(i)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithSize(m, k, f.opts...)
+}
+
+// filterSize returns the number of bits and hashes of filter i.
+func (f *ScalableBloomFilter) filterSize(i int) (uint64, uint32, error) {
+	if f.Capacity == 0 || i >= maxFilters ||
+		f.Capacity > math.MaxUint64>>i {
+		return 0, 0, ErrCapacity
+	}
+	if !(f.FPRate > 0 && f.FPRate < 1) {
+		return 0, 0, ErrFPRate
+	}
+	m, k := optimalSize(f.Capacity<<i,
+		f.FPRate*(1-tightening)*math.Pow(tightening, float64(i)))
+	if m > maxBits || k > maxHashes {
+		return 0, 0, ErrCapacity
+	}
+	return uint64(m), uint32(k), nil
+}
+
+// full returns the number of items that fill all filters.
+func (f *ScalableBloomFilter) full() uint64 {
+	return f.Capacity * (1<<len(f.Filters) - 1)
+}
+
+// Clear sets ScalableBloomFilter f back to its initial state.
+func (f *ScalableBloomFilter) Clear() {
+	f.Filters = f.Filters[:1]
+	f.Filters[0].Clear()
+	f.N = 0
+}
+
+// Add adds item to the set.
+func (f *ScalableBloomFilter) Add(item []byte) {
+	f.add(f.sum128(item))
+}
+
+// AddString adds the bytes of s to the set.
+func (f *ScalableBloomFilter) AddString(s string) {
+	f.add(f.sum128String(s))
+}
+
+// AddUint64 adds the little-endian encoding of v to the set.
+func (f *ScalableBloomFilter) AddUint64(v uint64) {
+	f.add(f.sum128Uint64(v))
+}
+
+// Test reports whether item may be in the set. It is certainly not if
+// Test returns false.
+func (f *ScalableBloomFilter) Test(item []byte) bool {
+	return f.test(f.sum128(item))
+}
+
+// TestString reports whether the bytes of s may be in the set.
+func (f *ScalableBloomFilter) TestString(s string) bool {
+	return f.test(f.sum128String(s))
+}
+
+// TestUint64 reports whether the little-endian encoding of v may be in
+// the set.
+func (f *ScalableBloomFilter) TestUint64(v uint64) bool {
+	return f.test(f.sum128Uint64(v))
+}
+
+// TestAndAdd adds item to the set and reports whether it may have been in
+// it before.
+func (f *ScalableBloomFilter) TestAndAdd(item []byte) bool {
+	return f.add(f.sum128(item))
+}
+
+// TestAndAddString adds the bytes of s to the set and reports whether
+// they may have been in it before.
+func (f *ScalableBloomFilter) TestAndAddString(s string) bool {
+	return f.add(f.sum128String(s))
+}
+
+// TestAndAddUint64 adds the little-endian encoding of v to the set and
+// reports whether it may have been in it before.
+func (f *ScalableBloomFilter) TestAndAddUint64(v uint64) bool {
+	return f.add(f.sum128Uint64(v))
+}
+
+// add adds the item hashed to h1 and h2 to the last filter unless it may
+// already be in the set, which it reports. Items found in the set are not
+// added again so that they do not fill the filters.
+func (f *ScalableBloomFilter) add(h1, h2 uint64) bool {
+	if f.test(h1, h2) {
+		return true
+	}
+	if f.N >= f.full() {
+		// If the next filter is too large, keep filling the last one,
+		// beyond the false positive rate it was sized for.
+		if next, err := f.newFilter(len(f.Filters)); err == nil {
+			f.Filters = append(f.Filters, next)
+		}
+	}
+	f.Filters[len(f.Filters)-1].add(h1, h2)
+	f.N++
+	return false
+}
+
+func (f *ScalableBloomFilter) test(h1, h2 uint64) bool {
+	for _, b := range f.Filters {
+		if b.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of items added that were not already in the
+// set, a slight underestimate of the number of distinct items because of
+// false positives.
+func (f *ScalableBloomFilter) Count() float64 {
+	return float64(f.N)
+}
+
+// ByteSize returns the size of the ScalableBloomFilter f in bytes
+func (f *ScalableBloomFilter) ByteSize() int {
+	size := 8 + 8 + 8 + 1
+	for _, b := range f.Filters {
+		size += b.ByteSize()
+	}
+	return size
+}
+
+// Serialize the ScalableBloomFilter f into bytes and store in the buffer:
+// Capacity, FPRate, N, the number of filters, then each filter.
+func (f *ScalableBloomFilter) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer("ScalableBloomFilter", f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint64(buffer, f.Capacity)
+	b.PutUint64(buffer[8:], math.Float64bits(f.FPRate))
+	b.PutUint64(buffer[16:], f.N)
+	buffer[24] = uint8(len(f.Filters))
+	offset := 25
+	for _, filter := range f.Filters {
+		if err := filter.Serialize(buffer[offset:]); err != nil {
+			return err
+		}
+		offset += filter.ByteSize()
+	}
+	return nil
+}
+
+// DeserializeScalable reconstruct a ScalableBloomFilter from the buffer
+func DeserializeScalable(buffer []byte,
+	opts ...Option) (*ScalableBloomFilter, error) {
+	const sketch = "ScalableBloomFilter"
+	if len(buffer) < 25 {
+		return nil, shortBuffer(sketch, 25, len(buffer))
+	}
+	b := binary.LittleEndian
+	f := &ScalableBloomFilter{
+		Capacity: b.Uint64(buffer),
+		FPRate:   math.Float64frombits(b.Uint64(buffer[8:])),
+		N:        b.Uint64(buffer[16:]),
+		hasher:   hasherOf(opts),
+		opts:     opts,
+	}
+	numFilters := int(buffer[24])
+	if numFilters == 0 || numFilters > maxFilters {
+		return nil, corrupt(sketch, fmt.Sprintf("%d filters is out of range",
+			numFilters))
+	}
+	offset := 25
+	for i := 0; i < numFilters; i++ {
+		m, k, err := f.filterSize(i)
+		if err != nil {
+			return nil, corrupt(sketch, fmt.Sprintf("filter %d: %v", i, err))
+		}
+		filter, err := Deserialize(buffer[offset:], opts...)
+		if err != nil {
+			var short *datasketch.ShortBufferError
+			if errors.As(err, &short) {
+				return nil, shortBuffer(sketch, offset+short.Need,
+					len(buffer))
+			}
+			return nil, err
+		}
+		if filter.M != m || filter.K != k {
+			return nil, corrupt(sketch, fmt.Sprintf("filter %d has %d bits "+
+				"and %d hashes, not %d and %d", i, filter.M, filter.K, m, k))
+		}
+		f.Filters = append(f.Filters, filter)
+		offset += filter.ByteSize()
+	}
+	if f.N < f.full()-f.Capacity<<(numFilters-1) {
+		return nil, corrupt(sketch, fmt.Sprintf("%d items do not fill %d "+
+			"filters", f.N, numFilters-1))
+	}
+	return f, nil
+}
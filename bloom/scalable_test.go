@@ -0,0 +1,130 @@
+package bloom
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+func TestScalableBloomFilterNew(t *testing.T) {
+	f, err := NewScalable(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Filters) != 1 || f.Filters[0].M != 14378 || f.Filters[0].K != 10 {
+		t.Error(len(f.Filters), f.Filters[0].M, f.Filters[0].K)
+	}
+	if _, err := NewScalable(0, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	if _, err := NewScalable(1000, 0); !errors.Is(err, ErrFPRate) {
+		t.Error(err)
+	}
+}
+
+func TestScalableBloomFilterGrowth(t *testing.T) {
+	const n, p = 100000, 0.01
+	f, _ := NewScalable(1000, p)
+	for i := uint64(0); i < n; i++ {
+		f.AddUint64(i)
+	}
+	// 1000 * (2^7 - 1) items fill 7 filters.
+	if len(f.Filters) != 7 {
+		t.Error(len(f.Filters))
+	}
+	for i := uint64(0); i < n; i++ {
+		if !f.TestUint64(i) {
+			t.Fatalf("%d is a false negative", i)
+		}
+	}
+	var fp int
+	for i := uint64(n); i < 11*n; i++ {
+		if f.TestUint64(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > p {
+		t.Errorf("false positive rate %v", rate)
+	}
+	if c := f.Count(); c > n || c < n-float64(fp)/10 {
+		t.Error(c)
+	}
+	f.Clear()
+	if len(f.Filters) != 1 || f.N != 0 || f.TestUint64(1) {
+		t.Error("Clear did not reset the ScalableBloomFilter")
+	}
+}
+
+func TestScalableBloomFilterTyped(t *testing.T) {
+	f, _ := NewScalable(10, 0.01, WithKey(1, 2))
+	f.Add([]byte("hello"))
+	if !f.TestString("hello") || !f.TestAndAddString("hello") ||
+		!f.TestAndAdd([]byte("hello")) {
+		t.Error("hello is not in the set")
+	}
+	if f.TestAndAddUint64(42) || !f.Test([]byte{42, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Error("42 was in the set")
+	}
+	if f.N != 2 {
+		t.Error(f.N)
+	}
+}
+
+func TestScalableBloomFilterSerialization(t *testing.T) {
+	f, _ := NewScalable(10, 0.01)
+	for i := uint64(0); i < 100; i++ {
+		f.AddUint64(i)
+	}
+	buf := make([]byte, f.ByteSize())
+	if err := f.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeScalable(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.N != f.N || len(d.Filters) != len(f.Filters) || !d.TestUint64(99) {
+		t.Error("Did not get back the same ScalableBloomFilter")
+	}
+	d.AddUint64(100)
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	var short *datasketch.ShortBufferError
+	if _, err := DeserializeScalable(buf[:len(buf)-1]); !errors.As(err,
+		&short) || short.Need != len(buf) {
+		t.Error(err)
+	}
+	buf[16] = 0
+	if _, err := DeserializeScalable(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[16] = byte(f.N)
+	buf[25] = 1
+	if _, err := DeserializeScalable(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserializeScalable(f *testing.F) {
+	s, _ := NewScalable(2, 0.1)
+	for i := uint64(0); i < 10; i++ {
+		s.AddUint64(i)
+	}
+	buf := make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := DeserializeScalable(data)
+		if err != nil {
+			return
+		}
+		s.AddString("hello")
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package bloom
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(BloomFilter)
+	_ driver.Valuer = new(BloomFilter)
+	_ sql.Scanner   = new(CountingBloomFilter)
+	_ driver.Valuer = new(CountingBloomFilter)
+	_ sql.Scanner   = new(ScalableBloomFilter)
+	_ driver.Valuer = new(ScalableBloomFilter)
+)
+
+// Value implements driver.Valuer so a BloomFilter can be stored in a
+// binary (BYTEA/BLOB) column. The stored value is the serialized filter
+// preceded by a tag identifying it as a BloomFilter.
+func (f *BloomFilter) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.BloomFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring a BloomFilter written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on f, if any, is kept.
+func (f *BloomFilter) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.BloomFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(f.hash))
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
+
+// Value implements driver.Valuer so a CountingBloomFilter can be stored in
+// a binary (BYTEA/BLOB) column.
+func (f *CountingBloomFilter) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.CountingBloomFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring a CountingBloomFilter written by
+// Value. The hash function set on f, if any, is kept.
+func (f *CountingBloomFilter) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.CountingBloomFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := DeserializeCounting(buffer, WithHashFunc(f.hash))
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
+
+// Value implements driver.Valuer so a ScalableBloomFilter can be stored in
+// a binary (BYTEA/BLOB) column.
+func (f *ScalableBloomFilter) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.ScalableBloomFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring a ScalableBloomFilter written by
+// Value. The options f was created with, if any, are kept.
+func (f *ScalableBloomFilter) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.ScalableBloomFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := DeserializeScalable(buffer, f.opts...)
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
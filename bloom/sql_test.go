@@ -0,0 +1,80 @@
+package bloom
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestBloomFilterValueScan(t *testing.T) {
+	f, _ := New(100, 0.01)
+	f.AddString("hello")
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d BloomFilter
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.M != f.M || d.K != f.K || !d.TestString("hello") {
+		t.Error("Did not get back the same BloomFilter")
+	}
+}
+
+func TestBloomFilterScanError(t *testing.T) {
+	var c BloomFilter
+	if err := c.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	h, _ := hyperloglog.New(4)
+	v, _ := h.Value()
+	if err := c.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
+
+func TestCountingBloomFilterValueScan(t *testing.T) {
+	f, _ := NewCounting(100, 0.01)
+	f.AddString("hello")
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d CountingBloomFilter
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.M != f.M || d.K != f.K || !d.RemoveString("hello") {
+		t.Error("Did not get back the same CountingBloomFilter")
+	}
+	b, _ := New(100, 0.01)
+	v, _ = b.Value()
+	if err := d.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
+
+func TestScalableBloomFilterValueScan(t *testing.T) {
+	f, _ := NewScalable(10, 0.01)
+	for i := uint64(0); i < 100; i++ {
+		f.AddUint64(i)
+	}
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d ScalableBloomFilter
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.N != f.N || len(d.Filters) != len(f.Filters) || !d.TestUint64(42) {
+		t.Error("Did not get back the same ScalableBloomFilter")
+	}
+	b, _ := New(100, 0.01)
+	v, _ = b.Value()
+	if err := d.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
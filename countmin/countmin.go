@@ -0,0 +1,330 @@
+// Package countmin implements the Count-Min sketch, a probabilistic data
+// structure for estimating the frequencies of items in a stream.
+//
+// An Improved Data Stream Summary: The Count-Min Sketch and its
+// Applications:
+// http://dimacs.rutgers.edu/~graham/pubs/papers/cm-full.pdf
+package countmin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*CountMin] = new(CountMin)
+	_ datasketch.Serializable         = new(CountMin)
+)
+
+// HashFunc hashes data to the two 64-bit hashes from which the row
+// hashes of a CountMin are derived.
+type HashFunc func(data []byte) (h1, h2 uint64)
+
+// CountMin data structure. Counts holds D rows of W counters, row after
+// row. N is the sum of all counts added. Counters and N saturate at
+// math.MaxUint64 instead of wrapping around, so estimates are never below
+// the true frequencies.
+type CountMin struct {
+	Counts       []uint64
+	W, D         uint32
+	N            uint64
+	Conservative bool
+
+	hash HashFunc
+}
+
+// Option configures a CountMin created by New or Deserialize.
+type Option func(*CountMin)
+
+// WithConservativeUpdate makes Add raise only the counters of an item
+// that are below its new estimate. Estimates are never larger than
+// without it, and usually much closer to the true frequencies, but the
+// counters are no longer sums of counts: Merge still gives a valid bound,
+// while subtracting the counters of one sketch from another, to remove a
+// stream, gives estimates that may fall below the true frequencies.
+func WithConservativeUpdate() Option {
+	return func(c *CountMin) {
+		c.Conservative = true
+	}
+}
+
+// WithHashFunc makes CountMin hash items with f instead of
+// murmur3.Sum128. CountMins are only comparable when their items were
+// hashed with the same function.
+func WithHashFunc(f HashFunc) Option {
+	return func(c *CountMin) {
+		c.hash = f
+	}
+}
+
+// WithKey makes CountMin hash items with SipHash keyed with the secret k0
+// and k1, so an attacker who controls the items cannot make them share
+// counters. CountMins are only comparable when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed128(k0, k1))
+}
+
+// New returns a CountMin whose estimates exceed the true frequencies by
+// at most eps times the sum of all counts, with probability 1-delta. It has
+// ceil(e/eps) counters in each of ceil(ln(1/delta)) rows, and New returns
+// ErrSize if they would not fit in memory.
+func New(eps, delta float64, opts ...Option) (*CountMin, error) {
+	if !(eps > 0 && eps < 1) {
+		return nil, ErrEpsilon
+	}
+	if !(delta > 0 && delta < 1) {
+		return nil, ErrDelta
+	}
+	w := math.Ceil(math.E / eps)
+	d := math.Ceil(math.Log(1 / delta))
+	if w*d > maxCounters {
+		return nil, ErrSize
+	}
+	return NewWithSize(uint32(w), uint32(d), opts...)
+}
+
+// maxCounters bounds the number of counters so that the serialized
+// CountMin fits in memory.
+const maxCounters = 1 << 28
+
+// NewWithSize returns a CountMin with d rows of w counters.
+func NewWithSize(w, d uint32, opts ...Option) (*CountMin, error) {
+	if w == 0 || d == 0 || uint64(w)*uint64(d) > maxCounters {
+		return nil, ErrSize
+	}
+	c := &CountMin{W: w, D: d}
+	c.Counts = make([]uint64, int(w)*int(d))
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Clear sets CountMin c back to its initial state.
+func (c *CountMin) Clear() {
+	for i := range c.Counts {
+		c.Counts[i] = 0
+	}
+	c.N = 0
+}
+
+func (c *CountMin) sum128(data []byte) (uint64, uint64) {
+	if c.hash != nil {
+		return c.hash(data)
+	}
+	return murmur3.Sum128(data)
+}
+
+// Add adds count occurrences of item.
+func (c *CountMin) Add(item []byte, count uint64) {
+	h1, h2 := c.sum128(item)
+	c.add(h1, h2, count)
+}
+
+// AddString adds count occurrences of the bytes of s.
+func (c *CountMin) AddString(s string, count uint64) {
+	if c.hash != nil {
+		c.Add(unsafe.Slice(unsafe.StringData(s), len(s)), count)
+		return
+	}
+	h1, h2 := murmur3.Sum128String(s, 0)
+	c.add(h1, h2, count)
+}
+
+// AddUint64 adds count occurrences of the little-endian encoding of v.
+func (c *CountMin) AddUint64(v uint64, count uint64) {
+	h1, h2 := c.sum128Uint64(v)
+	c.add(h1, h2, count)
+}
+
+func (c *CountMin) sum128Uint64(v uint64) (uint64, uint64) {
+	if c.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return c.hash(b[:])
+	}
+	return murmur3.Sum128Uint64(v, 0)
+}
+
+// index returns the counter of row i for the item hashed to h1 and h2,
+// combining them by double hashing. h2 must have gone through step.
+func (c *CountMin) index(i uint32, h1, h2 uint64) int {
+	return int(i*c.W) + int((h1+uint64(i)*h2)%uint64(c.W))
+}
+
+// step returns h2 made nonzero modulo the width: a multiple of W would put
+// the item in the same column of every row.
+func (c *CountMin) step(h2 uint64) uint64 {
+	if h2%uint64(c.W) == 0 {
+		return h2 + 1
+	}
+	return h2
+}
+
+func (c *CountMin) add(h1, h2, count uint64) {
+	h2 = c.step(h2)
+	c.N = satAdd(c.N, count)
+	if !c.Conservative {
+		for i := uint32(0); i < c.D; i++ {
+			j := c.index(i, h1, h2)
+			c.Counts[j] = satAdd(c.Counts[j], count)
+		}
+		return
+	}
+	est := satAdd(c.estimate(h1, h2), count)
+	for i := uint32(0); i < c.D; i++ {
+		if j := c.index(i, h1, h2); c.Counts[j] < est {
+			c.Counts[j] = est
+		}
+	}
+}
+
+// Estimate returns the estimated frequency of item. It is never below the
+// true frequency.
+func (c *CountMin) Estimate(item []byte) uint64 {
+	return c.estimate(c.sum128(item))
+}
+
+// EstimateString returns the estimated frequency of the bytes of s.
+func (c *CountMin) EstimateString(s string) uint64 {
+	if c.hash != nil {
+		return c.Estimate(unsafe.Slice(unsafe.StringData(s), len(s)))
+	}
+	return c.estimate(murmur3.Sum128String(s, 0))
+}
+
+// EstimateUint64 returns the estimated frequency of the little-endian
+// encoding of v.
+func (c *CountMin) EstimateUint64(v uint64) uint64 {
+	return c.estimate(c.sum128Uint64(v))
+}
+
+func (c *CountMin) estimate(h1, h2 uint64) uint64 {
+	h2 = c.step(h2)
+	est := uint64(math.MaxUint64)
+	for i := uint32(0); i < c.D; i++ {
+		if v := c.Counts[c.index(i, h1, h2)]; v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+// Merge takes another CountMin and combines it with CountMin c, making c
+// the sketch of both streams. Merging conservative sketches gives
+// estimates that are still never below the true frequencies.
+func (c *CountMin) Merge(other *CountMin) error {
+	if c.W != other.W || c.D != other.D {
+		return &SizeMismatchError{c.W, c.D, other.W, other.D}
+	}
+	for i, v := range other.Counts {
+		c.Counts[i] = satAdd(c.Counts[i], v)
+	}
+	c.N = satAdd(c.N, other.N)
+	return nil
+}
+
+// satAdd returns a+b, or math.MaxUint64 when the sum overflows.
+func satAdd(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// InnerProduct returns the estimated inner product of the frequency
+// vectors of the streams of c and other, e.g. the size of the join of two
+// relations on the counted key. It is never below the true inner
+// product, and exceeds it by at most eps times the product of the sums of
+// their counts with probability 1-delta. It saturates at math.MaxUint64
+// when the inner product does not fit in 64 bits.
+func InnerProduct(c, other *CountMin) (uint64, error) {
+	if c.W != other.W || c.D != other.D {
+		return 0, &SizeMismatchError{c.W, c.D, other.W, other.D}
+	}
+	est := uint64(math.MaxUint64)
+	for i := uint32(0); i < c.D; i++ {
+		var sum uint64
+		row := c.Counts[i*c.W : (i+1)*c.W]
+		for j, v := range other.Counts[i*c.W : (i+1)*c.W] {
+			hi, lo := bits.Mul64(row[j], v)
+			var carry uint64
+			sum, carry = bits.Add64(sum, lo, 0)
+			if hi != 0 || carry != 0 {
+				sum = math.MaxUint64
+				break
+			}
+		}
+		if sum < est {
+			est = sum
+		}
+	}
+	return est, nil
+}
+
+// ByteSize returns the size of the CountMin c in bytes
+func (c *CountMin) ByteSize() int {
+	return 4 + 4 + 1 + 8 + 8*len(c.Counts)
+}
+
+// Serialize the CountMin c into bytes and store in the buffer
+func (c *CountMin) Serialize(buffer []byte) error {
+	if len(buffer) < c.ByteSize() {
+		return shortBuffer(c.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint32(buffer, c.W)
+	b.PutUint32(buffer[4:], c.D)
+	buffer[8] = 0
+	if c.Conservative {
+		buffer[8] = 1
+	}
+	b.PutUint64(buffer[9:], c.N)
+	offset := 17
+	for _, v := range c.Counts {
+		b.PutUint64(buffer[offset:], v)
+		offset += 8
+	}
+	return nil
+}
+
+// Deserialize reconstruct a CountMin from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*CountMin, error) {
+	if len(buffer) < 17 {
+		return nil, shortBuffer(17, len(buffer))
+	}
+	b := binary.LittleEndian
+	w, d := b.Uint32(buffer), b.Uint32(buffer[4:])
+	if w == 0 || d == 0 || uint64(w)*uint64(d) > maxCounters {
+		return nil, corrupt(fmt.Sprintf("size %dx%d is out of range", w, d))
+	}
+	if buffer[8] > 1 {
+		return nil, corrupt(fmt.Sprintf("unknown flags %d", buffer[8]))
+	}
+	if need := 17 + 8*uint64(w)*uint64(d); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	c, err := NewWithSize(w, d, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if buffer[8] == 1 {
+		c.Conservative = true
+	}
+	c.N = b.Uint64(buffer[9:])
+	offset := 17
+	for i := range c.Counts {
+		c.Counts[i] = b.Uint64(buffer[offset:])
+		offset += 8
+	}
+	return c, nil
+}
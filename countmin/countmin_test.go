@@ -0,0 +1,275 @@
+package countmin
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// zipf returns a stream of n items drawn from a Zipf distribution, and the
+// true frequency of each.
+func zipf(seed int64, n int) ([]uint64, map[uint64]uint64) {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.1, 1, 100000)
+	stream := make([]uint64, n)
+	freq := make(map[uint64]uint64)
+	for i := range stream {
+		stream[i] = z.Uint64()
+		freq[stream[i]]++
+	}
+	return stream, freq
+}
+
+func TestCountMinNew(t *testing.T) {
+	c, err := New(0.01, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.W != 272 || c.D != 5 || len(c.Counts) != 272*5 {
+		t.Error(c.W, c.D, len(c.Counts))
+	}
+	for _, p := range [][2]float64{{0, 0.1}, {1, 0.1}, {math.NaN(), 0.1}} {
+		if _, err := New(p[0], p[1]); !errors.Is(err, ErrEpsilon) {
+			t.Error(p, err)
+		}
+	}
+	for _, delta := range []float64{0, 1, -1} {
+		if _, err := New(0.1, delta); !errors.Is(err, ErrDelta) {
+			t.Error(delta, err)
+		}
+	}
+	if _, err := New(1e-12, 0.1); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+	if _, err := NewWithSize(0, 4); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+}
+
+func TestCountMinEstimate(t *testing.T) {
+	const eps, delta = 0.001, 0.01
+	stream, freq := zipf(1, 100000)
+	c, _ := New(eps, delta)
+	cu, _ := New(eps, delta, WithConservativeUpdate())
+	for _, v := range stream {
+		c.AddUint64(v, 1)
+		cu.AddUint64(v, 1)
+	}
+	if c.N != uint64(len(stream)) || cu.N != c.N {
+		t.Error(c.N, cu.N)
+	}
+	bound := uint64(eps * float64(c.N))
+	var over, errSum, cuErrSum uint64
+	for v, f := range freq {
+		est, cuEst := c.EstimateUint64(v), cu.EstimateUint64(v)
+		if est < f || cuEst < f {
+			t.Fatalf("item %d: estimates %d and %d below %d", v, est, cuEst, f)
+		}
+		if cuEst > est {
+			t.Fatalf("item %d: conservative estimate %d above %d", v, cuEst, est)
+		}
+		if est-f > bound {
+			over++
+		}
+		errSum += est - f
+		cuErrSum += cuEst - f
+	}
+	if float64(over) > delta*float64(len(freq)) {
+		t.Errorf("%d of %d estimates exceed the bound", over, len(freq))
+	}
+	if cuErrSum >= errSum {
+		t.Errorf("conservative update error %d, standard %d", cuErrSum, errSum)
+	}
+}
+
+func TestCountMinTyped(t *testing.T) {
+	c, _ := New(0.01, 0.01)
+	c.Add([]byte("hello"), 3)
+	c.AddString("hello", 2)
+	if e := c.EstimateString("hello"); e != 5 {
+		t.Error(e)
+	}
+	if e := c.Estimate([]byte("hello")); e != 5 {
+		t.Error(e)
+	}
+	if e := c.EstimateString("world"); e != 0 {
+		t.Error(e)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		c.AddString("hello", 1)
+		c.AddUint64(42, 1)
+		c.EstimateString("hello")
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+	k, _ := New(0.01, 0.01, WithKey(1, 2))
+	k.AddString("hello", 3)
+	k.AddUint64(42, 1)
+	if e := k.Estimate([]byte("hello")); e != 3 {
+		t.Error(e)
+	}
+	c.Clear()
+	if c.N != 0 || c.EstimateString("hello") != 0 {
+		t.Error("Clear did not reset the CountMin")
+	}
+}
+
+func TestCountMinRowsIndependent(t *testing.T) {
+	// A second hash that is a multiple of the width must still spread the
+	// item over different columns.
+	for _, h2 := range []uint64{0, 100, 100 << 20} {
+		hash := func([]byte) (uint64, uint64) { return 7, h2 }
+		c, _ := NewWithSize(100, 4, WithHashFunc(hash))
+		c.Add([]byte("a"), 1)
+		cols := make(map[int]bool)
+		for j, v := range c.Counts {
+			if v != 0 {
+				cols[j%int(c.W)] = true
+			}
+		}
+		if len(cols) != int(c.D) {
+			t.Errorf("h2 %d: item in columns %v", h2, cols)
+		}
+		if e := c.Estimate([]byte("a")); e != 1 {
+			t.Error(h2, e)
+		}
+	}
+}
+
+func TestCountMinSaturation(t *testing.T) {
+	const big = math.MaxUint64 - 1
+	for _, opts := range [][]Option{nil, {WithConservativeUpdate()}} {
+		c, _ := New(0.01, 0.01, opts...)
+		c.AddString("hello", big)
+		c.AddString("hello", 3)
+		if e := c.EstimateString("hello"); e != math.MaxUint64 {
+			t.Error(e)
+		}
+		if c.N != math.MaxUint64 {
+			t.Error(c.N)
+		}
+		if err := c.Merge(c); err != nil {
+			t.Fatal(err)
+		}
+		if e := c.EstimateString("hello"); e != math.MaxUint64 {
+			t.Error(e)
+		}
+	}
+}
+
+func TestCountMinMerge(t *testing.T) {
+	s1, f1 := zipf(1, 10000)
+	s2, f2 := zipf(2, 10000)
+	a, _ := New(0.001, 0.01)
+	b, _ := New(0.001, 0.01)
+	all, _ := New(0.001, 0.01)
+	for _, v := range s1 {
+		a.AddUint64(v, 1)
+		all.AddUint64(v, 1)
+	}
+	for _, v := range s2 {
+		b.AddUint64(v, 1)
+		all.AddUint64(v, 1)
+	}
+
+	var product uint64
+	for v, f := range f1 {
+		product += f * f2[v]
+	}
+	ip, err := InnerProduct(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip < product || float64(ip-product) > 0.001*float64(a.N*b.N) {
+		t.Errorf("inner product %d (want %d)", ip, product)
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.N != all.N {
+		t.Error(a.N, all.N)
+	}
+	for i := range a.Counts {
+		if a.Counts[i] != all.Counts[i] {
+			t.Fatal("merge differs from adding both streams")
+		}
+	}
+
+	c, _ := NewWithSize(10, 3)
+	var sizeErr *SizeMismatchError
+	if err := a.Merge(c); !errors.As(err, &sizeErr) || sizeErr.OtherW != 10 ||
+		sizeErr.OtherD != 3 {
+		t.Error(err)
+	}
+	if _, err := InnerProduct(a, c); !errors.Is(err, ErrSizeMismatch) {
+		t.Error(err)
+	}
+	c2, _ := NewWithSize(10, 3)
+	c.AddString("hello", 1<<40)
+	c2.AddString("hello", 1<<40)
+	if ip, err := InnerProduct(c, c2); err != nil || ip != math.MaxUint64 {
+		t.Errorf("overflowing inner product %d (want %d)", ip,
+			uint64(math.MaxUint64))
+	}
+}
+
+func TestCountMinSerialization(t *testing.T) {
+	c, _ := New(0.1, 0.1, WithConservativeUpdate())
+	c.AddString("hello", 3)
+	c.AddString("world", 1)
+	buf := make([]byte, c.ByteSize())
+	if err := c.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.W != c.W || d.D != c.D || d.N != c.N || !d.Conservative ||
+		d.EstimateString("hello") != 3 {
+		t.Error("Did not get back the same CountMin")
+	}
+	opts := make([]Option, 0, 1)
+	if _, err := Deserialize(buf, opts...); err != nil || opts[:1][0] != nil {
+		t.Error("Deserialize wrote to the options of the caller", err)
+	}
+	if err := c.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[8] = 2
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[0], buf[1], buf[2], buf[3] = 0, 0, 0, 0
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	c, _ := NewWithSize(4, 2)
+	c.AddString("hello", 3)
+	buf := make([]byte, c.ByteSize())
+	c.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:17])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		c.EstimateString("hello")
+		out := make([]byte, c.ByteSize())
+		if err := c.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
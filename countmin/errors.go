@@ -0,0 +1,48 @@
+package countmin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrEpsilon is returned when eps is not between 0 and 1.
+	ErrEpsilon = errors.New("countmin: eps must be between 0 and 1")
+	// ErrDelta is returned when delta is not between 0 and 1.
+	ErrDelta = errors.New("countmin: delta must be between 0 and 1")
+	// ErrSize is returned when a width or depth is 0, or their product
+	// so large that the CountMin would not fit in memory.
+	ErrSize = errors.New("countmin: width and depth are out of range")
+	// ErrSizeMismatch is returned when CountMins of different sizes are
+	// combined. It is wrapped by SizeMismatchError.
+	ErrSizeMismatch = errors.New("countmin: sizes do not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// SizeMismatchError reports the sizes of two CountMins that cannot be
+// combined.
+type SizeMismatchError struct {
+	W, D, OtherW, OtherD uint32
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("countmin: sizes do not match: %dx%d != %dx%d", e.W,
+		e.D, e.OtherW, e.OtherD)
+}
+
+func (e *SizeMismatchError) Unwrap() error { return ErrSizeMismatch }
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "CountMin", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "CountMin", Reason: reason}
+}
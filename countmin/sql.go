@@ -0,0 +1,37 @@
+package countmin
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(CountMin)
+	_ driver.Valuer = new(CountMin)
+)
+
+// Value implements driver.Valuer so a CountMin can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized CountMin
+// preceded by a tag identifying it as a CountMin.
+func (c *CountMin) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.CountMin, c)
+}
+
+// Scan implements sql.Scanner, restoring a CountMin written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on c, if any, is kept.
+func (c *CountMin) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.CountMin, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(c.hash))
+	if err != nil {
+		return err
+	}
+	*c = *other
+	return nil
+}
@@ -0,0 +1,36 @@
+package countmin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestCountMinValueScan(t *testing.T) {
+	c, _ := New(0.1, 0.1)
+	c.AddString("hello", 3)
+	v, err := c.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d CountMin
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.W != c.W || d.D != c.D || d.EstimateString("hello") != 3 {
+		t.Error("Did not get back the same CountMin")
+	}
+}
+
+func TestCountMinScanError(t *testing.T) {
+	var c CountMin
+	if err := c.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	h, _ := hyperloglog.New(4)
+	v, _ := h.Value()
+	if err := c.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,295 @@
+// Package countsketch implements the Count Sketch, which estimates the
+// frequencies of items in a stream without bias, and the second frequency
+// moment F2 of the stream, also known as its self-join size.
+//
+// Finding Frequent Items in Data Streams:
+// http://www.cs.princeton.edu/courses/archive/spring04/cos598B/bib/CharikarCF.pdf
+//
+// The space complexity of approximating the frequency moments (AMS):
+// https://www.cs.tau.ac.il/~nogaa/PDFS/amsz4.pdf
+package countsketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*CountSketch] = new(CountSketch)
+	_ datasketch.Serializable            = new(CountSketch)
+)
+
+// HashFunc hashes data to the two 64-bit hashes from which the row
+// hashes and signs of a CountSketch are derived.
+type HashFunc func(data []byte) (h1, h2 uint64)
+
+// CountSketch data structure. Counts holds D rows of W counters, row
+// after row. Every item adds its count, times a sign of +1 or -1, to one
+// counter of each row.
+type CountSketch struct {
+	Counts []int64
+	W, D   uint32
+
+	hash HashFunc
+}
+
+// Option configures a CountSketch created by New or Deserialize.
+type Option func(*CountSketch)
+
+// WithHashFunc makes CountSketch hash items with f instead of
+// murmur3.Sum128. CountSketches are only comparable when their items were
+// hashed with the same function.
+func WithHashFunc(f HashFunc) Option {
+	return func(c *CountSketch) {
+		c.hash = f
+	}
+}
+
+// WithKey makes CountSketch hash items with SipHash keyed with the secret
+// k0 and k1, so an attacker who controls the items cannot make them share
+// counters. CountSketches are only comparable when built with the same
+// key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed128(k0, k1))
+}
+
+// maxCounters bounds the number of counters so that the serialized
+// CountSketch fits in memory.
+const maxCounters = 1 << 28
+
+// New returns a CountSketch whose point estimates are off by at most eps
+// times the L2 norm of the frequencies, and whose F2 estimate is off by a
+// relative error of about eps, with probability 1-delta. It has
+// ceil(3/eps^2) counters in each of ceil(ln(1/delta)) rows, made odd so
+// that medians are taken over an odd number of rows, and New returns
+// ErrSize if they would not fit in memory.
+func New(eps, delta float64, opts ...Option) (*CountSketch, error) {
+	if !(eps > 0 && eps < 1) {
+		return nil, ErrEpsilon
+	}
+	if !(delta > 0 && delta < 1) {
+		return nil, ErrDelta
+	}
+	w := math.Ceil(3 / (eps * eps))
+	d := math.Ceil(math.Log(1 / delta))
+	if math.Mod(d, 2) == 0 {
+		d++
+	}
+	if w*d > maxCounters {
+		return nil, ErrSize
+	}
+	return NewWithSize(uint32(w), uint32(d), opts...)
+}
+
+// NewWithSize returns a CountSketch with d rows of w counters.
+func NewWithSize(w, d uint32, opts ...Option) (*CountSketch, error) {
+	if w == 0 || d == 0 || uint64(w)*uint64(d) > maxCounters {
+		return nil, ErrSize
+	}
+	c := &CountSketch{W: w, D: d}
+	c.Counts = make([]int64, int(w)*int(d))
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Clear sets CountSketch c back to its initial state.
+func (c *CountSketch) Clear() {
+	for i := range c.Counts {
+		c.Counts[i] = 0
+	}
+}
+
+func (c *CountSketch) sum128(data []byte) (uint64, uint64) {
+	if c.hash != nil {
+		return c.hash(data)
+	}
+	return murmur3.Sum128(data)
+}
+
+func (c *CountSketch) sum128String(s string) (uint64, uint64) {
+	if c.hash != nil {
+		return c.hash(unsafe.Slice(unsafe.StringData(s), len(s)))
+	}
+	return murmur3.Sum128String(s, 0)
+}
+
+func (c *CountSketch) sum128Uint64(v uint64) (uint64, uint64) {
+	if c.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return c.hash(b[:])
+	}
+	return murmur3.Sum128Uint64(v, 0)
+}
+
+// Add adds count occurrences of item. A negative count removes
+// occurrences.
+func (c *CountSketch) Add(item []byte, count int64) {
+	h1, h2 := c.sum128(item)
+	c.add(h1, h2, count)
+}
+
+// AddString adds count occurrences of the bytes of s.
+func (c *CountSketch) AddString(s string, count int64) {
+	h1, h2 := c.sum128String(s)
+	c.add(h1, h2, count)
+}
+
+// AddUint64 adds count occurrences of the little-endian encoding of v.
+func (c *CountSketch) AddUint64(v uint64, count int64) {
+	h1, h2 := c.sum128Uint64(v)
+	c.add(h1, h2, count)
+}
+
+// cell returns the counter of row i for the item hashed to h1 and h2, and
+// the sign of the item in that row. Both come from one mix of a double
+// hash, the counter from its top bits and the sign from its bottom bit.
+func (c *CountSketch) cell(i uint32, h1, h2 uint64) (int, int64) {
+	g := mix.Mix64(h1 + uint64(i)*h2)
+	j := int(i*c.W) + int((g>>32)*uint64(c.W)>>32)
+	return j, int64(g&1)*2 - 1
+}
+
+func (c *CountSketch) add(h1, h2 uint64, count int64) {
+	for i := uint32(0); i < c.D; i++ {
+		j, sign := c.cell(i, h1, h2)
+		c.Counts[j] += sign * count
+	}
+}
+
+// Estimate returns the estimated frequency of item, the median of the
+// estimates of the rows. Each row's estimate is unbiased.
+func (c *CountSketch) Estimate(item []byte) int64 {
+	return c.estimate(c.sum128(item))
+}
+
+// EstimateString returns the estimated frequency of the bytes of s.
+func (c *CountSketch) EstimateString(s string) int64 {
+	return c.estimate(c.sum128String(s))
+}
+
+// EstimateUint64 returns the estimated frequency of the little-endian
+// encoding of v.
+func (c *CountSketch) EstimateUint64(v uint64) int64 {
+	return c.estimate(c.sum128Uint64(v))
+}
+
+func (c *CountSketch) estimate(h1, h2 uint64) int64 {
+	var buf [15]float64
+	est := buf[:0]
+	if c.D > uint32(len(buf)) {
+		est = make([]float64, 0, c.D)
+	}
+	est = est[:c.D]
+	for i := range est {
+		j, sign := c.cell(uint32(i), h1, h2)
+		est[i] = float64(sign * c.Counts[j])
+	}
+	return int64(median(est))
+}
+
+// F2 returns the estimated second frequency moment of the stream, the sum
+// of the squares of the frequencies of all items.
+func (c *CountSketch) F2() float64 {
+	f, _ := InnerProduct(c, c)
+	return f
+}
+
+// InnerProduct returns the estimated inner product of the frequency
+// vectors of the streams of c and other, e.g. the size of the join of two
+// relations on the counted key.
+func InnerProduct(c, other *CountSketch) (float64, error) {
+	if c.W != other.W || c.D != other.D {
+		return 0, &SizeMismatchError{c.W, c.D, other.W, other.D}
+	}
+	est := make([]float64, c.D)
+	for i := range est {
+		row := c.Counts[uint32(i)*c.W : uint32(i+1)*c.W]
+		for j, v := range other.Counts[uint32(i)*c.W : uint32(i+1)*c.W] {
+			est[i] += float64(row[j]) * float64(v)
+		}
+	}
+	return median(est), nil
+}
+
+// median sorts x, by insertion as there are few rows, and returns its
+// median.
+func median(x []float64) float64 {
+	for i := 1; i < len(x); i++ {
+		for j := i; j > 0 && x[j] < x[j-1]; j-- {
+			x[j], x[j-1] = x[j-1], x[j]
+		}
+	}
+	n := len(x)
+	if n%2 == 1 {
+		return x[n/2]
+	}
+	return (x[n/2-1] + x[n/2]) / 2
+}
+
+// Merge takes another CountSketch and combines it with CountSketch c,
+// making c the sketch of both streams.
+func (c *CountSketch) Merge(other *CountSketch) error {
+	if c.W != other.W || c.D != other.D {
+		return &SizeMismatchError{c.W, c.D, other.W, other.D}
+	}
+	for i, v := range other.Counts {
+		c.Counts[i] += v
+	}
+	return nil
+}
+
+// ByteSize returns the size of the CountSketch c in bytes
+func (c *CountSketch) ByteSize() int {
+	return 4 + 4 + 8*len(c.Counts)
+}
+
+// Serialize the CountSketch c into bytes and store in the buffer
+func (c *CountSketch) Serialize(buffer []byte) error {
+	if len(buffer) < c.ByteSize() {
+		return shortBuffer(c.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint32(buffer, c.W)
+	b.PutUint32(buffer[4:], c.D)
+	offset := 8
+	for _, v := range c.Counts {
+		b.PutUint64(buffer[offset:], uint64(v))
+		offset += 8
+	}
+	return nil
+}
+
+// Deserialize reconstruct a CountSketch from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*CountSketch, error) {
+	if len(buffer) < 8 {
+		return nil, shortBuffer(8, len(buffer))
+	}
+	b := binary.LittleEndian
+	w, d := b.Uint32(buffer), b.Uint32(buffer[4:])
+	if w == 0 || d == 0 || uint64(w)*uint64(d) > maxCounters {
+		return nil, corrupt(fmt.Sprintf("size %dx%d is out of range", w, d))
+	}
+	if need := 8 + 8*uint64(w)*uint64(d); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	c, err := NewWithSize(w, d, opts...)
+	if err != nil {
+		return nil, err
+	}
+	offset := 8
+	for i := range c.Counts {
+		c.Counts[i] = int64(b.Uint64(buffer[offset:]))
+		offset += 8
+	}
+	return c, nil
+}
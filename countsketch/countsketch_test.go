@@ -0,0 +1,243 @@
+package countsketch
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// zipf returns a stream of n items drawn from a Zipf distribution, and the
+// true frequency of each.
+func zipf(seed int64, n int) ([]uint64, map[uint64]int64) {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.1, 1, 100000)
+	stream := make([]uint64, n)
+	freq := make(map[uint64]int64)
+	for i := range stream {
+		stream[i] = z.Uint64()
+		freq[stream[i]]++
+	}
+	return stream, freq
+}
+
+func f2(freq map[uint64]int64) float64 {
+	var sum float64
+	for _, f := range freq {
+		sum += float64(f) * float64(f)
+	}
+	return sum
+}
+
+func TestCountSketchNew(t *testing.T) {
+	c, err := New(0.1, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.W != 300 || c.D != 5 || len(c.Counts) != 300*5 {
+		t.Error(c.W, c.D, len(c.Counts))
+	}
+	if c, _ := New(0.1, 0.1); c.D != 3 {
+		t.Errorf("depth %d (want 3)", c.D)
+	}
+	if _, err := New(0, 0.1); !errors.Is(err, ErrEpsilon) {
+		t.Error(err)
+	}
+	if _, err := New(1e-6, 0.1); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+	if _, err := New(0.1, 1); !errors.Is(err, ErrDelta) {
+		t.Error(err)
+	}
+	if _, err := NewWithSize(4, 0); !errors.Is(err, ErrSize) {
+		t.Error(err)
+	}
+}
+
+func TestCountSketchEstimate(t *testing.T) {
+	const eps, delta = 0.05, 0.01
+	stream, freq := zipf(1, 100000)
+	c, _ := New(eps, delta)
+	for _, v := range stream {
+		c.AddUint64(v, 1)
+	}
+	bound := eps * math.Sqrt(f2(freq))
+	over := 0
+	for v, f := range freq {
+		if math.Abs(float64(c.EstimateUint64(v)-f)) > bound {
+			over++
+		}
+	}
+	if float64(over) > delta*float64(len(freq)) {
+		t.Errorf("%d of %d estimates exceed the bound %.1f", over, len(freq),
+			bound)
+	}
+}
+
+func TestCountSketchUnbiased(t *testing.T) {
+	// The errors of the estimates of the rare items, averaged over
+	// independent hash functions, cancel out.
+	const trials = 200
+	stream, freq := zipf(1, 10000)
+	var sum, abs float64
+	for trial := uint64(0); trial < trials; trial++ {
+		c, _ := NewWithSize(64, 1, WithKey(trial, 0))
+		for _, v := range stream {
+			c.AddUint64(v, 1)
+		}
+		for v := uint64(100); v < 110; v++ {
+			e := float64(c.EstimateUint64(v) - freq[v])
+			sum += e
+			abs += math.Abs(e)
+		}
+	}
+	if mean, meanAbs := sum/(trials*10), abs/(trials*10); math.Abs(mean) > meanAbs/10 {
+		t.Errorf("mean error %.2f, mean absolute error %.2f", mean, meanAbs)
+	}
+}
+
+func TestCountSketchF2(t *testing.T) {
+	s1, f1 := zipf(1, 100000)
+	s2, f2s := zipf(2, 100000)
+	a, _ := New(0.05, 0.01)
+	b, _ := New(0.05, 0.01)
+	for _, v := range s1 {
+		a.AddUint64(v, 1)
+	}
+	for _, v := range s2 {
+		b.AddUint64(v, 1)
+	}
+	if want := f2(f1); math.Abs(a.F2()-want)/want > 0.05 {
+		t.Errorf("F2 %.0f (want %.0f)", a.F2(), want)
+	}
+	var join float64
+	for v, f := range f1 {
+		join += float64(f) * float64(f2s[v])
+	}
+	ip, err := InnerProduct(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The error is relative to the product of the L2 norms.
+	if math.Abs(ip-join) > 0.05*math.Sqrt(f2(f1)*f2(f2s)) {
+		t.Errorf("inner product %.0f (want %.0f)", ip, join)
+	}
+}
+
+func TestCountSketchTyped(t *testing.T) {
+	c, _ := New(0.1, 0.1)
+	c.Add([]byte("hello"), 3)
+	c.AddString("hello", 2)
+	c.AddUint64(42, 7)
+	if e := c.EstimateString("hello"); e != 5 {
+		t.Error(e)
+	}
+	c.AddString("hello", -5)
+	if e := c.Estimate([]byte("hello")); e != 0 {
+		t.Error(e)
+	}
+	if e := c.EstimateUint64(42); e != 7 {
+		t.Error(e)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		c.AddString("hello", 1)
+		c.EstimateString("hello")
+		c.EstimateUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+	k, _ := New(0.1, 0.1, WithKey(1, 2))
+	k.AddString("hello", 3)
+	if e := k.Estimate([]byte("hello")); e != 3 {
+		t.Error(e)
+	}
+	c.Clear()
+	if c.F2() != 0 {
+		t.Error("Clear did not reset the CountSketch")
+	}
+}
+
+func TestCountSketchMerge(t *testing.T) {
+	s1, _ := zipf(1, 10000)
+	s2, _ := zipf(2, 10000)
+	a, _ := New(0.1, 0.1)
+	b, _ := New(0.1, 0.1)
+	all, _ := New(0.1, 0.1)
+	for _, v := range s1 {
+		a.AddUint64(v, 1)
+		all.AddUint64(v, 1)
+	}
+	for _, v := range s2 {
+		b.AddUint64(v, 1)
+		all.AddUint64(v, 1)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := range a.Counts {
+		if a.Counts[i] != all.Counts[i] {
+			t.Fatal("merge differs from adding both streams")
+		}
+	}
+	c, _ := NewWithSize(10, 3)
+	var sizeErr *SizeMismatchError
+	if err := a.Merge(c); !errors.As(err, &sizeErr) || sizeErr.OtherW != 10 ||
+		sizeErr.OtherD != 3 {
+		t.Error(err)
+	}
+	if _, err := InnerProduct(a, c); !errors.Is(err, ErrSizeMismatch) {
+		t.Error(err)
+	}
+}
+
+func TestCountSketchSerialization(t *testing.T) {
+	c, _ := New(0.1, 0.1)
+	c.AddString("hello", 3)
+	c.AddString("world", -1)
+	buf := make([]byte, c.ByteSize())
+	if err := c.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.W != c.W || d.D != c.D || d.EstimateString("hello") != 3 ||
+		d.EstimateString("world") != -1 {
+		t.Error("Did not get back the same CountSketch")
+	}
+	if err := c.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[4], buf[5], buf[6], buf[7] = 0, 0, 0, 0
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	c, _ := NewWithSize(4, 3)
+	c.AddString("hello", 3)
+	buf := make([]byte, c.ByteSize())
+	c.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:8])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		c.EstimateString("hello")
+		c.F2()
+		out := make([]byte, c.ByteSize())
+		if err := c.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
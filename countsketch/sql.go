@@ -0,0 +1,37 @@
+package countsketch
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(CountSketch)
+	_ driver.Valuer = new(CountSketch)
+)
+
+// Value implements driver.Valuer so a CountSketch can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized CountSketch
+// preceded by a tag identifying it as a CountSketch.
+func (c *CountSketch) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.CountSketch, c)
+}
+
+// Scan implements sql.Scanner, restoring a CountSketch written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on c, if any, is kept.
+func (c *CountSketch) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.CountSketch, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(c.hash))
+	if err != nil {
+		return err
+	}
+	*c = *other
+	return nil
+}
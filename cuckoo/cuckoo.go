@@ -0,0 +1,523 @@
+// Package cuckoo implements the cuckoo filter, which tests whether an item
+// is in a set with no false negatives and a bounded rate of false
+// positives, and unlike a Bloom filter supports deleting items. At false
+// positive rates below 3% it also takes less space.
+//
+// Cuckoo Filter: Practically Better Than Bloom:
+// https://www.cs.cmu.edu/~dga/papers/cuckoo-conext2014.pdf
+package cuckoo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.CardinalityEstimator = new(CuckooFilter)
+	_ datasketch.Serializable         = new(CuckooFilter)
+)
+
+// HashFunc hashes data to the 64-bit hash from which the fingerprint and
+// buckets of an item are derived.
+type HashFunc func(data []byte) uint64
+
+const (
+	// DefaultFingerprintBits gives a false positive rate around 0.01%
+	// with buckets of 4.
+	DefaultFingerprintBits = 16
+	// DefaultBucketSize is the bucket size for which the paper finds
+	// filters smallest at most false positive rates.
+	DefaultBucketSize = 4
+
+	// maxKicks is the number of fingerprints relocated before an
+	// insertion gives up.
+	maxKicks = 500
+	// maxBuckets bounds the number of buckets so that their index fits in
+	// the 32 bits of the hash not used by the fingerprint.
+	maxBuckets = 1 << 32
+	// maxBits bounds the size of the table so that the serialized filter
+	// fits in memory.
+	maxBits = 1 << 36
+)
+
+// CuckooFilter data structure. Table holds NumBuckets buckets of
+// BucketSize fingerprints of FingerprintBits bits. An item with
+// fingerprint fp is stored in one of two buckets i1 and i2, where
+// i2 = i1 xor hash(fp), so either can be found from the other.
+type CuckooFilter struct {
+	Table           []uint64
+	NumBuckets      uint64
+	FingerprintBits uint8
+	BucketSize      uint8
+	// SemiSorted filters store the fingerprints of each bucket in sorted
+	// order, saving one bit per fingerprint.
+	SemiSorted bool
+	// N is the number of items in the filter.
+	N uint64
+
+	// victim is the fingerprint evicted by the last insertion that ran
+	// out of kicks. The filter is full while it is set.
+	victim      uint32
+	victimIndex uint64
+	hash        HashFunc
+	rng         uint64
+}
+
+// Option configures a CuckooFilter created by New or Deserialize.
+type Option func(*CuckooFilter)
+
+// WithFingerprintBits sets the size of the fingerprints, between 4 and 32
+// bits. The false positive rate is about 2*BucketSize/2^bits.
+func WithFingerprintBits(bits uint8) Option {
+	return func(f *CuckooFilter) {
+		f.FingerprintBits = bits
+	}
+}
+
+// WithBucketSize sets the number of fingerprints in each bucket, between
+// 1 and 8. Larger buckets reach higher load factors but need larger
+// fingerprints for the same false positive rate.
+func WithBucketSize(size uint8) Option {
+	return func(f *CuckooFilter) {
+		f.BucketSize = size
+	}
+}
+
+// WithSemiSorting stores the fingerprints of each bucket sorted, encoding
+// their high 4 bits together in 12 bits instead of 16. It requires
+// buckets of 4 and makes the filter slower.
+func WithSemiSorting() Option {
+	return func(f *CuckooFilter) {
+		f.SemiSorted = true
+	}
+}
+
+// WithHashFunc makes CuckooFilter hash items with f instead of
+// murmur3.Sum64. Filters are only comparable when their items were hashed
+// with the same function.
+func WithHashFunc(h HashFunc) Option {
+	return func(f *CuckooFilter) {
+		f.hash = h
+	}
+}
+
+// WithKey makes CuckooFilter hash items with SipHash keyed with the secret
+// k0 and k1, so an attacker who controls the items cannot choose ones
+// that are false positives or that fill a bucket. Filters are only
+// comparable when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed64(k0, k1))
+}
+
+// maxLoad returns the load factor below which insertions almost never
+// fail with buckets of the given size. It is below the load factors
+// measured in the paper, which small tables do not always reach.
+func maxLoad(bucketSize uint8) float64 {
+	switch {
+	case bucketSize == 1:
+		return 0.3
+	case bucketSize < 4:
+		return 0.7
+	case bucketSize < 6:
+		return 0.92
+	}
+	return 0.95
+}
+
+// maxLogBuckets returns the base 2 logarithm of the largest number of
+// buckets that fingerprints of fpBits bits can fill to maxLoad. The
+// other bucket of a fingerprint is one of only 2^fpBits-1 offsets from the
+// first, and with too few of them relocations stay stuck in small groups
+// of buckets.
+func maxLogBuckets(fpBits, bucketSize uint8) int {
+	if bucketSize > 3 {
+		bucketSize = 3
+	}
+	return (2*int(fpBits) - 4) * int(bucketSize)
+}
+
+// New returns a CuckooFilter with room for n items: insertions almost never
+// fail before it holds n. The number of buckets is a power of 2, so the
+// filter may hold up to twice as many. It returns
+// ErrSmallFingerprint if the fingerprints are too small to fill that many
+// buckets.
+func New(n uint64, opts ...Option) (*CuckooFilter, error) {
+	f := &CuckooFilter{
+		FingerprintBits: DefaultFingerprintBits,
+		BucketSize:      DefaultBucketSize,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrCapacity
+	}
+	buckets := math.Ceil(float64(n) / (float64(f.BucketSize) *
+		maxLoad(f.BucketSize)))
+	if buckets > maxBuckets {
+		return nil, ErrCapacity
+	}
+	f.NumBuckets = 1
+	for float64(f.NumBuckets) < buckets {
+		f.NumBuckets <<= 1
+	}
+	if f.NumBuckets*f.bucketBits() > maxBits {
+		return nil, ErrCapacity
+	}
+	if bits.Len64(f.NumBuckets)-1 > maxLogBuckets(f.FingerprintBits,
+		f.BucketSize) {
+		return nil, ErrSmallFingerprint
+	}
+	f.Table = make([]uint64, (f.NumBuckets*f.bucketBits()+63)/64)
+	return f, nil
+}
+
+// validate checks the fingerprint and bucket sizes.
+func (f *CuckooFilter) validate() error {
+	if f.FingerprintBits < 4 || f.FingerprintBits > 32 {
+		return ErrFingerprintBits
+	}
+	if f.BucketSize == 0 || f.BucketSize > maxBucketSize {
+		return ErrBucketSize
+	}
+	if f.SemiSorted && f.BucketSize != 4 {
+		return ErrSemiSorting
+	}
+	return nil
+}
+
+// Clear sets CuckooFilter f back to its initial state.
+func (f *CuckooFilter) Clear() {
+	for i := range f.Table {
+		f.Table[i] = 0
+	}
+	f.N = 0
+	f.victim, f.victimIndex = 0, 0
+}
+
+func (f *CuckooFilter) sum64(data []byte) uint64 {
+	if f.hash != nil {
+		return f.hash(data)
+	}
+	return murmur3.Sum64(data)
+}
+
+func (f *CuckooFilter) sum64String(s string) uint64 {
+	if f.hash != nil {
+		return f.hash(unsafe.Slice(unsafe.StringData(s), len(s)))
+	}
+	return murmur3.Sum64String(s, 0)
+}
+
+func (f *CuckooFilter) sum64Uint64(v uint64) uint64 {
+	if f.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return f.hash(b[:])
+	}
+	return murmur3.Sum64Uint64(v, 0)
+}
+
+// locate returns the fingerprint of the item hashed to h, taken from its
+// high bits and never 0, and its first bucket, taken from its low bits.
+func (f *CuckooFilter) locate(h uint64) (uint32, uint64) {
+	fp := uint32(h>>32) >> (32 - f.FingerprintBits)
+	if fp == 0 {
+		fp = 1
+	}
+	return fp, h & (f.NumBuckets - 1)
+}
+
+// altIndex returns the other bucket of fingerprint fp stored in bucket i.
+func (f *CuckooFilter) altIndex(i uint64, fp uint32) uint64 {
+	return (i ^ uint64(fp)*0x5bd1e995) & (f.NumBuckets - 1)
+}
+
+// Insert adds item to the set. Items may be inserted more than once, and
+// must then be deleted as many times. It returns ErrFull if there is no
+// room left, the set then being unchanged.
+func (f *CuckooFilter) Insert(item []byte) error {
+	return f.insert(f.locate(f.sum64(item)))
+}
+
+// InsertString adds the bytes of s to the set.
+func (f *CuckooFilter) InsertString(s string) error {
+	return f.insert(f.locate(f.sum64String(s)))
+}
+
+// InsertUint64 adds the little-endian encoding of v to the set.
+func (f *CuckooFilter) InsertUint64(v uint64) error {
+	return f.insert(f.locate(f.sum64Uint64(v)))
+}
+
+// Lookup reports whether item may be in the set. It is certainly not if
+// Lookup returns false.
+func (f *CuckooFilter) Lookup(item []byte) bool {
+	return f.lookup(f.locate(f.sum64(item)))
+}
+
+// LookupString reports whether the bytes of s may be in the set.
+func (f *CuckooFilter) LookupString(s string) bool {
+	return f.lookup(f.locate(f.sum64String(s)))
+}
+
+// LookupUint64 reports whether the little-endian encoding of v may be in
+// the set.
+func (f *CuckooFilter) LookupUint64(v uint64) bool {
+	return f.lookup(f.locate(f.sum64Uint64(v)))
+}
+
+// Delete removes item from the set, and reports whether it may have been
+// in it. Only items that were inserted may be deleted: deleting any other
+// item that is a false positive brings false negatives.
+func (f *CuckooFilter) Delete(item []byte) bool {
+	return f.delete(f.locate(f.sum64(item)))
+}
+
+// DeleteString removes the bytes of s from the set, and reports whether
+// they may have been in it.
+func (f *CuckooFilter) DeleteString(s string) bool {
+	return f.delete(f.locate(f.sum64String(s)))
+}
+
+// DeleteUint64 removes the little-endian encoding of v from the set, and
+// reports whether it may have been in it.
+func (f *CuckooFilter) DeleteUint64(v uint64) bool {
+	return f.delete(f.locate(f.sum64Uint64(v)))
+}
+
+func (f *CuckooFilter) insert(fp uint32, i uint64) error {
+	if f.victim != 0 {
+		return ErrFull
+	}
+	f.store(i, fp)
+	f.N++
+	return nil
+}
+
+// store stores fingerprint fp in bucket i or its other bucket, relocating
+// fingerprints if both are full.
+func (f *CuckooFilter) store(i uint64, fp uint32) {
+	if f.put(i, fp) || f.put(f.altIndex(i, fp), fp) {
+		return
+	}
+	if f.random()&1 == 1 {
+		i = f.altIndex(i, fp)
+	}
+	f.relocate(i, fp)
+}
+
+// random returns the next pseudo-random number of f.
+func (f *CuckooFilter) random() uint64 {
+	f.rng += 0x9e3779b97f4a7c15
+	return mix.Mix64(f.rng)
+}
+
+// relocate stores fingerprint fp in bucket i by moving the fingerprints
+// in its way to their other bucket, chosen at random. If it still has a
+// fingerprint in hand after maxKicks moves, it keeps it as the victim.
+func (f *CuckooFilter) relocate(i uint64, fp uint32) {
+	var r uint64
+	for kick := 0; kick < maxKicks; kick++ {
+		if kick%8 == 0 {
+			r = f.random()
+		}
+		b := f.bucket(i)
+		j := r % uint64(f.BucketSize)
+		r >>= 8
+		b[j], fp = fp, b[j]
+		f.setBucket(i, b)
+		i = f.altIndex(i, fp)
+		if f.put(i, fp) {
+			return
+		}
+	}
+	f.victim, f.victimIndex = fp, i
+}
+
+// put stores fingerprint fp in an empty slot of bucket i, and reports
+// whether there was one.
+func (f *CuckooFilter) put(i uint64, fp uint32) bool {
+	b := f.bucket(i)
+	for j := uint8(0); j < f.BucketSize; j++ {
+		if b[j] == 0 {
+			b[j] = fp
+			f.setBucket(i, b)
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the slot of fingerprint fp in bucket i, or -1.
+func (f *CuckooFilter) find(i uint64, fp uint32) int {
+	b := f.bucket(i)
+	for j := uint8(0); j < f.BucketSize; j++ {
+		if b[j] == fp {
+			return int(j)
+		}
+	}
+	return -1
+}
+
+func (f *CuckooFilter) lookup(fp uint32, i uint64) bool {
+	i2 := f.altIndex(i, fp)
+	if f.victim == fp && (f.victimIndex == i || f.victimIndex == i2) {
+		return true
+	}
+	return f.find(i, fp) >= 0 || f.find(i2, fp) >= 0
+}
+
+func (f *CuckooFilter) delete(fp uint32, i uint64) bool {
+	i2 := f.altIndex(i, fp)
+	if f.victim == fp && (f.victimIndex == i || f.victimIndex == i2) {
+		f.victim, f.victimIndex = 0, 0
+		f.N--
+		return true
+	}
+	for _, i := range [2]uint64{i, i2} {
+		if j := f.find(i, fp); j >= 0 {
+			b := f.bucket(i)
+			b[j] = 0
+			f.setBucket(i, b)
+			f.N--
+			if f.victim != 0 {
+				// Now that there is room, try to store the victim again.
+				v, vi := f.victim, f.victimIndex
+				f.victim, f.victimIndex = 0, 0
+				f.store(vi, v)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of items in the set, counting items inserted
+// several times as many times.
+func (f *CuckooFilter) Count() float64 {
+	return float64(f.N)
+}
+
+// LoadFactor returns the fraction of the slots that are used.
+func (f *CuckooFilter) LoadFactor() float64 {
+	return float64(f.N) / (float64(f.NumBuckets) * float64(f.BucketSize))
+}
+
+// FPRate returns the estimated false positive rate given the slots used:
+// a lookup compares the fingerprint of the item with those in two
+// buckets, each matching with probability 1/(2^FingerprintBits-1).
+func (f *CuckooFilter) FPRate() float64 {
+	used := 2 * f.LoadFactor() * float64(f.BucketSize)
+	return -math.Expm1(used * math.Log1p(-1/(math.Exp2(
+		float64(f.FingerprintBits))-1)))
+}
+
+// headerSize is the size of the serialized CuckooFilter before its table.
+const headerSize = 1 + 1 + 1 + 8 + 8 + 4 + 8
+
+// ByteSize returns the size of the CuckooFilter f in bytes
+func (f *CuckooFilter) ByteSize() int {
+	return headerSize + 8*len(f.Table)
+}
+
+// Serialize the CuckooFilter f into bytes and store in the buffer:
+// FingerprintBits, BucketSize, flags, NumBuckets, N, the victim
+// fingerprint and bucket, then the table in 64-bit little-endian words.
+func (f *CuckooFilter) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer(f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	buffer[0] = f.FingerprintBits
+	buffer[1] = f.BucketSize
+	buffer[2] = 0
+	if f.SemiSorted {
+		buffer[2] = 1
+	}
+	b.PutUint64(buffer[3:], f.NumBuckets)
+	b.PutUint64(buffer[11:], f.N)
+	b.PutUint32(buffer[19:], f.victim)
+	b.PutUint64(buffer[23:], f.victimIndex)
+	for i, w := range f.Table {
+		b.PutUint64(buffer[headerSize+8*i:], w)
+	}
+	return nil
+}
+
+// Deserialize reconstruct a CuckooFilter from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*CuckooFilter, error) {
+	if len(buffer) < headerSize {
+		return nil, shortBuffer(headerSize, len(buffer))
+	}
+	b := binary.LittleEndian
+	f := &CuckooFilter{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.FingerprintBits, f.BucketSize = buffer[0], buffer[1]
+	if buffer[2] > 1 {
+		return nil, corrupt(fmt.Sprintf("unknown flags %d", buffer[2]))
+	}
+	f.SemiSorted = buffer[2] == 1
+	if err := f.validate(); err != nil {
+		return nil, corrupt(err.Error())
+	}
+	f.NumBuckets = b.Uint64(buffer[3:])
+	if f.NumBuckets == 0 || f.NumBuckets > maxBuckets ||
+		f.NumBuckets&(f.NumBuckets-1) != 0 ||
+		f.NumBuckets*f.bucketBits() > maxBits {
+		return nil, corrupt(fmt.Sprintf("%d buckets is not a power of 2 in "+
+			"range", f.NumBuckets))
+	}
+	f.N = b.Uint64(buffer[11:])
+	f.victim, f.victimIndex = b.Uint32(buffer[19:]), b.Uint64(buffer[23:])
+	if f.victim>>f.FingerprintBits != 0 || f.victimIndex >= f.NumBuckets ||
+		f.victim == 0 && f.victimIndex != 0 {
+		return nil, corrupt(fmt.Sprintf("victim %d in bucket %d is out of "+
+			"range", f.victim, f.victimIndex))
+	}
+	bits := f.NumBuckets * f.bucketBits()
+	if need := headerSize + 8*((bits+63)/64); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	f.Table = make([]uint64, (bits+63)/64)
+	for i := range f.Table {
+		f.Table[i] = b.Uint64(buffer[headerSize+8*i:])
+	}
+	if r := bits % 64; r != 0 && f.Table[len(f.Table)-1]>>r != 0 {
+		return nil, corrupt("bits beyond the last bucket are set")
+	}
+	var n uint64
+	if f.victim != 0 {
+		n++
+	}
+	for i := uint64(0); i < f.NumBuckets; i++ {
+		if f.SemiSorted &&
+			int(f.getBits(i*f.bucketBits(), 12)) >= len(semiSortCodes) {
+			return nil, corrupt(fmt.Sprintf("bucket %d has an invalid "+
+				"encoding", i))
+		}
+		for _, fp := range f.bucket(i) {
+			if fp != 0 {
+				n++
+			}
+		}
+	}
+	if n != f.N {
+		return nil, corrupt(fmt.Sprintf("%d items are stored, not %d", n,
+			f.N))
+	}
+	return f, nil
+}
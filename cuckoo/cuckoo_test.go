@@ -0,0 +1,276 @@
+package cuckoo
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCuckooFilterNew(t *testing.T) {
+	f, err := New(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumBuckets != 512 || f.FingerprintBits != 16 || f.BucketSize != 4 ||
+		len(f.Table) != 512 {
+		t.Error(f.NumBuckets, f.FingerprintBits, f.BucketSize, len(f.Table))
+	}
+	s, _ := New(1000, WithSemiSorting())
+	if len(s.Table) != 480 {
+		t.Error(len(s.Table))
+	}
+	if _, err := New(0); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	if _, err := New(1 << 40); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	for _, bits := range []uint8{0, 3, 33} {
+		if _, err := New(10, WithFingerprintBits(bits)); !errors.Is(err,
+			ErrFingerprintBits) {
+			t.Error(bits, err)
+		}
+	}
+	for _, size := range []uint8{0, 9} {
+		if _, err := New(10, WithBucketSize(size)); !errors.Is(err,
+			ErrBucketSize) {
+			t.Error(size, err)
+		}
+	}
+	if _, err := New(10, WithBucketSize(2), WithSemiSorting()); !errors.Is(
+		err, ErrSemiSorting) {
+		t.Error(err)
+	}
+	if _, err := New(5000, WithBucketSize(1), WithFingerprintBits(4)); !errors.Is(
+		err, ErrSmallFingerprint) {
+		t.Error(err)
+	}
+}
+
+// configs are the filter configurations exercised by the tests.
+var configs = []struct {
+	name string
+	opts []Option
+}{
+	{"default", nil},
+	{"semi-sorted", []Option{WithSemiSorting()}},
+	{"semi-sorted 4 bits", []Option{WithSemiSorting(), WithFingerprintBits(4)}},
+	{"bucket 1", []Option{WithBucketSize(1), WithFingerprintBits(32)}},
+	{"bucket 2 6 bits", []Option{WithBucketSize(2), WithFingerprintBits(6)}},
+	{"bucket 8", []Option{WithBucketSize(8), WithFingerprintBits(7)}},
+	{"keyed", []Option{WithKey(1, 2), WithFingerprintBits(12)}},
+}
+
+func TestCuckooFilterDelete(t *testing.T) {
+	const n = 10000
+	for _, c := range configs {
+		f, _ := New(n, c.opts...)
+		for i := uint64(0); i < n; i++ {
+			if err := f.InsertUint64(i); err != nil {
+				t.Fatalf("%s: %d: %v", c.name, i, err)
+			}
+		}
+		for i := uint64(0); i < n; i += 2 {
+			if !f.DeleteUint64(i) {
+				t.Fatalf("%s: %d was not in the set", c.name, i)
+			}
+		}
+		if f.Count() != n/2 {
+			t.Error(c.name, f.Count())
+		}
+		for i := uint64(1); i < n; i += 2 {
+			if !f.LookupUint64(i) {
+				t.Fatalf("%s: %d is a false negative", c.name, i)
+			}
+		}
+		for i := uint64(1); i < n; i += 2 {
+			f.DeleteUint64(i)
+		}
+		for i, w := range f.Table {
+			if w != 0 {
+				t.Fatalf("%s: word %d is %x after deleting all items", c.name,
+					i, w)
+			}
+		}
+	}
+}
+
+func TestCuckooFilterFull(t *testing.T) {
+	for _, c := range configs {
+		f, _ := New(1000, c.opts...)
+		var i uint64
+		for ; f.InsertUint64(i) == nil; i++ {
+		}
+		if l := f.LoadFactor(); l < maxLoad(f.BucketSize)-0.05 {
+			t.Errorf("%s: full at load factor %v", c.name, l)
+		}
+		if f.Count() != float64(i) {
+			t.Error(c.name, f.Count(), i)
+		}
+		for j := uint64(0); j < i; j++ {
+			if !f.LookupUint64(j) {
+				t.Fatalf("%s: %d is a false negative", c.name, j)
+			}
+		}
+		// Deleting items makes room for the fingerprint evicted by the
+		// last insertion, and then for new items.
+		for j := uint64(0); j < i/2; j++ {
+			f.DeleteUint64(j)
+		}
+		if err := f.InsertUint64(i); err != nil {
+			t.Error(c.name, err)
+		}
+		for j := i / 2; j <= i; j++ {
+			if !f.LookupUint64(j) {
+				t.Fatalf("%s: %d is a false negative", c.name, j)
+			}
+		}
+	}
+}
+
+func TestCuckooFilterVictim(t *testing.T) {
+	f, _ := New(1000, WithBucketSize(2))
+	var i uint64
+	for ; f.InsertUint64(i) == nil; i++ {
+	}
+	v, vi := f.victim, f.victimIndex
+	if v == 0 {
+		t.Fatal("full filter has no victim")
+	}
+	// Deleting an item from one of the buckets of the victim makes room
+	// for it there.
+	for j := uint64(0); j < i; j++ {
+		// Delete looks in the first bucket of an item first.
+		fp, k := f.locate(f.sum64Uint64(j))
+		if (k == vi || k == f.altIndex(vi, v)) && f.find(k, fp) >= 0 {
+			f.DeleteUint64(j)
+			break
+		}
+	}
+	if f.victim != 0 {
+		t.Fatal("victim was not stored in the free slot")
+	}
+	if err := f.InsertUint64(i); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCuckooFilterFPRate(t *testing.T) {
+	const n = 100000
+	f, _ := New(n, WithFingerprintBits(12))
+	for i := uint64(0); i < n; i++ {
+		f.InsertUint64(i)
+	}
+	var fp int
+	for i := uint64(n); i < 11*n; i++ {
+		if f.LookupUint64(i) {
+			fp++
+		}
+	}
+	rate, want := float64(fp)/(10*n), f.FPRate()
+	if math.Abs(rate-want) > 0.1*want {
+		t.Errorf("false positive rate %v (want %v)", rate, want)
+	}
+}
+
+func TestCuckooFilterTyped(t *testing.T) {
+	f, _ := New(100)
+	f.Insert([]byte("hello"))
+	if !f.LookupString("hello") || !f.Lookup([]byte("hello")) {
+		t.Error("hello is not in the set")
+	}
+	f.InsertString("hello")
+	if !f.Delete([]byte("hello")) || !f.LookupString("hello") {
+		t.Error("hello was inserted twice")
+	}
+	if !f.DeleteString("hello") || f.LookupString("hello") ||
+		f.DeleteString("hello") {
+		t.Error("hello was not deleted")
+	}
+	f.InsertUint64(42)
+	if !f.Lookup([]byte{42, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Error("42 is not in the set")
+	}
+	f.Clear()
+	if f.Count() != 0 || f.LookupUint64(42) {
+		t.Error("Clear did not reset the CuckooFilter")
+	}
+}
+
+func TestCuckooFilterSerialization(t *testing.T) {
+	for _, c := range configs {
+		f, _ := New(100, c.opts...)
+		var i uint64
+		for ; f.InsertUint64(i) == nil; i++ {
+		}
+		buf := make([]byte, f.ByteSize())
+		if err := f.Serialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		d, err := Deserialize(buf, c.opts...)
+		if err != nil {
+			t.Fatal(c.name, err)
+		}
+		if d.N != f.N || d.FingerprintBits != f.FingerprintBits ||
+			d.BucketSize != f.BucketSize || d.SemiSorted != f.SemiSorted {
+			t.Error(c.name, "Did not get back the same CuckooFilter")
+		}
+		for j := uint64(0); j < i; j++ {
+			if !d.DeleteUint64(j) {
+				t.Fatalf("%s: %d is a false negative", c.name, j)
+			}
+		}
+	}
+	f, _ := New(100)
+	f.InsertString("hello")
+	buf := make([]byte, f.ByteSize())
+	f.Serialize(buf)
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[11]++
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[3] = 3
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	for _, c := range configs {
+		cf, _ := New(8, c.opts...)
+		for i := uint64(0); cf.InsertUint64(i) == nil; i++ {
+		}
+		buf := make([]byte, cf.ByteSize())
+		cf.Serialize(buf)
+		f.Add(buf)
+	}
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, c.ByteSize())
+		if err := c.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		n := c.N
+		c.DeleteString("hello")
+		if c.InsertString("hello") == nil && !c.LookupString("hello") {
+			t.Error("hello is not in the set")
+		}
+		if c.N > n+1 {
+			t.Error(c.N, n)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package cuckoo
+
+import (
+	"errors"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrCapacity is returned when the number of items is 0, or so large
+	// that the filter would not fit in memory.
+	ErrCapacity = errors.New("cuckoo: number of items is out of range")
+	// ErrFingerprintBits is returned when the fingerprint size is out of
+	// range.
+	ErrFingerprintBits = errors.New("cuckoo: fingerprint bits must be " +
+		"between 4 and 32")
+	// ErrSmallFingerprint is returned when the fingerprints are too small
+	// for the filter to reach its load factor with that many buckets.
+	ErrSmallFingerprint = errors.New("cuckoo: fingerprint bits are too " +
+		"small for the number of items")
+	// ErrBucketSize is returned when the bucket size is out of range.
+	ErrBucketSize = errors.New("cuckoo: bucket size must be between 1 and 8")
+	// ErrSemiSorting is returned when semi-sorting is requested for
+	// buckets that do not hold 4 fingerprints.
+	ErrSemiSorting = errors.New("cuckoo: semi-sorting requires buckets of 4")
+	// ErrFull is returned by Insert when the filter has no room left for
+	// the item.
+	ErrFull = errors.New("cuckoo: filter is full")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "CuckooFilter", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "CuckooFilter", Reason: reason}
+}
@@ -0,0 +1,37 @@
+package cuckoo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(CuckooFilter)
+	_ driver.Valuer = new(CuckooFilter)
+)
+
+// Value implements driver.Valuer so a CuckooFilter can be stored in a
+// binary (BYTEA/BLOB) column. The stored value is the serialized filter
+// preceded by a tag identifying it as a CuckooFilter.
+func (f *CuckooFilter) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.CuckooFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring a CuckooFilter written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on f, if any, is kept.
+func (f *CuckooFilter) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.CuckooFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(f.hash))
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
@@ -0,0 +1,37 @@
+package cuckoo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/bloom"
+)
+
+func TestCuckooFilterValueScan(t *testing.T) {
+	f, _ := New(100, WithSemiSorting())
+	f.InsertString("hello")
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d CuckooFilter
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.NumBuckets != f.NumBuckets || !d.SemiSorted ||
+		!d.DeleteString("hello") {
+		t.Error("Did not get back the same CuckooFilter")
+	}
+}
+
+func TestCuckooFilterScanError(t *testing.T) {
+	var c CuckooFilter
+	if err := c.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	b, _ := bloom.New(100, 0.01)
+	v, _ := b.Value()
+	if err := c.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
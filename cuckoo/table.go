@@ -0,0 +1,108 @@
+package cuckoo
+
+import "sort"
+
+// maxBucketSize is the largest number of fingerprints in a bucket.
+const maxBucketSize = 8
+
+// bucket holds the fingerprints of a bucket, 0 marking an empty slot.
+type bucket [maxBucketSize]uint32
+
+// semiSortCodes lists the 3876 sorted 4-tuples of 4-bit values, packed
+// in 16 bits in increasing order. A semi-sorted bucket stores the index of
+// the high 4 bits of its sorted fingerprints in this list, 12 bits
+// instead of 16.
+var semiSortCodes = func() []uint16 {
+	var codes []uint16
+	for a := uint16(0); a < 16; a++ {
+		for b := a; b < 16; b++ {
+			for c := b; c < 16; c++ {
+				for d := c; d < 16; d++ {
+					codes = append(codes, a<<12|b<<8|c<<4|d)
+				}
+			}
+		}
+	}
+	return codes
+}()
+
+// getBits returns the w bits of the table at bit pos, w <= 32.
+func (f *CuckooFilter) getBits(pos uint64, w uint8) uint32 {
+	if w == 0 {
+		return 0
+	}
+	i, s := pos/64, pos%64
+	v := f.Table[i] >> s
+	if s+uint64(w) > 64 {
+		v |= f.Table[i+1] << (64 - s)
+	}
+	return uint32(v & (1<<w - 1))
+}
+
+// putBits sets the w bits of the table at bit pos to v, w <= 32.
+func (f *CuckooFilter) putBits(pos uint64, w uint8, v uint32) {
+	if w == 0 {
+		return
+	}
+	i, s := pos/64, pos%64
+	mask := uint64(1)<<w - 1
+	f.Table[i] = f.Table[i]&^(mask<<s) | uint64(v)<<s
+	if s+uint64(w) > 64 {
+		f.Table[i+1] = f.Table[i+1]&^(mask>>(64-s)) | uint64(v)>>(64-s)
+	}
+}
+
+// bucketBits returns the number of bits of a bucket.
+func (f *CuckooFilter) bucketBits() uint64 {
+	if f.SemiSorted {
+		return 4*uint64(f.FingerprintBits) - 4
+	}
+	return uint64(f.BucketSize) * uint64(f.FingerprintBits)
+}
+
+// bucket returns the fingerprints of bucket i.
+func (f *CuckooFilter) bucket(i uint64) bucket {
+	var b bucket
+	pos := i * f.bucketBits()
+	if !f.SemiSorted {
+		for j := uint8(0); j < f.BucketSize; j++ {
+			b[j] = f.getBits(pos+uint64(j)*uint64(f.FingerprintBits),
+				f.FingerprintBits)
+		}
+		return b
+	}
+	code := semiSortCodes[f.getBits(pos, 12)]
+	low := f.FingerprintBits - 4
+	for j := 0; j < 4; j++ {
+		high := uint32(code>>(12-4*j)) & 0xf
+		b[j] = high<<low | f.getBits(pos+12+uint64(j)*uint64(low), low)
+	}
+	return b
+}
+
+// setBucket sets the fingerprints of bucket i to those of b.
+func (f *CuckooFilter) setBucket(i uint64, b bucket) {
+	pos := i * f.bucketBits()
+	if !f.SemiSorted {
+		for j := uint8(0); j < f.BucketSize; j++ {
+			f.putBits(pos+uint64(j)*uint64(f.FingerprintBits),
+				f.FingerprintBits, b[j])
+		}
+		return
+	}
+	fps := b[:4]
+	for j := 1; j < len(fps); j++ {
+		for k := j; k > 0 && fps[k] < fps[k-1]; k-- {
+			fps[k], fps[k-1] = fps[k-1], fps[k]
+		}
+	}
+	low := f.FingerprintBits - 4
+	var code uint16
+	for j, fp := range fps {
+		code |= uint16(fp>>low) << (12 - 4*j)
+		f.putBits(pos+12+uint64(j)*uint64(low), low, fp&(1<<low-1))
+	}
+	f.putBits(pos, 12, uint32(sort.Search(len(semiSortCodes), func(j int) bool {
+		return semiSortCodes[j] >= code
+	})))
+}
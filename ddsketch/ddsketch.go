@@ -0,0 +1,303 @@
+// Package ddsketch implements DDSketch, which estimates quantiles of a
+// stream of numbers within a relative error, so values spanning many
+// orders of magnitude, such as latencies, are all estimated accurately.
+//
+// DDSketch: A Fast and Fully-Mergeable Quantile Sketch with
+// Relative-Error Guarantees:
+// https://www.vldb.org/pvldb/vol12/p2195-masson.pdf
+package ddsketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*DDSketch] = new(DDSketch)
+	_ datasketch.Serializable         = new(DDSketch)
+)
+
+// MinRelativeAccuracy is the smallest relative accuracy, which keeps the
+// bucket indices of all finite values within 32 bits.
+const MinRelativeAccuracy = 1e-6
+
+// DDSketch data structure. Positive values are counted in the buckets of
+// the mapping, negative values in the buckets of their absolute value in
+// another store, and zeros apart.
+type DDSketch struct {
+	Mapping *LogarithmicMapping
+	// N is the total weight of the values.
+	N float64
+	// Min and Max are the smallest and largest values, valid if N > 0.
+	Min, Max float64
+	// Sum is the weighted sum of the values.
+	Sum float64
+	// ZeroCount is the total weight of the zeros.
+	ZeroCount float64
+
+	positive, negative store
+	kind               storeKind
+	maxBins            int
+}
+
+// Option configures a DDSketch created by New.
+type Option func(*DDSketch)
+
+// WithSparseStore keeps the buckets in maps rather than arrays, which
+// takes less memory when the values are scattered over a wide range.
+func WithSparseStore() Option {
+	return func(s *DDSketch) {
+		s.kind, s.maxBins = sparse, 0
+	}
+}
+
+// WithCollapsingLowest bounds the memory of the DDSketch to maxBins
+// buckets for positive values and as many for negative values. When more
+// are needed, the buckets of the values closest to zero are merged, so
+// only the quantiles of those values lose their accuracy.
+func WithCollapsingLowest(maxBins int) Option {
+	return func(s *DDSketch) {
+		s.kind, s.maxBins = collapsingLowest, maxBins
+	}
+}
+
+// WithCollapsingHighest is like WithCollapsingLowest, but merges the
+// buckets of the values farthest from zero.
+func WithCollapsingHighest(maxBins int) Option {
+	return func(s *DDSketch) {
+		s.kind, s.maxBins = collapsingHighest, maxBins
+	}
+}
+
+// New returns an empty DDSketch whose quantiles are within a relative
+// error of relativeAccuracy, e.g. 0.01 for 1%. Its buckets are kept in
+// arrays growing as needed unless opts say otherwise.
+func New(relativeAccuracy float64, opts ...Option) (*DDSketch, error) {
+	m, err := NewLogarithmicMapping(relativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	s := &DDSketch{Mapping: m}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.kind != dense && s.kind != sparse &&
+		(s.maxBins <= 0 || s.maxBins > math.MaxInt32) {
+		return nil, ErrMaxBins
+	}
+	s.positive = newStore(s.kind, s.maxBins)
+	s.negative = newStore(s.kind, s.maxBins)
+	return s, nil
+}
+
+// Clear sets the DDSketch back to its initial state.
+func (s *DDSketch) Clear() {
+	s.N, s.Min, s.Max, s.Sum, s.ZeroCount = 0, 0, 0, 0, 0
+	s.positive.clear()
+	s.negative.clear()
+}
+
+// Add adds the value x. NaNs and infinities are ignored.
+func (s *DDSketch) Add(x float64) {
+	s.AddWeighted(x, 1)
+}
+
+// AddWeighted adds the value x with weight w, as if x were added w times.
+// NaNs and infinities, and weights that are not positive and finite, are
+// ignored.
+func (s *DDSketch) AddWeighted(x, w float64) {
+	if math.IsNaN(x) || math.IsInf(x, 0) || !(w > 0) || math.IsInf(w, 0) {
+		return
+	}
+	switch {
+	case x > 0:
+		s.positive.add(s.Mapping.Index(x), w)
+	case x < 0:
+		s.negative.add(s.Mapping.Index(-x), w)
+	default:
+		s.ZeroCount += w
+	}
+	if s.N == 0 || x < s.Min {
+		s.Min = x
+	}
+	if s.N == 0 || x > s.Max {
+		s.Max = x
+	}
+	s.N += w
+	s.Sum += x * w
+}
+
+// Quantile returns the estimated q-quantile, within the relative accuracy
+// of the true one unless its bucket was collapsed. Quantile(0) and
+// Quantile(1) are Min and Max.
+func (s *DDSketch) Quantile(q float64) (float64, error) {
+	if !(q >= 0 && q <= 1) {
+		return 0, ErrQuantile
+	}
+	if s.N == 0 {
+		return 0, ErrEmpty
+	}
+	if q == 0 {
+		return s.Min, nil
+	}
+	if q == 1 {
+		return s.Max, nil
+	}
+	rank := q * (s.N - 1)
+	negatives := s.negative.total()
+	var x float64
+	switch {
+	case rank < negatives:
+		// The lowest negative values have the highest indices. Ranks are
+		// rounded down on both sides of zero.
+		x = -s.Mapping.Value(s.negative.keyAtRank(negatives - 1 -
+			math.Floor(rank)))
+	case rank < negatives+s.ZeroCount:
+		x = 0
+	default:
+		x = s.Mapping.Value(s.positive.keyAtRank(rank - negatives -
+			s.ZeroCount))
+	}
+	return math.Max(s.Min, math.Min(x, s.Max)), nil
+}
+
+// Merge takes another DDSketch and combines it with s, making s the
+// summary of both streams. Both must have the same relative accuracy. s
+// keeps its stores.
+func (s *DDSketch) Merge(other *DDSketch) error {
+	a, b := s.Mapping.RelativeAccuracy, other.Mapping.RelativeAccuracy
+	if a != b {
+		return &AccuracyMismatchError{a, b}
+	}
+	if other.N == 0 {
+		return nil
+	}
+	if s.N == 0 || other.Min < s.Min {
+		s.Min = other.Min
+	}
+	if s.N == 0 || other.Max > s.Max {
+		s.Max = other.Max
+	}
+	s.N += other.N
+	s.Sum += other.Sum
+	s.ZeroCount += other.ZeroCount
+	other.positive.forEach(s.positive.add)
+	other.negative.forEach(s.negative.add)
+	return nil
+}
+
+// headerSize is the size of the serialized DDSketch before its stores.
+const headerSize = 5 * 8
+
+// ByteSize returns the size of the serialized object.
+func (s *DDSketch) ByteSize() int {
+	return headerSize + s.positive.byteSize() + s.negative.byteSize()
+}
+
+// Serialize the DDSketch to bytes stored in buffer: the relative
+// accuracy, ZeroCount, Min, Max and Sum, then the stores of the positive
+// and the negative values, each made of its kind, maximum of buckets and
+// counts.
+func (s *DDSketch) Serialize(buffer []byte) error {
+	if len(buffer) < s.ByteSize() {
+		return shortBuffer(s.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	for i, v := range []float64{s.Mapping.RelativeAccuracy, s.ZeroCount,
+		s.Min, s.Max, s.Sum} {
+		b.PutUint64(buffer[8*i:], math.Float64bits(v))
+	}
+	s.positive.serialize(buffer[headerSize:])
+	s.negative.serialize(buffer[headerSize+s.positive.byteSize():])
+	return nil
+}
+
+// Deserialize reconstructs a DDSketch from the buffer
+func Deserialize(buffer []byte) (*DDSketch, error) {
+	if len(buffer) < headerSize {
+		return nil, shortBuffer(headerSize, len(buffer))
+	}
+	b := binary.LittleEndian
+	var header [5]float64
+	for i := range header {
+		header[i] = math.Float64frombits(b.Uint64(buffer[8*i:]))
+	}
+	m, err := NewLogarithmicMapping(header[0])
+	if err != nil {
+		return nil, corrupt(fmt.Sprintf("relative accuracy %v is not "+
+			"between %v and 1", header[0], MinRelativeAccuracy))
+	}
+	s := &DDSketch{Mapping: m, ZeroCount: header[1], Min: header[2],
+		Max: header[3], Sum: header[4]}
+	if !(s.ZeroCount >= 0) || math.IsInf(s.ZeroCount, 0) {
+		return nil, corrupt(fmt.Sprintf("zero count %v is not positive and "+
+			"finite", s.ZeroCount))
+	}
+	offset := headerSize
+	if s.positive, offset, err = deserializeStore(buffer, offset); err != nil {
+		return nil, err
+	}
+	if s.negative, _, err = deserializeStore(buffer, offset); err != nil {
+		return nil, err
+	}
+	s.kind, s.maxBins = storeKindOf(s.positive)
+	if kind, maxBins := storeKindOf(s.negative); kind != s.kind ||
+		maxBins != s.maxBins {
+		return nil, corrupt("stores are of different kinds")
+	}
+	s.N = s.ZeroCount + s.positive.total() + s.negative.total()
+	if math.IsInf(s.N, 0) {
+		return nil, corrupt("total count is not finite")
+	}
+	if s.N == 0 {
+		if s.Min != 0 || s.Max != 0 {
+			return nil, corrupt("an empty sketch has a minimum or maximum")
+		}
+		return s, nil
+	}
+	if !(s.Min <= s.Max) || math.IsInf(s.Min, 0) || math.IsInf(s.Max, 0) {
+		return nil, corrupt("minimum and maximum are not finite and ordered")
+	}
+	if s.ZeroCount > 0 && (s.Min > 0 || s.Max < 0) {
+		return nil, corrupt("zeros are outside of the minimum and maximum")
+	}
+	if !s.inBounds(s.positive, s.Min, s.Max) ||
+		!s.inBounds(s.negative, -s.Max, -s.Min) {
+		return nil, corrupt("buckets do not match the minimum and maximum")
+	}
+	return s, nil
+}
+
+// inBounds reports whether the buckets of st can hold the absolute values
+// between lo and hi that it counts. Buckets may have been collapsed
+// inwards, by st or by a sketch merged into s.
+func (s *DDSketch) inBounds(st store, lo, hi float64) bool {
+	if st.total() == 0 {
+		return hi <= 0
+	}
+	if hi <= 0 {
+		return false
+	}
+	first, last := math.MaxInt, math.MinInt
+	st.forEach(func(index int, _ float64) {
+		if index < first {
+			first = index
+		}
+		last = index
+	})
+	return last <= s.Mapping.Index(hi) &&
+		(lo <= 0 || first >= s.Mapping.Index(lo))
+}
+
+// storeKindOf returns the kind and maximum of buckets of st, which New
+// would have been configured with.
+func storeKindOf(st store) (storeKind, int) {
+	if d, ok := st.(*denseStore); ok {
+		return d.kind, d.maxBins
+	}
+	return sparse, 0
+}
@@ -0,0 +1,298 @@
+package ddsketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// stream returns n values spanning 16 orders of magnitude, a tenth of
+// them negative and a few zeros.
+func stream(seed int64, n int) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.Exp(r.Float64()*36 - 18)
+		switch r.Intn(100) {
+		case 0:
+			values[i] = 0
+		case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10:
+			values[i] = -values[i]
+		}
+	}
+	return values
+}
+
+// checkQuantiles fails t if the quantiles of s in [lo, hi] are not within
+// the relative accuracy of those of the sorted values.
+func checkQuantiles(t *testing.T, s *DDSketch, sorted []float64, lo,
+	hi float64) {
+	t.Helper()
+	a := s.Mapping.RelativeAccuracy
+	for q := lo; q <= hi; q += 0.001 {
+		want := sorted[int(q*float64(len(sorted)-1))]
+		got, err := s.Quantile(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-want) > a*math.Abs(want)*(1+1e-9) {
+			t.Fatalf("quantile %v is %v (want %v)", q, got, want)
+		}
+	}
+}
+
+func TestDDSketchNew(t *testing.T) {
+	for _, a := range []float64{0, 1e-7, 1, math.NaN()} {
+		if _, err := New(a); !errors.Is(err, ErrRelativeAccuracy) {
+			t.Error(a, err)
+		}
+	}
+	if _, err := New(0.01, WithCollapsingLowest(0)); !errors.Is(err,
+		ErrMaxBins) {
+		t.Error(err)
+	}
+	s, err := New(0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Quantile(0.5); !errors.Is(err, ErrEmpty) {
+		t.Error(err)
+	}
+	s.Add(1)
+	for _, q := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := s.Quantile(q); !errors.Is(err, ErrQuantile) {
+			t.Error(q, err)
+		}
+	}
+}
+
+func TestDDSketchRelativeAccuracy(t *testing.T) {
+	values := stream(1, 100000)
+	for _, opts := range [][]Option{nil, {WithSparseStore()}} {
+		s, _ := New(0.01, opts...)
+		for _, v := range values {
+			s.Add(v)
+		}
+		s.Add(math.NaN())
+		s.Add(math.Inf(-1))
+		s.AddWeighted(1, -1)
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if s.N != float64(len(values)) || s.Min != sorted[0] ||
+			s.Max != sorted[len(sorted)-1] || math.Abs(s.Sum-sum) > 1e-9*
+			math.Abs(sum) {
+			t.Error(s.N, s.Min, s.Max, s.Sum)
+		}
+		checkQuantiles(t, s, sorted, 0, 1)
+		if x, _ := s.Quantile(0); x != s.Min {
+			t.Error(x)
+		}
+		if x, _ := s.Quantile(1); x != s.Max {
+			t.Error(x)
+		}
+	}
+}
+
+func TestDDSketchWeighted(t *testing.T) {
+	s, _ := New(0.01)
+	s.AddWeighted(-5, 1)
+	s.AddWeighted(0, 2)
+	s.AddWeighted(100, 7)
+	if s.N != 10 || s.ZeroCount != 2 || s.Sum != 695 {
+		t.Error(s.N, s.ZeroCount, s.Sum)
+	}
+	for _, c := range []struct{ q, want float64 }{
+		{0, -5}, {0.15, 0}, {0.25, 0}, {0.5, 100}, {1, 100},
+	} {
+		got, _ := s.Quantile(c.q)
+		if math.Abs(got-c.want) > 0.01*math.Abs(c.want) {
+			t.Error(c.q, got)
+		}
+	}
+	s.Clear()
+	if s.N != 0 || s.positive.bins() != 0 {
+		t.Error("Clear did not reset the DDSketch")
+	}
+}
+
+func TestDDSketchCollapsing(t *testing.T) {
+	values := stream(1, 100000)
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	// 36 orders of magnitude of e need about 1800 buckets at 1%.
+	lowest, _ := New(0.01, WithCollapsingLowest(1000))
+	highest, _ := New(0.01, WithCollapsingHighest(1000))
+	for _, v := range values {
+		lowest.Add(v)
+		highest.Add(v)
+	}
+	for _, s := range []*DDSketch{lowest, highest} {
+		if s.positive.bins() > 1000 || s.negative.bins() > 1000 {
+			t.Error(s.positive.bins(), s.negative.bins())
+		}
+	}
+	// The largest positive values and the smallest negative ones, those
+	// farthest from zero, keep their accuracy.
+	checkQuantiles(t, lowest, sorted, 0, 0.05)
+	checkQuantiles(t, lowest, sorted, 0.7, 1)
+	checkQuantiles(t, highest, sorted, 0.14, 0.6)
+	x, _ := highest.Quantile(0.999)
+	if want := sorted[int(0.999*float64(len(sorted)-1))]; x > want/10 {
+		t.Errorf("collapsed quantile %v is close to %v", x, want)
+	}
+}
+
+func TestDDSketchMerge(t *testing.T) {
+	values := stream(1, 100000)
+	all, _ := New(0.01)
+	parts := make([]*DDSketch, 4)
+	for i := range parts {
+		parts[i], _ = New(0.01, WithSparseStore())
+	}
+	for i, v := range values {
+		all.Add(v)
+		parts[i%len(parts)].Add(v)
+	}
+	merged, _ := New(0.01)
+	for _, p := range parts {
+		if err := merged.Merge(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if merged.N != all.N || merged.Min != all.Min || merged.Max != all.Max {
+		t.Error(merged.N, merged.Min, merged.Max)
+	}
+	for q := 0.0; q <= 1; q += 0.01 {
+		x, _ := merged.Quantile(q)
+		y, _ := all.Quantile(q)
+		if x != y {
+			t.Fatal(q, x, y)
+		}
+	}
+
+	other, _ := New(0.02)
+	var mismatch *AccuracyMismatchError
+	if err := merged.Merge(other); !errors.As(err, &mismatch) ||
+		mismatch.OtherRelativeAccuracy != 0.02 {
+		t.Error(err)
+	}
+	if err := all.Merge(all); err != nil || all.N != 2*merged.N {
+		t.Error(all.N, err)
+	}
+}
+
+func TestDDSketchSerialization(t *testing.T) {
+	values := stream(1, 1000)
+	for _, opts := range [][]Option{nil, {WithSparseStore()},
+		{WithCollapsingLowest(100)}, {WithCollapsingHighest(100)}} {
+		s, _ := New(0.02, opts...)
+		for _, v := range values {
+			s.Add(v)
+		}
+		buf := make([]byte, s.ByteSize())
+		if err := s.Serialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		d, err := Deserialize(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.N != s.N || d.Min != s.Min || d.Max != s.Max || d.Sum != s.Sum ||
+			d.kind != s.kind || d.maxBins != s.maxBins {
+			t.Error("Did not get back the same DDSketch")
+		}
+		for _, q := range []float64{0, 0.1, 0.5, 0.9, 1} {
+			x, _ := s.Quantile(q)
+			y, _ := d.Quantile(q)
+			if x != y {
+				t.Error(q, x, y)
+			}
+		}
+		if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err,
+			ErrShortBuffer) {
+			t.Error(err)
+		}
+	}
+
+	// A sketch whose top buckets were collapsed by another one round-trips.
+	collapsed, _ := New(0.02, WithCollapsingHighest(10))
+	for _, v := range values {
+		collapsed.Add(v)
+	}
+	merged, _ := New(0.02)
+	merged.Merge(collapsed)
+	buf := make([]byte, merged.ByteSize())
+	merged.Serialize(buf)
+	if _, err := Deserialize(buf); err != nil {
+		t.Error(err)
+	}
+
+	s, _ := New(0.02)
+	s.Add(1)
+	buf = make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	if err := s.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	bad := append([]byte(nil), buf...)
+	bad[7] = 0xff
+	if _, err := Deserialize(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad = append([]byte(nil), buf...)
+	bad[headerSize] = 9
+	if _, err := Deserialize(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf = make([]byte, collapsed.ByteSize())
+	collapsed.Serialize(buf)
+	binary.LittleEndian.PutUint32(buf[headerSize+1:], math.MaxUint32)
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	for _, opts := range [][]Option{nil, {WithSparseStore()},
+		{WithCollapsingLowest(8)}} {
+		s, _ := New(0.05, opts...)
+		for _, v := range stream(1, 100) {
+			s.Add(v)
+		}
+		buf := make([]byte, s.ByteSize())
+		s.Serialize(buf)
+		f.Add(buf)
+	}
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		if s.N > 0 {
+			x, err := s.Quantile(0.5)
+			if err != nil || x < s.Min || x > s.Max {
+				t.Error(x, err)
+			}
+		}
+		// Adding values far from the others could take a dense store's
+		// whole memory.
+		if err := s.Merge(s); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package ddsketch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrRelativeAccuracy is returned when the relative accuracy is not
+	// between MinRelativeAccuracy and 1.
+	ErrRelativeAccuracy = errors.New("ddsketch: relative accuracy must be " +
+		"between 1e-06 and 1")
+	// ErrMaxBins is returned when a collapsing store has no buckets.
+	ErrMaxBins = errors.New("ddsketch: maximum of bins must be positive")
+	// ErrEmpty is returned when the quantiles of an empty DDSketch are
+	// queried.
+	ErrEmpty = errors.New("ddsketch: sketch is empty")
+	// ErrQuantile is returned when a quantile is not between 0 and 1.
+	ErrQuantile = errors.New("ddsketch: quantile must be between 0 and 1")
+	// ErrAccuracyMismatch is returned when DDSketches of different
+	// relative accuracies are merged. It is wrapped by
+	// AccuracyMismatchError.
+	ErrAccuracyMismatch = errors.New("ddsketch: relative accuracies do " +
+		"not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// AccuracyMismatchError reports the relative accuracies of two
+// DDSketches that cannot be merged.
+type AccuracyMismatchError struct {
+	RelativeAccuracy, OtherRelativeAccuracy float64
+}
+
+func (e *AccuracyMismatchError) Error() string {
+	return fmt.Sprintf("ddsketch: relative accuracies do not match: %v != %v",
+		e.RelativeAccuracy, e.OtherRelativeAccuracy)
+}
+
+func (e *AccuracyMismatchError) Unwrap() error { return ErrAccuracyMismatch }
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "DDSketch", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "DDSketch", Reason: reason}
+}
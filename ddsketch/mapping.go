@@ -0,0 +1,38 @@
+package ddsketch
+
+import "math"
+
+// LogarithmicMapping maps positive values to the indices of buckets whose
+// bounds grow geometrically by gamma = (1+a)/(1-a), where a is the
+// relative accuracy. Bucket i holds the values in (gamma^(i-1), gamma^i]
+// and any of them is within a relative error of a of the bucket's Value.
+type LogarithmicMapping struct {
+	RelativeAccuracy float64
+
+	gamma      float64
+	multiplier float64
+}
+
+// NewLogarithmicMapping returns the mapping with the relative accuracy a.
+func NewLogarithmicMapping(a float64) (*LogarithmicMapping, error) {
+	if !(a >= MinRelativeAccuracy && a < 1) {
+		return nil, ErrRelativeAccuracy
+	}
+	gamma := (1 + a) / (1 - a)
+	return &LogarithmicMapping{
+		RelativeAccuracy: a,
+		gamma:            gamma,
+		multiplier:       1 / math.Log(gamma),
+	}, nil
+}
+
+// Index returns the index of the bucket holding the positive value x.
+func (m *LogarithmicMapping) Index(x float64) int {
+	return int(math.Ceil(math.Log(x) * m.multiplier))
+}
+
+// Value returns the representative of bucket i, the value with the same
+// relative distance to both its bounds.
+func (m *LogarithmicMapping) Value(i int) float64 {
+	return math.Exp(float64(i)/m.multiplier) * 2 / (1 + m.gamma)
+}
@@ -0,0 +1,36 @@
+package ddsketch
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(DDSketch)
+	_ driver.Valuer = new(DDSketch)
+)
+
+// Value implements driver.Valuer so a DDSketch can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized DDSketch
+// preceded by a tag identifying it as a DDSketch.
+func (s *DDSketch) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.DDSketch, s)
+}
+
+// Scan implements sql.Scanner, restoring a DDSketch written by Value.
+// Blobs holding any other kind of sketch are rejected.
+func (s *DDSketch) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.DDSketch, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer)
+	if err != nil {
+		return err
+	}
+	*s = *other
+	return nil
+}
@@ -0,0 +1,38 @@
+package ddsketch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestDDSketchValueScan(t *testing.T) {
+	s, _ := New(0.01)
+	for i := 0; i < 1000; i++ {
+		s.Add(float64(i))
+	}
+	v, err := s.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d DDSketch
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.N != s.N || d.Min != s.Min || d.Max != s.Max {
+		t.Error("Did not get back the same DDSketch")
+	}
+}
+
+func TestDDSketchScanError(t *testing.T) {
+	var s DDSketch
+	if err := s.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	h, _ := hyperloglog.New(4)
+	v, _ := h.Value()
+	if err := s.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
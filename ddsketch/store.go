@@ -0,0 +1,343 @@
+package ddsketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// storeKind identifies how a store keeps its buckets. Values are
+// persisted.
+type storeKind uint8
+
+const (
+	// dense stores keep a counter for every index between the lowest and
+	// the highest.
+	dense storeKind = iota
+	// collapsingLowest and collapsingHighest are dense stores of at most
+	// maxBins counters, which fold the lowest, or highest, indices into
+	// the next ones when more would be needed.
+	collapsingLowest
+	collapsingHighest
+	// sparse stores keep the counters of the indices seen in a map.
+	sparse
+)
+
+// store counts the values in the buckets of a DDSketch.
+type store interface {
+	add(index int, count float64)
+	// total returns the sum of all the counts.
+	total() float64
+	// keyAtRank returns the lowest index whose cumulative count exceeds
+	// rank.
+	keyAtRank(rank float64) int
+	// forEach calls f with every index holding a count, in increasing
+	// order.
+	forEach(f func(index int, count float64))
+	clear()
+	// bins returns the number of counters kept.
+	bins() int
+	byteSize() int
+	serialize(buffer []byte)
+}
+
+func newStore(kind storeKind, maxBins int) store {
+	if kind == sparse {
+		return &sparseStore{counts: make(map[int]float64)}
+	}
+	return &denseStore{kind: kind, maxBins: maxBins}
+}
+
+// denseStore keeps the counts of the indices from offset to
+// offset+len(counts)-1. counts is a window of buf, which has zeros around
+// it to grow into, so that adding ever lower or higher indices does not
+// copy the counts every time.
+type denseStore struct {
+	kind    storeKind
+	maxBins int
+
+	buf    []float64
+	start  int
+	counts []float64
+	offset int
+	count  float64
+}
+
+func (s *denseStore) add(index int, count float64) {
+	lo, hi := index, index
+	if len(s.counts) > 0 {
+		lo, hi = s.offset, s.offset+len(s.counts)-1
+		if index < lo {
+			lo = index
+		}
+		if index > hi {
+			hi = index
+		}
+	}
+	if s.kind != dense && hi-lo+1 > s.maxBins {
+		if s.kind == collapsingLowest {
+			lo = hi - s.maxBins + 1
+			if index < lo {
+				index = lo
+			}
+		} else {
+			hi = lo + s.maxBins - 1
+			if index > hi {
+				index = hi
+			}
+		}
+	}
+	s.resize(lo, hi)
+	s.counts[index-s.offset] += count
+	s.count += count
+}
+
+// resize makes the store cover exactly the indices lo to hi, adding the
+// counts of the indices left out to the nearest one kept. It reallocates
+// buf only when the indices do not fit in it, doubling its size.
+func (s *denseStore) resize(lo, hi int) {
+	n := len(s.counts)
+	if n > 0 && lo == s.offset && hi == s.offset+n-1 {
+		return
+	}
+	var below, above float64
+	for i, c := range s.counts {
+		if j := s.offset + i; j < lo {
+			below += c
+		} else if j > hi {
+			above += c
+		} else {
+			continue
+		}
+		s.counts[i] = 0
+	}
+	size := hi - lo + 1
+	// bufLo is the index held by buf[0], placing an empty store in the
+	// middle of buf.
+	bufLo := lo - (len(s.buf)-size)/2
+	if n > 0 {
+		bufLo = s.offset - s.start
+	}
+	if lo < bufLo || hi >= bufLo+len(s.buf) {
+		l := 2 * len(s.buf)
+		if s.kind != dense && l > s.maxBins {
+			l = s.maxBins
+		}
+		if l < size {
+			l = size
+		}
+		buf := make([]float64, l)
+		newLo := lo - (l-size)/2
+		for i, c := range s.counts {
+			if j := s.offset + i; j >= lo && j <= hi {
+				buf[j-newLo] = c
+			}
+		}
+		s.buf, bufLo = buf, newLo
+	}
+	s.start, s.offset = lo-bufLo, lo
+	s.counts = s.buf[s.start : s.start+size]
+	s.counts[0] += below
+	s.counts[size-1] += above
+}
+
+func (s *denseStore) total() float64 { return s.count }
+
+func (s *denseStore) keyAtRank(rank float64) int {
+	var cum float64
+	for i, c := range s.counts {
+		cum += c
+		if cum > rank {
+			return s.offset + i
+		}
+	}
+	return s.offset + len(s.counts) - 1
+}
+
+func (s *denseStore) forEach(f func(index int, count float64)) {
+	for i, c := range s.counts {
+		if c > 0 {
+			f(s.offset+i, c)
+		}
+	}
+}
+
+func (s *denseStore) clear() {
+	for i := range s.counts {
+		s.counts[i] = 0
+	}
+	s.counts, s.offset, s.count = s.counts[:0], 0, 0
+}
+
+func (s *denseStore) bins() int { return len(s.counts) }
+
+func (s *denseStore) byteSize() int { return 1 + 4 + 4 + 4 + 8*len(s.counts) }
+
+// serialize writes the kind, maxBins, offset and number of counters, then
+// the counters.
+func (s *denseStore) serialize(buffer []byte) {
+	b := binary.LittleEndian
+	buffer[0] = byte(s.kind)
+	b.PutUint32(buffer[1:], uint32(s.maxBins))
+	b.PutUint32(buffer[5:], uint32(int32(s.offset)))
+	b.PutUint32(buffer[9:], uint32(len(s.counts)))
+	for i, c := range s.counts {
+		b.PutUint64(buffer[13+8*i:], math.Float64bits(c))
+	}
+}
+
+// sparseStore keeps the counts of the indices seen.
+type sparseStore struct {
+	counts map[int]float64
+	count  float64
+}
+
+func (s *sparseStore) add(index int, count float64) {
+	s.counts[index] += count
+	s.count += count
+}
+
+func (s *sparseStore) total() float64 { return s.count }
+
+// keys returns the indices holding a count in increasing order.
+func (s *sparseStore) keys() []int {
+	keys := make([]int, 0, len(s.counts))
+	for i := range s.counts {
+		keys = append(keys, i)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func (s *sparseStore) keyAtRank(rank float64) int {
+	keys := s.keys()
+	var cum float64
+	for _, i := range keys {
+		cum += s.counts[i]
+		if cum > rank {
+			return i
+		}
+	}
+	return keys[len(keys)-1]
+}
+
+func (s *sparseStore) forEach(f func(index int, count float64)) {
+	for _, i := range s.keys() {
+		f(i, s.counts[i])
+	}
+}
+
+func (s *sparseStore) clear() {
+	s.counts, s.count = make(map[int]float64), 0
+}
+
+func (s *sparseStore) bins() int { return len(s.counts) }
+
+func (s *sparseStore) byteSize() int { return 1 + 4 + 4 + 12*len(s.counts) }
+
+// serialize writes the kind, a zero maxBins and the number of counters,
+// then the index and count of every counter in increasing order of index.
+func (s *sparseStore) serialize(buffer []byte) {
+	b := binary.LittleEndian
+	buffer[0] = byte(sparse)
+	b.PutUint32(buffer[1:], 0)
+	b.PutUint32(buffer[5:], uint32(len(s.counts)))
+	offset := 9
+	s.forEach(func(index int, count float64) {
+		b.PutUint32(buffer[offset:], uint32(int32(index)))
+		b.PutUint64(buffer[offset+4:], math.Float64bits(count))
+		offset += 12
+	})
+}
+
+// deserializeStore reads a store written by serialize at offset in
+// buffer, returning it and the offset of the bytes following it.
+func deserializeStore(whole []byte, start int) (store, int, error) {
+	buffer := whole[start:]
+	if len(buffer) < 9 {
+		return nil, 0, shortBuffer(start+9, len(whole))
+	}
+	b := binary.LittleEndian
+	kind, maxBins := storeKind(buffer[0]), int(b.Uint32(buffer[1:]))
+	validCount := func(c float64) error {
+		if !(c >= 0) || math.IsInf(c, 0) {
+			return corrupt(fmt.Sprintf("count %v is not positive and finite",
+				c))
+		}
+		return nil
+	}
+	switch kind {
+	case sparse:
+		if maxBins != 0 {
+			return nil, 0, corrupt("a sparse store has a maximum of bins")
+		}
+		n := uint64(b.Uint32(buffer[5:]))
+		if need := 9 + 12*n; uint64(len(buffer)) < need {
+			return nil, 0, shortBuffer(start+int(need), len(whole))
+		}
+		s := newStore(sparse, 0).(*sparseStore)
+		offset, last := 9, 0
+		for i := uint64(0); i < n; i++ {
+			index := int(int32(b.Uint32(buffer[offset:])))
+			c := math.Float64frombits(b.Uint64(buffer[offset+4:]))
+			offset += 12
+			if err := validCount(c); err != nil {
+				return nil, 0, err
+			}
+			if c == 0 {
+				return nil, 0, corrupt("a sparse store holds an empty bucket")
+			}
+			if i > 0 && index <= last {
+				return nil, 0, corrupt("indices are out of order")
+			}
+			s.add(index, c)
+			last = index
+		}
+		return s, start + offset, nil
+	case dense, collapsingLowest, collapsingHighest:
+		if len(buffer) < 13 {
+			return nil, 0, shortBuffer(start+13, len(whole))
+		}
+		if kind == dense && maxBins != 0 || kind != dense &&
+			(maxBins == 0 || maxBins > math.MaxInt32) {
+			return nil, 0, corrupt(fmt.Sprintf("a store of kind %d has a "+
+				"maximum of %d bins", kind, maxBins))
+		}
+		lo := int64(int32(b.Uint32(buffer[5:])))
+		n := uint64(b.Uint32(buffer[9:]))
+		if lo+int64(n) > math.MaxInt32+1 {
+			return nil, 0, corrupt("indices overflow")
+		}
+		if kind != dense && n > uint64(maxBins) {
+			return nil, 0, corrupt(fmt.Sprintf("%d bins are more than the "+
+				"maximum of %d", n, maxBins))
+		}
+		if need := 13 + 8*n; uint64(len(buffer)) < need {
+			return nil, 0, shortBuffer(start+int(need), len(whole))
+		}
+		s := newStore(kind, maxBins).(*denseStore)
+		if n == 0 {
+			if lo != 0 {
+				return nil, 0, corrupt("an empty store has an offset")
+			}
+			return s, start + 13, nil
+		}
+		s.offset = int(lo)
+		s.buf = make([]float64, n)
+		s.counts = s.buf
+		for i := range s.counts {
+			c := math.Float64frombits(b.Uint64(buffer[13+8*i:]))
+			if err := validCount(c); err != nil {
+				return nil, 0, err
+			}
+			s.counts[i] = c
+			s.count += c
+		}
+		if s.counts[0] == 0 || s.counts[n-1] == 0 {
+			return nil, 0, corrupt("a dense store has empty end buckets")
+		}
+		return s, start + 13 + 8*int(n), nil
+	}
+	return nil, 0, corrupt(fmt.Sprintf("store kind %d is unknown", kind))
+}
@@ -0,0 +1,105 @@
+package ddsketch
+
+import "testing"
+
+func TestDenseStoreCollapse(t *testing.T) {
+	lowest := newStore(collapsingLowest, 3)
+	highest := newStore(collapsingHighest, 3)
+	for _, s := range []store{lowest, highest} {
+		for i := -2; i <= 2; i++ {
+			s.add(i, float64(i+3))
+		}
+		if s.bins() != 3 || s.total() != 15 {
+			t.Error(s.bins(), s.total())
+		}
+	}
+	// Indices -2 and -1 were folded into 0, and 1 and 2 into 0.
+	for _, c := range []struct {
+		s     store
+		index int
+		count float64
+	}{{lowest, 0, 6}, {highest, 0, 12}} {
+		var count float64
+		c.s.forEach(func(index int, n float64) {
+			if index == c.index {
+				count = n
+			}
+		})
+		if count != c.count {
+			t.Error(c.index, count)
+		}
+	}
+	if k := lowest.keyAtRank(5.5); k != 0 {
+		t.Error(k)
+	}
+	if k := lowest.keyAtRank(6); k != 1 {
+		t.Error(k)
+	}
+	if k := highest.keyAtRank(0.5); k != -2 {
+		t.Error(k)
+	}
+}
+
+func TestStoresAgree(t *testing.T) {
+	d, s := newStore(dense, 0), newStore(sparse, 0)
+	for _, i := range []int{5, -3, 5, 100, 0, -3, -3} {
+		d.add(i, 1)
+		s.add(i, 1)
+	}
+	if d.total() != 7 || s.total() != 7 || d.bins() != 104 || s.bins() != 4 {
+		t.Error(d.total(), s.total(), d.bins(), s.bins())
+	}
+	for rank := 0.0; rank < 7; rank += 0.5 {
+		if a, b := d.keyAtRank(rank), s.keyAtRank(rank); a != b {
+			t.Error(rank, a, b)
+		}
+	}
+	d.clear()
+	s.clear()
+	if d.total() != 0 || s.bins() != 0 {
+		t.Error("clear did not empty the stores")
+	}
+}
+
+func TestDenseStoreGrow(t *testing.T) {
+	d, s := newStore(dense, 0).(*denseStore), newStore(sparse, 0)
+	grows := 0
+	for i := 0; i < 1000; i++ {
+		buf := d.buf
+		for _, index := range []int{i, -i} {
+			d.add(index, 1)
+			s.add(index, 1)
+		}
+		if len(d.buf) != len(buf) {
+			grows++
+		}
+	}
+	// The buffer doubles instead of growing by one bucket at a time.
+	if d.bins() != 1999 || grows > 12 {
+		t.Error(d.bins(), grows)
+	}
+	var indices []int
+	d.forEach(func(index int, count float64) {
+		if count != s.(*sparseStore).counts[index] {
+			t.Error(index, count)
+		}
+		indices = append(indices, index)
+	})
+	if len(indices) != 1999 || indices[0] != -999 {
+		t.Error(len(indices), indices[0])
+	}
+	d.clear()
+	d.add(5000, 2)
+	if d.bins() != 1 || d.total() != 2 || d.keyAtRank(1) != 5000 {
+		t.Error(d.bins(), d.total(), d.keyAtRank(1))
+	}
+
+	lowest := newStore(collapsingLowest, 100).(*denseStore)
+	for i := 0; i < 1000; i++ {
+		lowest.add(i, 1)
+	}
+	if lowest.bins() != 100 || len(lowest.buf) != 100 ||
+		lowest.counts[0] != 901 {
+		t.Error(lowest.bins(), len(lowest.buf), lowest.counts[0])
+	}
+}
@@ -1,4 +1,10 @@
 /*
 Probabilistic data structures for processing very large datasets
+
+This package defines the interfaces shared by the sketches in its
+subpackages, so generic code can digest, merge, query and serialize any
+of them.
+
+The package requires Go 1.20 or later.
 */
 package datasketch
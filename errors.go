@@ -0,0 +1,59 @@
+package datasketch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors shared by every sketch in this library. Failures are reported as
+// one of the typed errors below, which wrap these sentinels so callers can
+// test for them with errors.Is regardless of the sketch type.
+var (
+	// ErrShortBuffer means a buffer is too small to hold, or to
+	// reconstruct, a serialized sketch.
+	ErrShortBuffer = errors.New("datasketch: short buffer")
+	// ErrCorrupt means a serialized sketch contains invalid values.
+	ErrCorrupt = errors.New("datasketch: corrupt sketch")
+	// ErrWrongSketch means a stored value holds a different kind of
+	// sketch than the one it is being decoded into.
+	ErrWrongSketch = errors.New("datasketch: wrong sketch type")
+)
+
+// ShortBufferError reports a buffer holding fewer bytes than needed.
+type ShortBufferError struct {
+	Sketch string // Name of the sketch being encoded or decoded.
+	Need   int    // Minimum number of bytes required.
+	Have   int    // Number of bytes available.
+}
+
+func (e *ShortBufferError) Error() string {
+	return fmt.Sprintf("%s: buffer has %d bytes, need %d", e.Sketch, e.Have,
+		e.Need)
+}
+
+func (e *ShortBufferError) Unwrap() error { return ErrShortBuffer }
+
+// CorruptError reports a serialized sketch with an invalid field.
+type CorruptError struct {
+	Sketch string // Name of the sketch being decoded.
+	Reason string // Description of the invalid field.
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("%s: corrupt sketch: %s", e.Sketch, e.Reason)
+}
+
+func (e *CorruptError) Unwrap() error { return ErrCorrupt }
+
+// WrongSketchError reports a stored value holding an unexpected kind of
+// sketch, or no sketch at all.
+type WrongSketchError struct {
+	Want string // Name of the sketch being decoded.
+	Got  string // Description of the value found instead.
+}
+
+func (e *WrongSketchError) Error() string {
+	return fmt.Sprintf("cannot decode %s into a %s", e.Got, e.Want)
+}
+
+func (e *WrongSketchError) Unwrap() error { return ErrWrongSketch }
@@ -0,0 +1,26 @@
+package frequent
+
+import (
+	"errors"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrCapacity is returned when the number of counters is not
+	// positive.
+	ErrCapacity = errors.New("frequent: capacity must be positive")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+func shortBuffer(sketch string, need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: sketch, Need: need, Have: have}
+}
+
+func corrupt(sketch, reason string) error {
+	return &datasketch.CorruptError{Sketch: sketch, Reason: reason}
+}
@@ -0,0 +1,68 @@
+// Package frequent implements summaries finding the most frequent items of
+// a stream, or heavy hitters, in space independent of the number of
+// distinct items: SpaceSaving and Misra–Gries. Both report, for every
+// item, bounds on its true frequency.
+//
+// Efficient Computation of Frequent and Top-k Elements in Data Streams
+// (SpaceSaving):
+// https://www.cs.ucsb.edu/sites/default/files/documents/2005-23.pdf
+//
+// Finding Repeated Elements (Misra–Gries):
+// https://www.cs.utexas.edu/users/misra/scannedPdf.dir/FindRepeatedElements.pdf
+//
+// Mergeable Summaries:
+// https://www.cs.utah.edu/~jeffp/papers/merge-summ.pdf
+package frequent
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Item is an item of a stream with bounds on its frequency: the true
+// frequency is between Count-Error and Count.
+type Item struct {
+	Value string
+	Count uint64
+	Error uint64
+}
+
+// Lower returns the lower bound on the frequency of the item.
+func (it Item) Lower() uint64 {
+	return it.Count - it.Error
+}
+
+// topK sorts items by decreasing Count, ties broken by value, and returns
+// the first k of them.
+func topK(items []Item, k int) []Item {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Value < items[j].Value
+	})
+	if k >= 0 && k < len(items) {
+		items = items[:k]
+	}
+	return items
+}
+
+// putString writes s preceded by its length and returns the number of
+// bytes written.
+func putString(buffer []byte, s string) int {
+	binary.LittleEndian.PutUint32(buffer, uint32(len(s)))
+	return 4 + copy(buffer[4:], s)
+}
+
+// getString reads a string written by putString from buffer, returning it
+// and the number of bytes read, or false if buffer is too short.
+func getString(buffer []byte) (string, int, bool) {
+	if len(buffer) < 4 {
+		return "", 0, false
+	}
+	n := binary.LittleEndian.Uint32(buffer)
+	if uint64(len(buffer)-4) < uint64(n) {
+		return "", 0, false
+	}
+	return string(buffer[4 : 4+n]), 4 + int(n), true
+}
@@ -0,0 +1,53 @@
+package frequent
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// zipf returns a stream of n items drawn from a Zipf distribution, and the
+// true frequency of each.
+func zipf(seed int64, n int) ([]string, map[string]uint64) {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.1, 1, 100000)
+	stream := make([]string, n)
+	freq := make(map[string]uint64)
+	for i := range stream {
+		stream[i] = "/endpoint/" + strconv.FormatUint(z.Uint64(), 10)
+		freq[stream[i]]++
+	}
+	return stream, freq
+}
+
+// summary is implemented by both SpaceSaving and MisraGries.
+type summary interface {
+	Estimate(item string) Item
+	TopK(k int) []Item
+}
+
+// checkBounds fails t if the bounds of s do not hold for freq or are
+// looser than bound.
+func checkBounds(t interface {
+	Helper()
+	Fatalf(string, ...interface{})
+}, s summary, freq map[string]uint64, bound uint64) {
+	t.Helper()
+	for v, f := range freq {
+		it := s.Estimate(v)
+		if it.Lower() > f || it.Count < f {
+			t.Fatalf("%s: frequency %d outside [%d, %d]", v, f, it.Lower(),
+				it.Count)
+		}
+		if it.Error > bound {
+			t.Fatalf("%s: error %d exceeds %d", v, it.Error, bound)
+		}
+	}
+}
+
+// trueTopK returns the k most frequent items of freq.
+func trueTopK(freq map[string]uint64, k int) []Item {
+	items := make([]Item, 0, len(freq))
+	for v, f := range freq {
+		items = append(items, Item{Value: v, Count: f})
+	}
+	return topK(items, k)
+}
@@ -0,0 +1,207 @@
+package frequent
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*MisraGries] = new(MisraGries)
+	_ datasketch.Serializable           = new(MisraGries)
+)
+
+// MisraGries keeps counters that are lower bounds of the frequencies of
+// their items. When more than Capacity items have counters, all counters
+// are decreased by the same amount until at most Capacity are left, and
+// the amount is added to Offset. An item's frequency exceeds its counter
+// by at most Offset, which is at most N/(Capacity+1).
+type MisraGries struct {
+	Capacity int
+	// N is the total weight of the updates.
+	N uint64
+	// Offset is the total amount subtracted from every counter.
+	Offset uint64
+
+	counters map[string]uint64
+}
+
+// NewMisraGries returns a MisraGries with capacity counters. Its
+// estimates are off by at most N/(capacity+1).
+func NewMisraGries(capacity int) (*MisraGries, error) {
+	if capacity <= 0 || capacity > math.MaxInt32/2 {
+		return nil, ErrCapacity
+	}
+	return &MisraGries{
+		Capacity: capacity,
+		counters: make(map[string]uint64),
+	}, nil
+}
+
+// Clear sets the MisraGries back to its initial state.
+func (s *MisraGries) Clear() {
+	s.N, s.Offset = 0, 0
+	s.counters = make(map[string]uint64)
+}
+
+// Update adds weight occurrences of item.
+func (s *MisraGries) Update(item string, weight uint64) {
+	if weight == 0 {
+		return
+	}
+	s.N += weight
+	s.counters[item] += weight
+	// Decreasing the counters only once twice Capacity items have some
+	// makes updates O(1) amortized.
+	if len(s.counters) >= 2*s.Capacity {
+		s.reduce()
+	}
+}
+
+// reduce decreases all counters by the Capacity+1-th largest one, which
+// leaves at most Capacity of them.
+func (s *MisraGries) reduce() {
+	if len(s.counters) <= s.Capacity {
+		return
+	}
+	counts := make([]uint64, 0, len(s.counters))
+	for _, c := range s.counters {
+		counts = append(counts, c)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i] > counts[j] })
+	d := counts[s.Capacity]
+	for v, c := range s.counters {
+		if c <= d {
+			delete(s.counters, v)
+		} else {
+			s.counters[v] = c - d
+		}
+	}
+	s.Offset += d
+}
+
+// Estimate returns the bounds on the frequency of item.
+func (s *MisraGries) Estimate(item string) Item {
+	return Item{Value: item, Count: s.counters[item] + s.Offset,
+		Error: s.Offset}
+}
+
+// TopK returns the k items with the largest counts, in decreasing order
+// of count, or all of them if k is negative. An item is certainly among
+// the k most frequent if its Lower bound is at least the Count of the
+// k+1-th item.
+func (s *MisraGries) TopK(k int) []Item {
+	items := make([]Item, 0, len(s.counters))
+	for v, c := range s.counters {
+		items = append(items, Item{Value: v, Count: c + s.Offset,
+			Error: s.Offset})
+	}
+	return topK(items, k)
+}
+
+// Merge takes another MisraGries and combines it with s, making s the
+// summary of both streams: the counters are added and then decreased
+// until at most Capacity are left. s keeps its Capacity.
+func (s *MisraGries) Merge(other *MisraGries) error {
+	for v, c := range other.counters {
+		s.counters[v] += c
+	}
+	s.N += other.N
+	s.Offset += other.Offset
+	s.reduce()
+	return nil
+}
+
+// ByteSize returns the size of the serialized object.
+func (s *MisraGries) ByteSize() int {
+	size := 4 + 4 + 8 + 8
+	for v := range s.counters {
+		size += 8 + 4 + len(v)
+	}
+	return size
+}
+
+// Serialize the MisraGries to bytes stored in buffer: Capacity, the
+// number of counters, N, Offset, then the count and item of every counter
+// in decreasing order of count.
+func (s *MisraGries) Serialize(buffer []byte) error {
+	if len(buffer) < s.ByteSize() {
+		return shortBuffer("MisraGries", s.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint32(buffer, uint32(s.Capacity))
+	b.PutUint32(buffer[4:], uint32(len(s.counters)))
+	b.PutUint64(buffer[8:], s.N)
+	b.PutUint64(buffer[16:], s.Offset)
+	offset := 24
+	for _, it := range s.TopK(-1) {
+		b.PutUint64(buffer[offset:], it.Count-s.Offset)
+		offset += 8 + putString(buffer[offset+8:], it.Value)
+	}
+	return nil
+}
+
+// DeserializeMisraGries reconstructs a MisraGries from the buffer
+func DeserializeMisraGries(buffer []byte) (*MisraGries, error) {
+	const sketch = "MisraGries"
+	if len(buffer) < 24 {
+		return nil, shortBuffer(sketch, 24, len(buffer))
+	}
+	b := binary.LittleEndian
+	capacity, n := b.Uint32(buffer), b.Uint32(buffer[4:])
+	if capacity == 0 || capacity > math.MaxInt32/2 {
+		return nil, corrupt(sketch, fmt.Sprintf("capacity %d is out of range",
+			capacity))
+	}
+	if n >= 2*capacity {
+		return nil, corrupt(sketch, fmt.Sprintf("%d counters are too many "+
+			"for capacity %d", n, capacity))
+	}
+	// Each counter takes at least 12 bytes, which bounds the allocations.
+	if need := 24 + 12*uint64(n); uint64(len(buffer)) < need {
+		return nil, shortBuffer(sketch, int(need), len(buffer))
+	}
+	s, err := NewMisraGries(int(capacity))
+	if err != nil {
+		return nil, err
+	}
+	s.N = b.Uint64(buffer[8:])
+	s.Offset = b.Uint64(buffer[16:])
+	if s.Offset > s.N {
+		return nil, corrupt(sketch, fmt.Sprintf("offset %d exceeds the "+
+			"total weight %d", s.Offset, s.N))
+	}
+	offset := 24
+	var last Item
+	for i := uint32(0); i < n; i++ {
+		if len(buffer)-offset < 12 {
+			return nil, shortBuffer(sketch, offset+12, len(buffer))
+		}
+		c := b.Uint64(buffer[offset:])
+		v, size, ok := getString(buffer[offset+8:])
+		if !ok {
+			return nil, shortBuffer(sketch, offset+12+int(b.Uint32(
+				buffer[offset+8:])), len(buffer))
+		}
+		offset += 8 + size
+		if c == 0 || c > s.N {
+			return nil, corrupt(sketch, fmt.Sprintf("counter of %q is %d, "+
+				"not between 1 and %d", v, c, s.N))
+		}
+		if _, ok := s.counters[v]; ok {
+			return nil, corrupt(sketch, fmt.Sprintf("%q is counted twice", v))
+		}
+		// Counters are stored in the order of TopK, which Serialize
+		// reproduces.
+		if i > 0 && (c > last.Count || c == last.Count && v <= last.Value) {
+			return nil, corrupt(sketch, "counters are out of order")
+		}
+		last = Item{Value: v, Count: c}
+		s.counters[v] = c
+	}
+	return s, nil
+}
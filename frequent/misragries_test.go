@@ -0,0 +1,165 @@
+package frequent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMisraGriesNew(t *testing.T) {
+	if _, err := NewMisraGries(-1); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	s, err := NewMisraGries(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it := s.Estimate("a"); it.Count != 0 || it.Error != 0 {
+		t.Error(it)
+	}
+}
+
+func TestMisraGriesBounds(t *testing.T) {
+	const capacity = 200
+	stream, freq := zipf(1, 100000)
+	s, _ := NewMisraGries(capacity)
+	for _, v := range stream {
+		s.Update(v, 1)
+	}
+	if s.N != uint64(len(stream)) {
+		t.Error(s.N)
+	}
+	if len(s.TopK(-1)) >= 2*capacity {
+		t.Error(len(s.TopK(-1)))
+	}
+	checkBounds(t, s, freq, s.N/(capacity+1))
+
+	want := trueTopK(freq, 10)
+	got := s.TopK(10)
+	for i := range want {
+		if got[i].Value != want[i].Value {
+			t.Errorf("item %d is %s (want %s)", i, got[i].Value,
+				want[i].Value)
+		}
+	}
+}
+
+func TestMisraGriesWeights(t *testing.T) {
+	s, _ := NewMisraGries(1)
+	s.Update("a", 5)
+	s.Update("b", 3)
+	s.Update("c", 0)
+	// Two counters trigger a reduction by b's count.
+	if s.Offset != 3 || s.N != 8 {
+		t.Error(s.Offset, s.N)
+	}
+	if it := s.Estimate("a"); it.Count != 5 || it.Error != 3 {
+		t.Error(it)
+	}
+	if it := s.Estimate("b"); it.Count != 3 || it.Lower() != 0 {
+		t.Error(it)
+	}
+	s.Clear()
+	if s.N != 0 || s.Offset != 0 || len(s.TopK(-1)) != 0 {
+		t.Error("Clear did not reset the MisraGries")
+	}
+}
+
+func TestMisraGriesMerge(t *testing.T) {
+	const capacity = 200
+	s1, f1 := zipf(1, 50000)
+	s2, f2 := zipf(2, 50000)
+	a, _ := NewMisraGries(capacity)
+	b, _ := NewMisraGries(capacity)
+	for _, v := range s1 {
+		a.Update(v, 1)
+	}
+	for _, v := range s2 {
+		b.Update(v, 1)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for v, f := range f2 {
+		f1[v] += f
+	}
+	if a.N != 100000 || len(a.TopK(-1)) > capacity {
+		t.Error(a.N, len(a.TopK(-1)))
+	}
+	// Merged summaries keep the bound of a single one.
+	checkBounds(t, a, f1, a.N/(capacity+1))
+}
+
+func TestMisraGriesSerialization(t *testing.T) {
+	stream, _ := zipf(1, 1000)
+	s, _ := NewMisraGries(20)
+	for _, v := range stream {
+		s.Update(v, 1)
+	}
+	buf := make([]byte, s.ByteSize())
+	if err := s.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeMisraGries(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Capacity != s.Capacity || d.N != s.N || d.Offset != s.Offset {
+		t.Error(d.Capacity, d.N, d.Offset)
+	}
+	want, got := s.TopK(-1), d.TopK(-1)
+	if len(got) != len(want) {
+		t.Fatal(len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatal("Did not get back the same MisraGries")
+		}
+	}
+
+	if err := s.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := DeserializeMisraGries(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	// Making the last counter the largest breaks the order.
+	bad := append([]byte(nil), buf...)
+	last := len(bad) - 4 - len(want[len(want)-1].Value) - 8
+	bad[last+7] = 0xff
+	if _, err := DeserializeMisraGries(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad = append([]byte(nil), buf...)
+	bad[0], bad[1], bad[2], bad[3] = 0, 0, 0, 0
+	if _, err := DeserializeMisraGries(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserializeMisraGries(f *testing.F) {
+	s, _ := NewMisraGries(2)
+	for _, v := range []string{"a", "b", "a", "c", "d", "e", "a"} {
+		s.Update(v, 1)
+	}
+	buf := make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:24])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := DeserializeMisraGries(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		s.Update("a", 1)
+		s.TopK(2)
+	})
+}
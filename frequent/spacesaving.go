@@ -0,0 +1,272 @@
+package frequent
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*SpaceSaving] = new(SpaceSaving)
+	_ datasketch.Serializable            = new(SpaceSaving)
+)
+
+// SpaceSaving keeps at most Capacity counters. An item without a counter
+// takes over the smallest one, inheriting its count as error. Any item
+// more frequent than N/Capacity has a counter.
+type SpaceSaving struct {
+	Capacity int
+	// N is the total weight of the updates.
+	N uint64
+
+	// floor is the most any item without a counter can have occurred
+	// while there are fewer than Capacity counters. It is 0 until a
+	// summary of a smaller capacity is merged.
+	floor    uint64
+	counters map[string]*ssCounter
+	// byCount is a min-heap of the counters, the smallest at its root.
+	byCount ssHeap
+}
+
+type ssCounter struct {
+	Item
+	index int
+}
+
+// ssHeap implements heap.Interface.
+type ssHeap []*ssCounter
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *ssHeap) Push(x interface{}) {
+	c := x.(*ssCounter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+func (h *ssHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// NewSpaceSaving returns a SpaceSaving with capacity counters. Its
+// estimates are off by at most N/capacity.
+func NewSpaceSaving(capacity int) (*SpaceSaving, error) {
+	if capacity <= 0 || capacity > math.MaxInt32 {
+		return nil, ErrCapacity
+	}
+	return &SpaceSaving{
+		Capacity: capacity,
+		counters: make(map[string]*ssCounter),
+	}, nil
+}
+
+// Clear sets the SpaceSaving back to its initial state.
+func (s *SpaceSaving) Clear() {
+	s.N = 0
+	s.floor = 0
+	s.counters = make(map[string]*ssCounter)
+	s.byCount = s.byCount[:0]
+}
+
+// Update adds weight occurrences of item.
+func (s *SpaceSaving) Update(item string, weight uint64) {
+	if weight == 0 {
+		return
+	}
+	s.N += weight
+	if c, ok := s.counters[item]; ok {
+		c.Count += weight
+		heap.Fix(&s.byCount, c.index)
+		return
+	}
+	if len(s.byCount) < s.Capacity {
+		c := &ssCounter{Item: Item{Value: item, Count: s.floor + weight,
+			Error: s.floor}}
+		s.counters[item] = c
+		heap.Push(&s.byCount, c)
+		return
+	}
+	c := s.byCount[0]
+	delete(s.counters, c.Value)
+	c.Value, c.Error = item, c.Count
+	c.Count += weight
+	s.counters[item] = c
+	heap.Fix(&s.byCount, 0)
+}
+
+// min returns the smallest count, the most any item without a counter can
+// have occurred.
+func (s *SpaceSaving) min() uint64 {
+	if len(s.byCount) < s.Capacity {
+		return s.floor
+	}
+	return s.byCount[0].Count
+}
+
+// Estimate returns the bounds on the frequency of item.
+func (s *SpaceSaving) Estimate(item string) Item {
+	if c, ok := s.counters[item]; ok {
+		return c.Item
+	}
+	m := s.min()
+	return Item{Value: item, Count: m, Error: m}
+}
+
+// TopK returns the k items with the largest counts, in decreasing order
+// of count, or all of them if k is negative. An item is certainly among
+// the k most frequent if its Lower bound is at least the Count of the
+// k+1-th item.
+func (s *SpaceSaving) TopK(k int) []Item {
+	items := make([]Item, len(s.byCount))
+	for i, c := range s.byCount {
+		items[i] = c.Item
+	}
+	return topK(items, k)
+}
+
+// Merge takes another SpaceSaving and combines it with s, making s the
+// summary of both streams. An item missing from one summary is counted as
+// the smallest count of that summary, with as much error. s keeps its
+// Capacity.
+func (s *SpaceSaving) Merge(other *SpaceSaving) error {
+	m, otherMin := s.min(), other.min()
+	merged := make(map[string]Item, len(s.counters)+len(other.counters))
+	for v, c := range s.counters {
+		it := c.Item
+		if o, ok := other.counters[v]; ok {
+			it.Count += o.Count
+			it.Error += o.Error
+		} else {
+			it.Count += otherMin
+			it.Error += otherMin
+		}
+		merged[v] = it
+	}
+	for v, o := range other.counters {
+		if _, ok := merged[v]; !ok {
+			it := o.Item
+			it.Count += m
+			it.Error += m
+			merged[v] = it
+		}
+	}
+	items := make([]Item, 0, len(merged))
+	for _, it := range merged {
+		items = append(items, it)
+	}
+	n := s.N + other.N
+	s.Clear()
+	s.N = n
+	// If other has fewer counters, s may keep fewer than Capacity, and
+	// the items dropped from both may still have occurred up to m+otherMin
+	// times.
+	s.floor = m + otherMin
+	for _, it := range topK(items, s.Capacity) {
+		c := &ssCounter{Item: it}
+		s.counters[it.Value] = c
+		heap.Push(&s.byCount, c)
+	}
+	return nil
+}
+
+// ByteSize returns the size of the serialized object.
+func (s *SpaceSaving) ByteSize() int {
+	size := 4 + 4 + 8 + 8
+	for _, c := range s.byCount {
+		size += 8 + 8 + 4 + len(c.Value)
+	}
+	return size
+}
+
+// Serialize the SpaceSaving to bytes stored in buffer: Capacity, the
+// number of counters, N, the count of the items without a counter, then
+// the count, error and item of every counter.
+func (s *SpaceSaving) Serialize(buffer []byte) error {
+	if len(buffer) < s.ByteSize() {
+		return shortBuffer("SpaceSaving", s.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint32(buffer, uint32(s.Capacity))
+	b.PutUint32(buffer[4:], uint32(len(s.byCount)))
+	b.PutUint64(buffer[8:], s.N)
+	b.PutUint64(buffer[16:], s.floor)
+	offset := 24
+	for _, c := range s.byCount {
+		b.PutUint64(buffer[offset:], c.Count)
+		b.PutUint64(buffer[offset+8:], c.Error)
+		offset += 16 + putString(buffer[offset+16:], c.Value)
+	}
+	return nil
+}
+
+// DeserializeSpaceSaving reconstructs a SpaceSaving from the buffer
+func DeserializeSpaceSaving(buffer []byte) (*SpaceSaving, error) {
+	const sketch = "SpaceSaving"
+	if len(buffer) < 24 {
+		return nil, shortBuffer(sketch, 24, len(buffer))
+	}
+	b := binary.LittleEndian
+	capacity, n := b.Uint32(buffer), b.Uint32(buffer[4:])
+	if capacity == 0 || capacity > math.MaxInt32 {
+		return nil, corrupt(sketch, fmt.Sprintf("capacity %d is out of range",
+			capacity))
+	}
+	if n > capacity {
+		return nil, corrupt(sketch, fmt.Sprintf("%d counters are more than "+
+			"capacity %d", n, capacity))
+	}
+	// Each counter takes at least 20 bytes, which bounds the allocations.
+	if need := 24 + 20*uint64(n); uint64(len(buffer)) < need {
+		return nil, shortBuffer(sketch, int(need), len(buffer))
+	}
+	s, err := NewSpaceSaving(int(capacity))
+	if err != nil {
+		return nil, err
+	}
+	s.N, s.floor = b.Uint64(buffer[8:]), b.Uint64(buffer[16:])
+	if s.floor > s.N {
+		return nil, corrupt(sketch, fmt.Sprintf("count %d of the items "+
+			"without a counter exceeds N %d", s.floor, s.N))
+	}
+	offset := 24
+	for i := uint32(0); i < n; i++ {
+		if len(buffer)-offset < 20 {
+			return nil, shortBuffer(sketch, offset+20, len(buffer))
+		}
+		it := Item{Count: b.Uint64(buffer[offset:]),
+			Error: b.Uint64(buffer[offset+8:])}
+		v, size, ok := getString(buffer[offset+16:])
+		if !ok {
+			return nil, shortBuffer(sketch, offset+20+int(b.Uint32(
+				buffer[offset+16:])), len(buffer))
+		}
+		it.Value = v
+		offset += 16 + size
+		if it.Error > it.Count || it.Count < s.floor {
+			return nil, corrupt(sketch, fmt.Sprintf("bounds of %q are "+
+				"inconsistent", v))
+		}
+		if _, ok := s.counters[v]; ok {
+			return nil, corrupt(sketch, fmt.Sprintf("%q is counted twice", v))
+		}
+		// Counters are stored in heap order, which Serialize reproduces.
+		if i > 0 && s.byCount[(i-1)/2].Count > it.Count {
+			return nil, corrupt(sketch, "counters are not in heap order")
+		}
+		c := &ssCounter{Item: it, index: len(s.byCount)}
+		s.counters[v] = c
+		s.byCount = append(s.byCount, c)
+	}
+	return s, nil
+}
@@ -0,0 +1,213 @@
+package frequent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSpaceSavingNew(t *testing.T) {
+	if _, err := NewSpaceSaving(0); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	s, err := NewSpaceSaving(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it := s.Estimate("a"); it.Count != 0 || it.Error != 0 {
+		t.Error(it)
+	}
+	if len(s.TopK(5)) != 0 {
+		t.Error("empty summary has items")
+	}
+}
+
+func TestSpaceSavingBounds(t *testing.T) {
+	const capacity = 200
+	stream, freq := zipf(1, 100000)
+	s, _ := NewSpaceSaving(capacity)
+	for _, v := range stream {
+		s.Update(v, 1)
+	}
+	if s.N != uint64(len(stream)) {
+		t.Error(s.N)
+	}
+	checkBounds(t, s, freq, s.N/capacity)
+
+	// The frequent items of a skewed stream are found exactly in order.
+	want := trueTopK(freq, 10)
+	got := s.TopK(10)
+	for i := range want {
+		if got[i].Value != want[i].Value {
+			t.Errorf("item %d is %s (want %s)", i, got[i].Value,
+				want[i].Value)
+		}
+	}
+	if len(s.TopK(-1)) != capacity {
+		t.Error(len(s.TopK(-1)))
+	}
+}
+
+func TestSpaceSavingWeights(t *testing.T) {
+	s, _ := NewSpaceSaving(2)
+	s.Update("a", 5)
+	s.Update("b", 3)
+	s.Update("c", 1)
+	s.Update("d", 0)
+	if s.N != 9 {
+		t.Error(s.N)
+	}
+	// c took over b's counter.
+	if it := s.Estimate("c"); it.Count != 4 || it.Error != 3 {
+		t.Error(it)
+	}
+	if it := s.Estimate("b"); it.Count != 4 || it.Error != 4 {
+		t.Error(it)
+	}
+	top := s.TopK(1)
+	if len(top) != 1 || top[0] != (Item{Value: "a", Count: 5}) {
+		t.Error(top)
+	}
+	s.Clear()
+	if s.N != 0 || len(s.TopK(-1)) != 0 {
+		t.Error("Clear did not reset the SpaceSaving")
+	}
+}
+
+func TestSpaceSavingMerge(t *testing.T) {
+	const capacity = 200
+	s1, f1 := zipf(1, 50000)
+	s2, f2 := zipf(2, 50000)
+	a, _ := NewSpaceSaving(capacity)
+	b, _ := NewSpaceSaving(capacity)
+	for _, v := range s1 {
+		a.Update(v, 1)
+	}
+	for _, v := range s2 {
+		b.Update(v, 1)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for v, f := range f2 {
+		f1[v] += f
+	}
+	if a.N != 100000 || len(a.TopK(-1)) != capacity {
+		t.Error(a.N, len(a.TopK(-1)))
+	}
+	checkBounds(t, a, f1, a.N/capacity)
+}
+
+func TestSpaceSavingMergeCapacities(t *testing.T) {
+	small, _ := NewSpaceSaving(10)
+	for i := 0; i < 250; i++ {
+		for j := 0; j < 20; j++ {
+			small.Update(fmt.Sprint(j), 1)
+		}
+	}
+	large, _ := NewSpaceSaving(100)
+	if err := large.Merge(small); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(large.TopK(-1)); n != 10 {
+		t.Fatal(n)
+	}
+	// Every item occurred 250 times, including those dropped by small.
+	for j := 0; j < 20; j++ {
+		if it := large.Estimate(fmt.Sprint(j)); it.Count < 250 ||
+			it.Lower() > 250 {
+			t.Errorf("item %d: %+v", j, it)
+		}
+	}
+	large.Update("new", 1)
+	if it := large.Estimate("new"); it.Lower() > 1 || it.Count < 1 {
+		t.Error(it)
+	}
+
+	buf := make([]byte, large.ByteSize())
+	large.Serialize(buf)
+	d, err := DeserializeSpaceSaving(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it := d.Estimate("19"); it.Count < 250 {
+		t.Error(it)
+	}
+}
+
+func TestSpaceSavingSerialization(t *testing.T) {
+	stream, _ := zipf(1, 1000)
+	s, _ := NewSpaceSaving(20)
+	for _, v := range stream {
+		s.Update(v, 1)
+	}
+	buf := make([]byte, s.ByteSize())
+	if err := s.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeSpaceSaving(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Capacity != s.Capacity || d.N != s.N {
+		t.Error(d.Capacity, d.N)
+	}
+	want, got := s.TopK(-1), d.TopK(-1)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatal("Did not get back the same SpaceSaving")
+		}
+	}
+	// The deserialized summary keeps working.
+	d.Update("new", 100)
+	if it := d.Estimate("new"); it.Count < 100 {
+		t.Error(it)
+	}
+
+	if err := s.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := DeserializeSpaceSaving(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	// The root counter is the smallest; making it the largest breaks the
+	// heap order.
+	bad := append([]byte(nil), buf...)
+	bad[24+7] = 0xff
+	if _, err := DeserializeSpaceSaving(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad = append([]byte(nil), buf...)
+	bad[4] = 21
+	if _, err := DeserializeSpaceSaving(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserializeSpaceSaving(f *testing.F) {
+	s, _ := NewSpaceSaving(4)
+	for _, v := range []string{"a", "b", "a", "c", "d", "e", "a"} {
+		s.Update(v, 1)
+	}
+	buf := make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:28])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := DeserializeSpaceSaving(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		s.Update("a", 1)
+		s.TopK(2)
+	})
+}
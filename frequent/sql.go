@@ -0,0 +1,59 @@
+package frequent
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(SpaceSaving)
+	_ driver.Valuer = new(SpaceSaving)
+	_ sql.Scanner   = new(MisraGries)
+	_ driver.Valuer = new(MisraGries)
+)
+
+// Value implements driver.Valuer so a SpaceSaving can be stored in a
+// binary (BYTEA/BLOB) column. The stored value is the serialized summary
+// preceded by a tag identifying it as a SpaceSaving.
+func (s *SpaceSaving) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.SpaceSaving, s)
+}
+
+// Scan implements sql.Scanner, restoring a SpaceSaving written by Value.
+// Blobs holding any other kind of sketch are rejected.
+func (s *SpaceSaving) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.SpaceSaving, src)
+	if err != nil {
+		return err
+	}
+	other, err := DeserializeSpaceSaving(buffer)
+	if err != nil {
+		return err
+	}
+	*s = *other
+	return nil
+}
+
+// Value implements driver.Valuer so a MisraGries can be stored in a
+// binary (BYTEA/BLOB) column.
+func (s *MisraGries) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.MisraGries, s)
+}
+
+// Scan implements sql.Scanner, restoring a MisraGries written by Value.
+// Blobs holding any other kind of sketch are rejected.
+func (s *MisraGries) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.MisraGries, src)
+	if err != nil {
+		return err
+	}
+	other, err := DeserializeMisraGries(buffer)
+	if err != nil {
+		return err
+	}
+	*s = *other
+	return nil
+}
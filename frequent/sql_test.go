@@ -0,0 +1,52 @@
+package frequent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestSpaceSavingValueScan(t *testing.T) {
+	s, _ := NewSpaceSaving(10)
+	s.Update("/index.html", 3)
+	v, err := s.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d SpaceSaving
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Capacity != 10 || d.Estimate("/index.html").Count != 3 {
+		t.Error("Did not get back the same SpaceSaving")
+	}
+	var m MisraGries
+	if err := m.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
+
+func TestMisraGriesValueScan(t *testing.T) {
+	s, _ := NewMisraGries(10)
+	s.Update("/index.html", 3)
+	v, err := s.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d MisraGries
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Capacity != 10 || d.Estimate("/index.html").Count != 3 {
+		t.Error("Did not get back the same MisraGries")
+	}
+	if err := d.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	h, _ := hyperloglog.New(4)
+	hv, _ := h.Value()
+	if err := d.Scan(hv); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
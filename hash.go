@@ -0,0 +1,56 @@
+package datasketch
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/hashfunction/siphash"
+)
+
+// HashFunc computes the 32-bit hash of data. It must not retain or modify
+// data.
+type HashFunc func(data []byte) uint32
+
+// Hasher turns raw items into the 32-bit hashes consumed by the sketches.
+// The zero value hashes with murmur3.Sum32 and seed 0 without allocating;
+// setting Func plugs in a different hash function.
+type Hasher struct {
+	Func HashFunc
+}
+
+// Sum32 returns the hash of data.
+func (h Hasher) Sum32(data []byte) uint32 {
+	if h.Func == nil {
+		return murmur3.Sum32(data, 0)
+	}
+	return h.Func(data)
+}
+
+// Sum32String returns the hash of the bytes of s, without copying them.
+func (h Hasher) Sum32String(s string) uint32 {
+	if h.Func == nil {
+		return murmur3.Sum32String(s, 0)
+	}
+	return h.Func(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Sum32Uint64 returns the hash of the little-endian encoding of v.
+func (h Hasher) Sum32Uint64(v uint64) uint32 {
+	if h.Func == nil {
+		return murmur3.Sum32Uint64(v, 0)
+	}
+	// The buffer escapes through Func, so only this path allocates.
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return h.Func(b)
+}
+
+// KeyedHashFunc returns a HashFunc computing SipHash-1-3 keyed with k0 and
+// k1. Sketches built on a secret key resist inputs crafted to collide,
+// such as strings chosen to all land in one HyperLogLog register.
+func KeyedHashFunc(k0, k1 uint64) HashFunc {
+	return func(data []byte) uint32 {
+		return uint32(siphash.Sum64R13(k0, k1, data))
+	}
+}
@@ -0,0 +1,57 @@
+package datasketch
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+)
+
+func TestHasher(t *testing.T) {
+	var h Hasher
+	data := []byte("hello, world")
+	want := murmur3.Sum32(data, 0)
+	if v := h.Sum32(data); v != want {
+		t.Errorf("0x%x (want 0x%x)", v, want)
+	}
+	if v := h.Sum32String(string(data)); v != want {
+		t.Errorf("0x%x (want 0x%x)", v, want)
+	}
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, 42)
+	want = murmur3.Sum32(b, 0)
+	if v := h.Sum32Uint64(42); v != want {
+		t.Errorf("0x%x (want 0x%x)", v, want)
+	}
+
+	h.Func = func(data []byte) uint32 { return uint32(len(data)) }
+	if v := h.Sum32String("hello"); v != 5 {
+		t.Error(v)
+	}
+	if v := h.Sum32Uint64(42); v != 8 {
+		t.Error(v)
+	}
+}
+
+func TestHasherAllocs(t *testing.T) {
+	var h Hasher
+	s := "The quick brown fox jumps over the lazy dog."
+	n := testing.AllocsPerRun(100, func() {
+		h.Sum32String(s)
+		h.Sum32Uint64(42)
+	})
+	if n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+}
+
+func TestKeyedHashFunc(t *testing.T) {
+	h := Hasher{Func: KeyedHashFunc(1, 2)}
+	v := h.Sum32String("hello")
+	if v != (Hasher{Func: KeyedHashFunc(1, 2)}).Sum32String("hello") {
+		t.Error("same key should give the same hash")
+	}
+	if v == (Hasher{Func: KeyedHashFunc(3, 4)}).Sum32String("hello") {
+		t.Error("different keys should give different hashes")
+	}
+}
@@ -27,12 +27,14 @@ type Hash128 interface {
 // digest128 represents a partial evaluation of a 128 bites hash.
 type digest128 struct {
 	digest
-	h1 uint64 // Unfinalized running hash part 1.
-	h2 uint64 // Unfinalized running hash part 2.
+	s1, s2 uint64 // Seeds, restored by Reset.
+	h1     uint64 // Unfinalized running hash part 1.
+	h2     uint64 // Unfinalized running hash part 2.
 }
 
 func New128(s1, s2 uint64) Hash128 {
 	d := new(digest128)
+	d.s1, d.s2 = s1, s2
 	d.h1 = s1
 	d.h2 = s2
 	d.bmixer = d
@@ -41,7 +43,7 @@ func New128(s1, s2 uint64) Hash128 {
 
 func (d *digest128) Size() int { return 16 }
 
-func (d *digest128) reset() { d.h1, d.h2 = 0, 0 }
+func (d *digest128) reset() { d.h1, d.h2 = d.s1, d.s2 }
 
 func (d *digest128) Sum(b []byte) []byte {
 	h1, h2 := d.h1, d.h2
@@ -55,8 +57,17 @@ func (d *digest128) Sum(b []byte) []byte {
 }
 
 func (d *digest128) bmix(p []byte) (tail []byte) {
-	h1, h2 := d.h1, d.h2
+	d.h1, d.h2, tail = bmix128(d.h1, d.h2, p)
+	return tail
+}
+
+func (d *digest128) Sum128() (h1, h2 uint64) {
+	return tmix128(d.h1, d.h2, d.tail, d.clen)
+}
 
+// bmix128 mixes as many 16 bytes blocks of p as possible into the running
+// hash h1, h2.
+func bmix128(h1, h2 uint64, p []byte) (uint64, uint64, []byte) {
 	nblocks := len(p) / 16
 	for i := 0; i < nblocks; i++ {
 		t := (*[2]uint64)(unsafe.Pointer(&p[i*16]))
@@ -80,36 +91,34 @@ func (d *digest128) bmix(p []byte) (tail []byte) {
 		h2 += h1
 		h2 = h2*5 + 0x38495ab5
 	}
-	d.h1, d.h2 = h1, h2
-	return p[nblocks*d.Size():]
+	return h1, h2, p[nblocks*16:]
 }
 
-func (d *digest128) Sum128() (h1, h2 uint64) {
-
-	h1, h2 = d.h1, d.h2
-
+// tmix128 mixes the fewer than 16 bytes of tail into the running hash h1,
+// h2 and finalizes it for an input of clen bytes.
+func tmix128(h1, h2 uint64, tail []byte, clen int) (uint64, uint64) {
 	var k1, k2 uint64
-	switch len(d.tail) & 15 {
+	switch len(tail) & 15 {
 	case 15:
-		k2 ^= uint64(d.tail[14]) << 48
+		k2 ^= uint64(tail[14]) << 48
 		fallthrough
 	case 14:
-		k2 ^= uint64(d.tail[13]) << 40
+		k2 ^= uint64(tail[13]) << 40
 		fallthrough
 	case 13:
-		k2 ^= uint64(d.tail[12]) << 32
+		k2 ^= uint64(tail[12]) << 32
 		fallthrough
 	case 12:
-		k2 ^= uint64(d.tail[11]) << 24
+		k2 ^= uint64(tail[11]) << 24
 		fallthrough
 	case 11:
-		k2 ^= uint64(d.tail[10]) << 16
+		k2 ^= uint64(tail[10]) << 16
 		fallthrough
 	case 10:
-		k2 ^= uint64(d.tail[9]) << 8
+		k2 ^= uint64(tail[9]) << 8
 		fallthrough
 	case 9:
-		k2 ^= uint64(d.tail[8]) << 0
+		k2 ^= uint64(tail[8]) << 0
 
 		k2 *= c2_128
 		k2 = (k2 << 33) | (k2 >> 31) // rotl64(k2, 33)
@@ -119,36 +128,36 @@ func (d *digest128) Sum128() (h1, h2 uint64) {
 		fallthrough
 
 	case 8:
-		k1 ^= uint64(d.tail[7]) << 56
+		k1 ^= uint64(tail[7]) << 56
 		fallthrough
 	case 7:
-		k1 ^= uint64(d.tail[6]) << 48
+		k1 ^= uint64(tail[6]) << 48
 		fallthrough
 	case 6:
-		k1 ^= uint64(d.tail[5]) << 40
+		k1 ^= uint64(tail[5]) << 40
 		fallthrough
 	case 5:
-		k1 ^= uint64(d.tail[4]) << 32
+		k1 ^= uint64(tail[4]) << 32
 		fallthrough
 	case 4:
-		k1 ^= uint64(d.tail[3]) << 24
+		k1 ^= uint64(tail[3]) << 24
 		fallthrough
 	case 3:
-		k1 ^= uint64(d.tail[2]) << 16
+		k1 ^= uint64(tail[2]) << 16
 		fallthrough
 	case 2:
-		k1 ^= uint64(d.tail[1]) << 8
+		k1 ^= uint64(tail[1]) << 8
 		fallthrough
 	case 1:
-		k1 ^= uint64(d.tail[0]) << 0
+		k1 ^= uint64(tail[0]) << 0
 		k1 *= c1_128
 		k1 = (k1 << 31) | (k1 >> 33) // rotl64(k1, 31)
 		k1 *= c2_128
 		h1 ^= k1
 	}
 
-	h1 ^= uint64(d.clen)
-	h2 ^= uint64(d.clen)
+	h1 ^= uint64(clen)
+	h2 ^= uint64(clen)
 
 	h1 += h2
 	h2 += h1
@@ -183,8 +192,17 @@ func rotl64(x uint64, r byte) uint64 {
 //     hasher.Write(data)
 //     return hasher.Sum128()
 func Sum128(data []byte) (h1 uint64, h2 uint64) {
-	d := &digest128{h1: 0, h2: 0}
-	d.tail = d.bmix(data)
-	d.clen = len(data)
-	return d.Sum128()
+	return Sum128WithSeed(data, 0)
+}
+
+// Sum128WithSeed returns the MurmurHash3 sum of data, seeded like the
+// reference x64_128 implementation (and the Java and Python ports of it).
+// It is equivalent to the following sequence (without the extra burden
+// and the extra allocation):
+//     hasher := New128(uint64(seed), uint64(seed))
+//     hasher.Write(data)
+//     return hasher.Sum128()
+func Sum128WithSeed(data []byte, seed uint32) (h1 uint64, h2 uint64) {
+	h1, h2, tail := bmix128(uint64(seed), uint64(seed), data)
+	return tmix128(h1, h2, tail, len(data))
 }
@@ -0,0 +1,220 @@
+package murmur3
+
+import (
+	"hash"
+	"unsafe"
+)
+
+const (
+	c1_128x86 uint32 = 0x239b961b
+	c2_128x86 uint32 = 0xab0e9789
+	c3_128x86 uint32 = 0x38b34ae5
+	c4_128x86 uint32 = 0xa1e38b93
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ hash.Hash = new(digest128x86)
+	_ Hash128   = new(digest128x86)
+	_ bmixer    = new(digest128x86)
+)
+
+// digest128x86 represents a partial evaluation of the x86 flavour of the
+// 128 bites hash, which works on four 32 bits lanes.
+type digest128x86 struct {
+	digest
+	seed           uint32
+	h1, h2, h3, h4 uint32 // Unfinalized running hash.
+}
+
+// New128x86 returns the x86_128 variant of MurmurHash3. It produces
+// different sums than New128, which is the x64_128 variant.
+func New128x86(seed uint32) Hash128 {
+	d := new(digest128x86)
+	d.seed = seed
+	d.bmixer = d
+	d.reset()
+	return d
+}
+
+func (d *digest128x86) Size() int { return 16 }
+
+func (d *digest128x86) reset() { d.h1, d.h2, d.h3, d.h4 = d.seed, d.seed, d.seed, d.seed }
+
+func (d *digest128x86) Sum(b []byte) []byte {
+	h1, h2 := d.Sum128()
+	return append(b,
+		byte(h1>>56), byte(h1>>48), byte(h1>>40), byte(h1>>32),
+		byte(h1>>24), byte(h1>>16), byte(h1>>8), byte(h1),
+
+		byte(h2>>56), byte(h2>>48), byte(h2>>40), byte(h2>>32),
+		byte(h2>>24), byte(h2>>16), byte(h2>>8), byte(h2),
+	)
+}
+
+func (d *digest128x86) bmix(p []byte) (tail []byte) {
+	h1, h2, h3, h4 := d.h1, d.h2, d.h3, d.h4
+
+	nblocks := len(p) / 16
+	for i := 0; i < nblocks; i++ {
+		t := (*[4]uint32)(unsafe.Pointer(&p[i*16]))
+		k1, k2, k3, k4 := t[0], t[1], t[2], t[3]
+
+		k1 *= c1_128x86
+		k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
+		k1 *= c2_128x86
+		h1 ^= k1
+
+		h1 = (h1 << 19) | (h1 >> 13) // rotl32(h1, 19)
+		h1 += h2
+		h1 = h1*5 + 0x561ccd1b
+
+		k2 *= c2_128x86
+		k2 = (k2 << 16) | (k2 >> 16) // rotl32(k2, 16)
+		k2 *= c3_128x86
+		h2 ^= k2
+
+		h2 = (h2 << 17) | (h2 >> 15) // rotl32(h2, 17)
+		h2 += h3
+		h2 = h2*5 + 0x0bcaa747
+
+		k3 *= c3_128x86
+		k3 = (k3 << 17) | (k3 >> 15) // rotl32(k3, 17)
+		k3 *= c4_128x86
+		h3 ^= k3
+
+		h3 = (h3 << 15) | (h3 >> 17) // rotl32(h3, 15)
+		h3 += h4
+		h3 = h3*5 + 0x96cd1c35
+
+		k4 *= c4_128x86
+		k4 = (k4 << 18) | (k4 >> 14) // rotl32(k4, 18)
+		k4 *= c1_128x86
+		h4 ^= k4
+
+		h4 = (h4 << 13) | (h4 >> 19) // rotl32(h4, 13)
+		h4 += h1
+		h4 = h4*5 + 0x32ac3b17
+	}
+	d.h1, d.h2, d.h3, d.h4 = h1, h2, h3, h4
+	return p[nblocks*d.Size():]
+}
+
+// Sum128 returns the hash laid out like the x64_128 variant: h1 holds the
+// first 8 bytes of the reference little-endian output, h2 the last 8.
+func (d *digest128x86) Sum128() (h1, h2 uint64) {
+
+	x1, x2, x3, x4 := d.h1, d.h2, d.h3, d.h4
+
+	var k1, k2, k3, k4 uint32
+	switch len(d.tail) & 15 {
+	case 15:
+		k4 ^= uint32(d.tail[14]) << 16
+		fallthrough
+	case 14:
+		k4 ^= uint32(d.tail[13]) << 8
+		fallthrough
+	case 13:
+		k4 ^= uint32(d.tail[12]) << 0
+		k4 *= c4_128x86
+		k4 = (k4 << 18) | (k4 >> 14) // rotl32(k4, 18)
+		k4 *= c1_128x86
+		x4 ^= k4
+		fallthrough
+
+	case 12:
+		k3 ^= uint32(d.tail[11]) << 24
+		fallthrough
+	case 11:
+		k3 ^= uint32(d.tail[10]) << 16
+		fallthrough
+	case 10:
+		k3 ^= uint32(d.tail[9]) << 8
+		fallthrough
+	case 9:
+		k3 ^= uint32(d.tail[8]) << 0
+		k3 *= c3_128x86
+		k3 = (k3 << 17) | (k3 >> 15) // rotl32(k3, 17)
+		k3 *= c4_128x86
+		x3 ^= k3
+		fallthrough
+
+	case 8:
+		k2 ^= uint32(d.tail[7]) << 24
+		fallthrough
+	case 7:
+		k2 ^= uint32(d.tail[6]) << 16
+		fallthrough
+	case 6:
+		k2 ^= uint32(d.tail[5]) << 8
+		fallthrough
+	case 5:
+		k2 ^= uint32(d.tail[4]) << 0
+		k2 *= c2_128x86
+		k2 = (k2 << 16) | (k2 >> 16) // rotl32(k2, 16)
+		k2 *= c3_128x86
+		x2 ^= k2
+		fallthrough
+
+	case 4:
+		k1 ^= uint32(d.tail[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= uint32(d.tail[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint32(d.tail[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint32(d.tail[0]) << 0
+		k1 *= c1_128x86
+		k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
+		k1 *= c2_128x86
+		x1 ^= k1
+	}
+
+	clen := uint32(d.clen)
+	x1 ^= clen
+	x2 ^= clen
+	x3 ^= clen
+	x4 ^= clen
+
+	x1 += x2 + x3 + x4
+	x2 += x1
+	x3 += x1
+	x4 += x1
+
+	x1 = fmix32(x1)
+	x2 = fmix32(x2)
+	x3 = fmix32(x3)
+	x4 = fmix32(x4)
+
+	x1 += x2 + x3 + x4
+	x2 += x1
+	x3 += x1
+	x4 += x1
+
+	return uint64(x2)<<32 | uint64(x1), uint64(x4)<<32 | uint64(x3)
+}
+
+func fmix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// Sum128x86 returns the x86_128 MurmurHash3 sum of data. It is equivalent
+// to the following sequence (without the extra burden and the extra
+// allocation):
+//     hasher := New128x86(seed)
+//     hasher.Write(data)
+//     return hasher.Sum128()
+func Sum128x86(data []byte, seed uint32) (h1 uint64, h2 uint64) {
+	d := &digest128x86{h1: seed, h2: seed, h3: seed, h4: seed}
+	d.tail = d.bmix(data)
+	d.clen = len(data)
+	return d.Sum128()
+}
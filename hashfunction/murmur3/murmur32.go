@@ -21,11 +21,13 @@ const (
 // digest32 represents a partial evaluation of a 32 bites hash.
 type digest32 struct {
 	digest
-	h1 uint32 // Unfinalized running hash.
+	seed uint32 // Seed, restored by Reset.
+	h1   uint32 // Unfinalized running hash.
 }
 
 func New32(s uint32) hash.Hash32 {
 	d := new(digest32)
+	d.seed = s
 	d.h1 = s
 	d.bmixer = d
 	return d
@@ -33,7 +35,7 @@ func New32(s uint32) hash.Hash32 {
 
 func (d *digest32) Size() int { return 4 }
 
-func (d *digest32) reset() { d.h1 = 0 }
+func (d *digest32) reset() { d.h1 = d.seed }
 
 func (d *digest32) Sum(b []byte) []byte {
 	h := d.h1
@@ -46,49 +48,48 @@ func (d *digest32) bmix(p []byte) (tail []byte) {
 
 	nblocks := len(p) / 4
 	for i := 0; i < nblocks; i++ {
-		k1 := *(*uint32)(unsafe.Pointer(&p[i*4]))
-
-		k1 *= c1_32
-		k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
-		k1 *= c2_32
-
-		h1 ^= k1
-		h1 = (h1 << 13) | (h1 >> 19) // rotl32(h1, 13)
-		h1 = h1*5 + 0xe6546b64
+		h1 = bmix32(h1, *(*uint32)(unsafe.Pointer(&p[i*4])))
 	}
 	d.h1 = h1
 	return p[nblocks*d.Size():]
 }
 
 func (d *digest32) Sum32() (h1 uint32) {
+	return tmix32(d.h1, d.tail, d.clen)
+}
+
+// bmix32 mixes the 4 bytes block k1 into the running hash h1.
+func bmix32(h1, k1 uint32) uint32 {
+	k1 *= c1_32
+	k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
+	k1 *= c2_32
 
-	h1 = d.h1
+	h1 ^= k1
+	h1 = (h1 << 13) | (h1 >> 19) // rotl32(h1, 13)
+	return h1*5 + 0xe6546b64
+}
 
+// tmix32 mixes the fewer than 4 bytes of tail into the running hash h1 and
+// finalizes it for an input of clen bytes.
+func tmix32(h1 uint32, tail []byte, clen int) uint32 {
 	var k1 uint32
-	switch len(d.tail) & 3 {
+	switch len(tail) & 3 {
 	case 3:
-		k1 ^= uint32(d.tail[2]) << 16
+		k1 ^= uint32(tail[2]) << 16
 		fallthrough
 	case 2:
-		k1 ^= uint32(d.tail[1]) << 8
+		k1 ^= uint32(tail[1]) << 8
 		fallthrough
 	case 1:
-		k1 ^= uint32(d.tail[0])
+		k1 ^= uint32(tail[0])
 		k1 *= c1_32
 		k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
 		k1 *= c2_32
 		h1 ^= k1
 	}
 
-	h1 ^= uint32(d.clen)
-
-	h1 ^= h1 >> 16
-	h1 *= 0x85ebca6b
-	h1 ^= h1 >> 13
-	h1 *= 0xc2b2ae35
-	h1 ^= h1 >> 16
-
-	return h1
+	h1 ^= uint32(clen)
+	return fmix32(h1)
 }
 
 /*
@@ -107,48 +108,9 @@ func Sum32(data []byte, seed uint32) uint32 {
 	var h1 uint32 = seed
 
 	nblocks := len(data) / 4
-	var p uintptr
-	if len(data) > 0 {
-		p = uintptr(unsafe.Pointer(&data[0]))
-	}
-	p1 := p + uintptr(4*nblocks)
-	for ; p < p1; p += 4 {
-		k1 := *(*uint32)(unsafe.Pointer(p))
-
-		k1 *= c1_32
-		k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
-		k1 *= c2_32
-
-		h1 ^= k1
-		h1 = (h1 << 13) | (h1 >> 19) // rotl32(h1, 13)
-		h1 = h1*5 + 0xe6546b64
-	}
-
-	tail := data[nblocks*4:]
-
-	var k1 uint32
-	switch len(tail) & 3 {
-	case 3:
-		k1 ^= uint32(tail[2]) << 16
-		fallthrough
-	case 2:
-		k1 ^= uint32(tail[1]) << 8
-		fallthrough
-	case 1:
-		k1 ^= uint32(tail[0])
-		k1 *= c1_32
-		k1 = (k1 << 15) | (k1 >> 17) // rotl32(k1, 15)
-		k1 *= c2_32
-		h1 ^= k1
+	for i := 0; i < nblocks; i++ {
+		h1 = bmix32(h1, *(*uint32)(unsafe.Pointer(&data[i*4])))
 	}
 
-	h1 ^= uint32(len(data))
-
-	h1 ^= h1 >> 16
-	h1 *= 0x85ebca6b
-	h1 ^= h1 >> 13
-	h1 *= 0xc2b2ae35
-	h1 ^= h1 >> 16
-
-	return h1
+	return tmix32(h1, data[nblocks*4:], len(data))
 }
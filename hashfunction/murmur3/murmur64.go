@@ -37,9 +37,17 @@ func (d *digest64) Sum64() uint64 {
 //     hasher.Write(data)
 //     return hasher.Sum64()
 func Sum64(data []byte) uint64 {
-	d := &digest128{h1: 0, h2: 0}
-	d.tail = d.bmix(data)
-	d.clen = len(data)
-	h1, _ := d.Sum128()
+	return Sum64WithSeed(data, 0)
+}
+
+// Sum64WithSeed returns the first half of Sum128WithSeed. It is equivalent
+// to the following sequence (without the extra burden and the extra
+// allocation):
+//     hasher := New128(uint64(seed), uint64(seed))
+//     hasher.Write(data)
+//     h1, _ := hasher.Sum128()
+//     return h1
+func Sum64WithSeed(data []byte, seed uint32) uint64 {
+	h1, _ := Sum128WithSeed(data, seed)
 	return h1
 }
@@ -1,6 +1,7 @@
 package murmur3
 
 import (
+	"encoding/binary"
 	"hash"
 	"testing"
 )
@@ -56,10 +57,107 @@ func TestRef(t *testing.T) {
 	}
 }
 
+var data128x86 = []struct {
+	h1   uint64
+	h2   uint64
+	seed uint32
+	s    string
+}{
+	{0x0000000000000000, 0x0000000000000000, 0, ""},
+	{0xdb91def72b2444a0, 0x9adb31b69adb31b6, 0, "hello"},
+	{0xb9b98a1e8b21605c, 0xeb5957c793273a83, 0, "hello, world"},
+	{0x8d28ce425cea0ad4, 0x38ccaf8cb50613f0, 0, "19 Jan 2038 at 3:14:07 AM"},
+	{0x7dd6ed5e6cbb6099, 0x9b627b552bbf0fbb, 0, "The quick brown fox jumps over the lazy dog."},
+	{0x5b576a1cf7bed5a1, 0x5b576a1c5b576a1c, 0x9747b28c, ""},
+	{0x322922b590935c71, 0xd498b585d498b585, 0x9747b28c, "hello"},
+	{0x31038e8bc35d95c1, 0xbde79608d0bd1a6b, 0x9747b28c, "hello, world"},
+	{0xaf2ed330fd1ec50b, 0x9517e83b99fc3292, 0x9747b28c, "19 Jan 2038 at 3:14:07 AM"},
+	{0x33aeb22650717901, 0x2769e5553381824f, 0x9747b28c, "The quick brown fox jumps over the lazy dog."},
+}
+
+func TestRef128x86(t *testing.T) {
+	for _, elem := range data128x86 {
+		var h128 Hash128 = New128x86(elem.seed)
+		h128.Write([]byte(elem.s))
+		if v1, v2 := h128.Sum128(); v1 != elem.h1 || v2 != elem.h2 {
+			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h1, elem.h2)
+		}
+
+		if v1, v2 := Sum128x86([]byte(elem.s), elem.seed); v1 != elem.h1 || v2 != elem.h2 {
+			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h1, elem.h2)
+		}
+	}
+}
+
+// verification reproduces the self test of SMHasher, the reference test
+// suite: the hashes of {}, {0}, {0, 1}, ... {0, .., 254} with seeds 256,
+// 255, ... 2 are hashed with seed 0, and the first 4 bytes of the result
+// are returned.
+func verification(sum func(data []byte, seed uint32) []byte) uint32 {
+	key := make([]byte, 256)
+	var hashes []byte
+	for i := range key {
+		key[i] = byte(i)
+		hashes = append(hashes, sum(key[:i], uint32(256-i))...)
+	}
+	v := sum(hashes, 0)
+	return uint32(v[0]) | uint32(v[1])<<8 | uint32(v[2])<<16 | uint32(v[3])<<24
+}
+
+func le128(h1, h2 uint64) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, h1)
+	binary.LittleEndian.PutUint64(b[8:], h2)
+	return b
+}
+
+func TestVerification(t *testing.T) {
+	if v := verification(func(data []byte, seed uint32) []byte {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, Sum32(data, seed))
+		return b
+	}); v != 0xB0F57EE3 {
+		t.Errorf("x86_32: 0x%x (want 0xB0F57EE3)", v)
+	}
+	if v := verification(func(data []byte, seed uint32) []byte {
+		return le128(Sum128WithSeed(data, seed))
+	}); v != 0x6384BA69 {
+		t.Errorf("x64_128: 0x%x (want 0x6384BA69)", v)
+	}
+	if v := verification(func(data []byte, seed uint32) []byte {
+		return le128(Sum128x86(data, seed))
+	}); v != 0xB3ECE62A {
+		t.Errorf("x86_128: 0x%x (want 0xB3ECE62A)", v)
+	}
+}
+
+func TestSeeded(t *testing.T) {
+	for _, elem := range data {
+		const seed = 0x9747b28c
+		h128 := New128(seed, seed)
+		h128.Write([]byte(elem.s))
+		v1, v2 := h128.Sum128()
+		if w1, w2 := Sum128WithSeed([]byte(elem.s), seed); v1 != w1 || v2 != w2 {
+			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, w1, w2, v1, v2)
+		}
+		if w1 := Sum64WithSeed([]byte(elem.s), seed); v1 != w1 {
+			t.Errorf("'%s': 0x%x (want 0x%x)", elem.s, w1, v1)
+		}
+
+		// Reset must restore the seed.
+		h128.Reset()
+		h128.Write([]byte(elem.s))
+		if w1, w2 := h128.Sum128(); v1 != w1 || v2 != w2 {
+			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, w1, w2, v1, v2)
+		}
+	}
+}
+
 func TestIncremental(t *testing.T) {
 	for _, elem := range data {
 		h32 := New32(seed)
 		h128 := New128(seed64, seed64)
+		h128x86 := New128x86(seed)
 		for i, j, k := 0, 0, len(elem.s); i < k; i = j {
 			j = 2*i + 3
 			if j > k {
@@ -69,6 +167,7 @@ func TestIncremental(t *testing.T) {
 			print(s + "|")
 			h32.Write([]byte(s))
 			h128.Write([]byte(s))
+			h128x86.Write([]byte(s))
 		}
 		println()
 		if v := h32.Sum32(); v != elem.h32 {
@@ -77,6 +176,10 @@ func TestIncremental(t *testing.T) {
 		if v1, v2 := h128.Sum128(); v1 != elem.h64_1 || v2 != elem.h64_2 {
 			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h64_1, elem.h64_2)
 		}
+		w1, w2 := Sum128x86([]byte(elem.s), seed)
+		if v1, v2 := h128x86.Sum128(); v1 != w1 || v2 != w2 {
+			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, w1, w2)
+		}
 	}
 }
 
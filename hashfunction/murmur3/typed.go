@@ -0,0 +1,107 @@
+package murmur3
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// The functions below hash common fixed-width and string inputs without
+// converting them to []byte or allocating a hasher. Each returns the same
+// value as the corresponding function on the little-endian encoding of
+// its input, e.g. Sum32Uint64(v, seed) == Sum32(le64(v), seed).
+
+// Sum32Uint32 returns the MurmurHash3 sum of the 4 bytes of v.
+func Sum32Uint32(v uint32, seed uint32) uint32 {
+	return fmix32(bmix32(seed, v) ^ 4)
+}
+
+// Sum32Uint64 returns the MurmurHash3 sum of the 8 bytes of v.
+func Sum32Uint64(v uint64, seed uint32) uint32 {
+	h1 := bmix32(seed, uint32(v))
+	h1 = bmix32(h1, uint32(v>>32))
+	return fmix32(h1 ^ 8)
+}
+
+// Sum32String returns the MurmurHash3 sum of the bytes of s.
+func Sum32String(s string, seed uint32) uint32 {
+	return Sum32(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+}
+
+// Sum32Strings returns the MurmurHash3 sum of a tuple of strings: the
+// concatenation of every string preceded by its length as 4 bytes, so
+// that ("ab", "c") and ("a", "bc") hash differently.
+func Sum32Strings(ss []string, seed uint32) uint32 {
+	s := state32{h1: seed}
+	for _, v := range ss {
+		s.writeUint32(uint32(len(v)))
+		s.write(unsafe.Slice(unsafe.StringData(v), len(v)))
+	}
+	return s.sum()
+}
+
+// Sum64String returns the first half of the MurmurHash3 x64_128 sum of
+// the bytes of s.
+func Sum64String(s string, seed uint32) uint64 {
+	return Sum64WithSeed(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+}
+
+// Sum64Uint64 returns the first half of the MurmurHash3 x64_128 sum of the
+// 8 bytes of v.
+func Sum64Uint64(v uint64, seed uint32) uint64 {
+	h1, _ := Sum128Uint64(v, seed)
+	return h1
+}
+
+// Sum128String returns the MurmurHash3 x64_128 sum of the bytes of s.
+func Sum128String(s string, seed uint32) (h1 uint64, h2 uint64) {
+	return Sum128WithSeed(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+}
+
+// Sum128Uint64 returns the MurmurHash3 x64_128 sum of the 8 bytes of v.
+func Sum128Uint64(v uint64, seed uint32) (h1 uint64, h2 uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return Sum128WithSeed(b[:], seed)
+}
+
+// state32 is a streaming evaluation of Sum32 that, unlike digest32, lives
+// on the stack.
+type state32 struct {
+	h1   uint32
+	clen int
+	buf  [4]byte // Pending bytes not yet forming a full block.
+	nbuf int
+}
+
+func (s *state32) write(p []byte) {
+	s.clen += len(p)
+	if s.nbuf > 0 {
+		k := copy(s.buf[s.nbuf:], p)
+		s.nbuf += k
+		p = p[k:]
+		if s.nbuf < 4 {
+			return
+		}
+		s.h1 = bmix32(s.h1, binary.LittleEndian.Uint32(s.buf[:]))
+		s.nbuf = 0
+	}
+	for ; len(p) >= 4; p = p[4:] {
+		s.h1 = bmix32(s.h1, binary.LittleEndian.Uint32(p))
+	}
+	s.nbuf = copy(s.buf[:], p)
+}
+
+func (s *state32) writeUint32(v uint32) {
+	if s.nbuf == 0 {
+		s.clen += 4
+		s.h1 = bmix32(s.h1, v)
+		return
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	s.write(b[:])
+}
+
+func (s *state32) sum() uint32 {
+	return tmix32(s.h1, s.buf[:s.nbuf], s.clen)
+}
@@ -0,0 +1,99 @@
+package murmur3
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	b4 := make([]byte, 4)
+	b8 := make([]byte, 8)
+	for _, v := range []uint64{0, 1, 0xdeadbeef, 0x0123456789abcdef} {
+		binary.LittleEndian.PutUint32(b4, uint32(v))
+		binary.LittleEndian.PutUint64(b8, v)
+		if a, b := Sum32Uint32(uint32(v), 7), Sum32(b4, 7); a != b {
+			t.Errorf("0x%x: 0x%x (want 0x%x)", v, a, b)
+		}
+		if a, b := Sum32Uint64(v, 7), Sum32(b8, 7); a != b {
+			t.Errorf("0x%x: 0x%x (want 0x%x)", v, a, b)
+		}
+		if a, b := Sum64Uint64(v, 7), Sum64WithSeed(b8, 7); a != b {
+			t.Errorf("0x%x: 0x%x (want 0x%x)", v, a, b)
+		}
+		a1, a2 := Sum128Uint64(v, 7)
+		if b1, b2 := Sum128WithSeed(b8, 7); a1 != b1 || a2 != b2 {
+			t.Errorf("0x%x: 0x%x-0x%x (want 0x%x-0x%x)", v, a1, a2, b1, b2)
+		}
+	}
+	for _, elem := range data {
+		if v := Sum32String(elem.s, seed); v != elem.h32 {
+			t.Errorf("'%s': 0x%x (want 0x%x)", elem.s, v, elem.h32)
+		}
+		if v := Sum64String(elem.s, 0); v != elem.h64_1 {
+			t.Errorf("'%s': 0x%x (want 0x%x)", elem.s, v, elem.h64_1)
+		}
+		if v1, v2 := Sum128String(elem.s, 0); v1 != elem.h64_1 || v2 != elem.h64_2 {
+			t.Errorf("'%s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h64_1, elem.h64_2)
+		}
+	}
+}
+
+func TestSum32Strings(t *testing.T) {
+	tuples := [][]string{
+		{},
+		{""},
+		{"hello"},
+		{"ab", "c"},
+		{"a", "bc"},
+		{"hello", "", "world", "19 Jan 2038 at 3:14:07 AM"},
+	}
+	seen := make(map[uint32]bool)
+	for _, tuple := range tuples {
+		var buf []byte
+		for _, s := range tuple {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s)))
+			buf = append(buf, s...)
+		}
+		v := Sum32Strings(tuple, seed)
+		if w := Sum32(buf, seed); v != w {
+			t.Errorf("%q: 0x%x (want 0x%x)", tuple, v, w)
+		}
+		if seen[v] {
+			t.Errorf("%q: collision", tuple)
+		}
+		seen[v] = true
+	}
+}
+
+func TestTypedAllocs(t *testing.T) {
+	tuple := []string{"hello", "world", "19 Jan 2038 at 3:14:07 AM"}
+	n := testing.AllocsPerRun(100, func() {
+		Sum32Uint32(42, seed)
+		Sum32Uint64(42, seed)
+		Sum32String(tuple[2], seed)
+		Sum32Strings(tuple, seed)
+		Sum64String(tuple[2], seed)
+		Sum128Uint64(42, seed)
+	})
+	if n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+}
+
+//---
+
+func BenchmarkSum32Uint64(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sum32Uint64(uint64(i), seed)
+	}
+}
+
+func BenchmarkNew32Uint64(b *testing.B) {
+	buf := make([]byte, 8)
+	for i := 0; i < b.N; i++ {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		h := New32(seed)
+		h.Write(buf)
+		h.Sum32()
+	}
+}
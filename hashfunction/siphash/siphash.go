@@ -0,0 +1,231 @@
+/*
+Package siphash implements Jean-Philippe Aumasson and Daniel J. Bernstein's
+SipHash, a keyed pseudorandom function. Unlike MurmurHash3 or xxHash, its
+output cannot be predicted without the 128 bits key, so it resists
+hash-flooding: an attacker cannot craft inputs colliding in a sketch built
+with a secret key.
+
+Both the standard SipHash-2-4 and the faster SipHash-1-3 are provided, each
+with 64 and 128 bits output.
+
+	Reference implementation and paper:
+	   https://github.com/veorq/SipHash
+	   https://131002.net/siphash/siphash.pdf
+*/
+package siphash
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ hash.Hash   = new(digest)
+	_ hash.Hash64 = new(digest)
+	_ Hash128     = new(digest)
+)
+
+// Hack: the standard api doesn't define any Hash128 interface.
+type Hash128 interface {
+	hash.Hash
+	Sum128() (uint64, uint64)
+}
+
+// state is the four words of internal SipHash state.
+type state struct {
+	v0, v1, v2, v3 uint64
+}
+
+func initState(k0, k1 uint64, size int) state {
+	s := state{
+		v0: k0 ^ 0x736f6d6570736575,
+		v1: k1 ^ 0x646f72616e646f6d,
+		v2: k0 ^ 0x6c7967656e657261,
+		v3: k1 ^ 0x7465646279746573,
+	}
+	if size == 16 {
+		s.v1 ^= 0xee
+	}
+	return s
+}
+
+func (s *state) rounds(n int) {
+	v0, v1, v2, v3 := s.v0, s.v1, s.v2, s.v3
+	for i := 0; i < n; i++ {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	s.v0, s.v1, s.v2, s.v3 = v0, v1, v2, v3
+}
+
+func (s *state) compress(m uint64, c int) {
+	s.v3 ^= m
+	s.rounds(c)
+	s.v0 ^= m
+}
+
+// Digest as many 8 bytes blocks as possible.
+func (s *state) blocks(p []byte, c int) (tail []byte) {
+	for ; len(p) >= 8; p = p[8:] {
+		s.compress(binary.LittleEndian.Uint64(p), c)
+	}
+	return p
+}
+
+// final mixes the last block, made of the fewer than 8 bytes in tail and
+// the total length, and returns the 64 or 128 bits output.
+func (s state) final(tail []byte, clen, size, c, d int) (h1, h2 uint64) {
+	b := uint64(clen) << 56
+	for i, v := range tail {
+		b |= uint64(v) << (8 * uint(i))
+	}
+	s.compress(b, c)
+
+	if size == 16 {
+		s.v2 ^= 0xee
+	} else {
+		s.v2 ^= 0xff
+	}
+	s.rounds(d)
+	h1 = s.v0 ^ s.v1 ^ s.v2 ^ s.v3
+	if size == 8 {
+		return h1, 0
+	}
+	s.v1 ^= 0xdd
+	s.rounds(d)
+	h2 = s.v0 ^ s.v1 ^ s.v2 ^ s.v3
+	return h1, h2
+}
+
+// digest represents a partial evaluation of a SipHash-c-d with 8 or 16
+// bytes output.
+type digest struct {
+	k0, k1 uint64
+	c, d   int // Compression and finalization rounds.
+	size   int
+	s      state
+	clen   int     // Digested input cumulative length.
+	buf    [8]byte // Pending bytes not yet forming a full block.
+	nbuf   int
+}
+
+func newDigest(k0, k1 uint64, c, d, size int) *digest {
+	h := &digest{k0: k0, k1: k1, c: c, d: d, size: size}
+	h.Reset()
+	return h
+}
+
+// New64 returns a SipHash-2-4 hash.Hash64 keyed with k0 and k1.
+func New64(k0, k1 uint64) hash.Hash64 { return newDigest(k0, k1, 2, 4, 8) }
+
+// New128 returns a SipHash-2-4 Hash128 keyed with k0 and k1.
+func New128(k0, k1 uint64) Hash128 { return newDigest(k0, k1, 2, 4, 16) }
+
+// New64R13 returns a SipHash-1-3 hash.Hash64 keyed with k0 and k1.
+func New64R13(k0, k1 uint64) hash.Hash64 { return newDigest(k0, k1, 1, 3, 8) }
+
+// New128R13 returns a SipHash-1-3 Hash128 keyed with k0 and k1.
+func New128R13(k0, k1 uint64) Hash128 { return newDigest(k0, k1, 1, 3, 16) }
+
+func (h *digest) Size() int      { return h.size }
+func (h *digest) BlockSize() int { return 8 }
+
+func (h *digest) Reset() {
+	h.s = initState(h.k0, h.k1, h.size)
+	h.clen = 0
+	h.nbuf = 0
+}
+
+func (h *digest) Write(p []byte) (n int, err error) {
+	n = len(p)
+	h.clen += n
+
+	if h.nbuf > 0 {
+		k := copy(h.buf[h.nbuf:], p)
+		h.nbuf += k
+		p = p[k:]
+		if h.nbuf < 8 {
+			return n, nil
+		}
+		h.s.blocks(h.buf[:], h.c)
+		h.nbuf = 0
+	}
+	p = h.s.blocks(p, h.c)
+	h.nbuf = copy(h.buf[:], p)
+	return n, nil
+}
+
+// Sum appends the little-endian output of SipHash, as in the reference
+// implementation.
+func (h *digest) Sum(b []byte) []byte {
+	h1, h2 := h.Sum128()
+	b = binary.LittleEndian.AppendUint64(b, h1)
+	if h.size == 16 {
+		b = binary.LittleEndian.AppendUint64(b, h2)
+	}
+	return b
+}
+
+func (h *digest) Sum64() uint64 {
+	h1, _ := h.s.final(h.buf[:h.nbuf], h.clen, h.size, h.c, h.d)
+	return h1
+}
+
+// Sum128 returns both halves of the output, h1 holding its first 8 bytes.
+// The second half is 0 for a digest with 64 bits output.
+func (h *digest) Sum128() (h1, h2 uint64) {
+	return h.s.final(h.buf[:h.nbuf], h.clen, h.size, h.c, h.d)
+}
+
+func sum(k0, k1 uint64, data []byte, c, d, size int) (h1, h2 uint64) {
+	s := initState(k0, k1, size)
+	tail := s.blocks(data, c)
+	return s.final(tail, len(data), size, c, d)
+}
+
+// Sum64 returns the SipHash-2-4 sum of data keyed with k0 and k1. It is
+// equivalent to the following sequence (without the extra burden and the
+// extra allocation):
+//
+//	hasher := New64(k0, k1)
+//	hasher.Write(data)
+//	return hasher.Sum64()
+func Sum64(k0, k1 uint64, data []byte) uint64 {
+	h1, _ := sum(k0, k1, data, 2, 4, 8)
+	return h1
+}
+
+// Sum128 returns the SipHash-2-4 128 bits sum of data keyed with k0 and
+// k1, h1 holding the first 8 bytes of the output.
+func Sum128(k0, k1 uint64, data []byte) (h1, h2 uint64) {
+	return sum(k0, k1, data, 2, 4, 16)
+}
+
+// Sum64R13 returns the SipHash-1-3 sum of data keyed with k0 and k1.
+func Sum64R13(k0, k1 uint64, data []byte) uint64 {
+	h1, _ := sum(k0, k1, data, 1, 3, 8)
+	return h1
+}
+
+// Sum128R13 returns the SipHash-1-3 128 bits sum of data keyed with k0
+// and k1, h1 holding the first 8 bytes of the output.
+func Sum128R13(k0, k1 uint64, data []byte) (h1, h2 uint64) {
+	return sum(k0, k1, data, 1, 3, 16)
+}
@@ -0,0 +1,189 @@
+package siphash
+
+import (
+	"hash"
+	"testing"
+)
+
+// The key and messages of the SipHash paper: k = 00 01 .. 0f and
+// m = 00 01 .. (n-1).
+var k0, k1 uint64 = 0x0706050403020100, 0x0f0e0d0c0b0a0908
+
+var data = []struct {
+	h64       uint64
+	h128_1    uint64
+	h128_2    uint64
+	h64r13    uint64
+	h128r13_1 uint64
+	h128r13_2 uint64
+	n         int
+}{
+	{0x726fdb47dd0e0e31, 0xe6a825ba047f81a3, 0x930255c71472f66d, 0xabac0158050fc4dc, 0xbea58827b2bc7ee7, 0x013030dd6adb62fd, 0},
+	{0x74f839c593dc67fd, 0x44af996bd8c187da, 0x45fc229b11597634, 0xc9f49bf37d57ca93, 0xa8edd36004376ffc, 0x63f02f2bcc73055e, 1},
+	{0xab0200f58b01d137, 0x53c1dbd8beebf1a1, 0x3982f01fa64ab8c0, 0xd3927d989bb11140, 0xc3e0aaf223b98410, 0x77ab4808c82e2fa6, 7},
+	{0x93f5f5799a932462, 0x61f55862baa9623b, 0xb49714f364e2830f, 0x369095118d299a8e, 0xb4dae3d5e1fe12aa, 0x99c7f935ab164f72, 8},
+	{0xa129ca6149be45e5, 0x11a8b03399e99354, 0xd9c3cf970fec087e, 0xd320d86d2a519956, 0x6c52bdb205557ec1, 0x09017e1eeccd2129, 15},
+	{0x958a324ceb064572, 0x4a83502f77d15051, 0x7cbd3f979a063e50, 0x9d199062b7bbb3a8, 0x6f42fe4ee300584c, 0xad6052a70a6b9f07, 63},
+}
+
+func message(n int) []byte {
+	m := make([]byte, n)
+	for i := range m {
+		m[i] = byte(i)
+	}
+	return m
+}
+
+func TestRef(t *testing.T) {
+	for _, elem := range data {
+		m := message(elem.n)
+
+		var h64 hash.Hash64 = New64(k0, k1)
+		h64.Write(m)
+		if v := h64.Sum64(); v != elem.h64 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64)
+		}
+
+		if v := Sum64(k0, k1, m); v != elem.h64 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64)
+		}
+
+		var h128 Hash128 = New128(k0, k1)
+		h128.Write(m)
+		if v1, v2 := h128.Sum128(); v1 != elem.h128_1 || v2 != elem.h128_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128_1, elem.h128_2)
+		}
+
+		if v1, v2 := Sum128(k0, k1, m); v1 != elem.h128_1 || v2 != elem.h128_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128_1, elem.h128_2)
+		}
+
+		h64 = New64R13(k0, k1)
+		h64.Write(m)
+		if v := h64.Sum64(); v != elem.h64r13 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64r13)
+		}
+
+		if v := Sum64R13(k0, k1, m); v != elem.h64r13 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64r13)
+		}
+
+		h128 = New128R13(k0, k1)
+		h128.Write(m)
+		if v1, v2 := h128.Sum128(); v1 != elem.h128r13_1 || v2 != elem.h128r13_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128r13_1, elem.h128r13_2)
+		}
+
+		if v1, v2 := Sum128R13(k0, k1, m); v1 != elem.h128r13_1 || v2 != elem.h128r13_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128r13_1, elem.h128r13_2)
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	for _, elem := range data {
+		m := message(elem.n)
+		h64 := New64(k0, k1)
+		h128 := New128(k0, k1)
+		for i, j, k := 0, 0, len(m); i < k; i = j {
+			j = 2*i + 3
+			if j > k {
+				j = k
+			}
+			h64.Write(m[i:j])
+			h128.Write(m[i:j])
+		}
+		if v := h64.Sum64(); v != elem.h64 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64)
+		}
+		if v1, v2 := h128.Sum128(); v1 != elem.h128_1 || v2 != elem.h128_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128_1, elem.h128_2)
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	// The output of the paper is the little-endian encoding of the sum.
+	want := []byte{0xe5, 0x45, 0xbe, 0x49, 0x61, 0xca, 0x29, 0xa1}
+	h := New64(k0, k1)
+	h.Write(message(15))
+	if b := h.Sum(nil); string(b) != string(want) {
+		t.Errorf("%x (want %x)", b, want)
+	}
+	if b := New128(k0, k1).Sum(nil); len(b) != 16 {
+		t.Error(len(b))
+	}
+}
+
+//---
+
+func bench64(b *testing.B, length int) {
+	buf := make([]byte, length)
+	b.SetBytes(int64(length))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum64(k0, k1, buf)
+	}
+}
+
+func Benchmark64_8(b *testing.B) {
+	bench64(b, 8)
+}
+func Benchmark64_16(b *testing.B) {
+	bench64(b, 16)
+}
+func Benchmark64_128(b *testing.B) {
+	bench64(b, 128)
+}
+func Benchmark64_1024(b *testing.B) {
+	bench64(b, 1024)
+}
+
+//---
+
+func bench64R13(b *testing.B, length int) {
+	buf := make([]byte, length)
+	b.SetBytes(int64(length))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum64R13(k0, k1, buf)
+	}
+}
+
+func Benchmark64R13_8(b *testing.B) {
+	bench64R13(b, 8)
+}
+func Benchmark64R13_16(b *testing.B) {
+	bench64R13(b, 16)
+}
+func Benchmark64R13_128(b *testing.B) {
+	bench64R13(b, 128)
+}
+func Benchmark64R13_1024(b *testing.B) {
+	bench64R13(b, 1024)
+}
+
+func TestString(t *testing.T) {
+	for _, elem := range data {
+		s := string(message(elem.n))
+		if v := Sum64String(k0, k1, s); v != elem.h64 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64)
+		}
+		if v1, v2 := Sum128String(k0, k1, s); v1 != elem.h128_1 || v2 != elem.h128_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128_1, elem.h128_2)
+		}
+		if v := Sum64R13String(k0, k1, s); v != elem.h64r13 {
+			t.Errorf("%d: 0x%x (want 0x%x)", elem.n, v, elem.h64r13)
+		}
+		if v1, v2 := Sum128R13String(k0, k1, s); v1 != elem.h128r13_1 || v2 != elem.h128r13_2 {
+			t.Errorf("%d: 0x%x-0x%x (want 0x%x-0x%x)", elem.n, v1, v2, elem.h128r13_1, elem.h128r13_2)
+		}
+	}
+	s := string(message(63))
+	if n := testing.AllocsPerRun(100, func() {
+		Sum64String(k0, k1, s)
+		Sum64R13String(k0, k1, s)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+}
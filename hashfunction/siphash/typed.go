@@ -0,0 +1,25 @@
+package siphash
+
+import "unsafe"
+
+// The functions below hash strings without converting them to []byte.
+
+// Sum64String returns the SipHash-2-4 sum of the bytes of s.
+func Sum64String(k0, k1 uint64, s string) uint64 {
+	return Sum64(k0, k1, unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Sum128String returns the SipHash-2-4 128 bits sum of the bytes of s.
+func Sum128String(k0, k1 uint64, s string) (h1, h2 uint64) {
+	return Sum128(k0, k1, unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Sum64R13String returns the SipHash-1-3 sum of the bytes of s.
+func Sum64R13String(k0, k1 uint64, s string) uint64 {
+	return Sum64R13(k0, k1, unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Sum128R13String returns the SipHash-1-3 128 bits sum of the bytes of s.
+func Sum128R13String(k0, k1 uint64, s string) (h1, h2 uint64) {
+	return Sum128R13(k0, k1, unsafe.Slice(unsafe.StringData(s), len(s)))
+}
@@ -0,0 +1,40 @@
+package xxhash
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+// The functions below hash strings and fixed-width inputs without
+// converting them to []byte. Each returns the same value as the
+// corresponding function on the little-endian encoding of its input.
+
+// Sum64String returns the xxHash64 sum of the bytes of s.
+func Sum64String(s string, seed uint64) uint64 {
+	return Sum64(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+}
+
+// Sum64Uint64 returns the xxHash64 sum of the 8 bytes of v.
+func Sum64Uint64(v uint64, seed uint64) uint64 {
+	h := seed + prime64_5 + 8
+	h ^= round64(0, v)
+	h = rotl64(h, 27)*prime64_1 + prime64_4
+	return xxh64Avalanche(h)
+}
+
+// Sum3String returns the XXH3 64 bits sum of the bytes of s.
+func Sum3String(s string, seed uint64) uint64 {
+	return Sum3(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+}
+
+// Sum3Uint64 returns the XXH3 64 bits sum of the 8 bytes of v.
+func Sum3Uint64(v uint64, seed uint64) uint64 {
+	seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+	bitflip := (u64(kSecret[8:]) ^ u64(kSecret[16:])) - seed
+	return rrmxmx(rotl64(v, 32)^bitflip, 8)
+}
+
+// Sum128String returns the XXH128 sum of the bytes of s.
+func Sum128String(s string, seed uint64) (hi, lo uint64) {
+	return Sum128(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+}
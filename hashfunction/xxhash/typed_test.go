@@ -0,0 +1,46 @@
+package xxhash
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	b := make([]byte, 8)
+	for _, v := range []uint64{0, 1, 0xdeadbeef, 0x0123456789abcdef} {
+		binary.LittleEndian.PutUint64(b, v)
+		for _, seed := range []uint64{0, 0x2545f4914f6cdd1d} {
+			if x, y := Sum64Uint64(v, seed), Sum64(b, seed); x != y {
+				t.Errorf("0x%x: 0x%x (want 0x%x)", v, x, y)
+			}
+			if x, y := Sum3Uint64(v, seed), Sum3(b, seed); x != y {
+				t.Errorf("0x%x: 0x%x (want 0x%x)", v, x, y)
+			}
+		}
+	}
+	for _, elem := range data {
+		if v := Sum64String(elem.s, elem.seed); v != elem.h64 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h64)
+		}
+		if v := Sum3String(elem.s, elem.seed); v != elem.h3 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h3)
+		}
+		if v1, v2 := Sum128String(elem.s, elem.seed); v1 != elem.h128hi || v2 != elem.h128lo {
+			t.Errorf("'%.20s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h128hi, elem.h128lo)
+		}
+	}
+}
+
+func TestTypedAllocs(t *testing.T) {
+	s := data[len(data)-1].s
+	n := testing.AllocsPerRun(100, func() {
+		Sum64String(s, 0)
+		Sum3String(s, 0)
+		Sum128String(s, 0)
+		Sum64Uint64(42, 0)
+		Sum3Uint64(42, 0)
+	})
+	if n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+}
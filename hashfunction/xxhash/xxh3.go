@@ -0,0 +1,471 @@
+package xxhash
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ hash.Hash   = new(digest3)
+	_ hash.Hash64 = new(digest3)
+	_ Hash128     = new(digest128)
+)
+
+const (
+	primeMx1 uint64 = 0x165667919E3779F9
+	primeMx2 uint64 = 0x9FB21C651E98DF25
+
+	secretSize   = 192
+	stripeLen    = 64
+	accNb        = stripeLen / 8
+	stripesBlock = (secretSize - stripeLen) / 8 // Stripes per block.
+	blockLen     = stripeLen * stripesBlock
+	midSizeMax   = 240
+	bufSize      = 256 // Streaming buffer, a multiple of stripeLen.
+)
+
+// kSecret is the default secret of XXH3.
+var kSecret = [secretSize]byte{
+	0xb8, 0xfe, 0x6c, 0x39, 0x23, 0xa4, 0x4b, 0xbe, 0x7c, 0x01, 0x81, 0x2c, 0xf7, 0x21, 0xad, 0x1c,
+	0xde, 0xd4, 0x6d, 0xe9, 0x83, 0x90, 0x97, 0xdb, 0x72, 0x40, 0xa4, 0xa4, 0xb7, 0xb3, 0x67, 0x1f,
+	0xcb, 0x79, 0xe6, 0x4e, 0xcc, 0xc0, 0xe5, 0x78, 0x82, 0x5a, 0xd0, 0x7d, 0xcc, 0xff, 0x72, 0x21,
+	0xb8, 0x08, 0x46, 0x74, 0xf7, 0x43, 0x24, 0x8e, 0xe0, 0x35, 0x90, 0xe6, 0x81, 0x3a, 0x26, 0x4c,
+	0x3c, 0x28, 0x52, 0xbb, 0x91, 0xc3, 0x00, 0xcb, 0x88, 0xd0, 0x65, 0x8b, 0x1b, 0x53, 0x2e, 0xa3,
+	0x71, 0x64, 0x48, 0x97, 0xa2, 0x0d, 0xf9, 0x4e, 0x38, 0x19, 0xef, 0x46, 0xa9, 0xde, 0xac, 0xd8,
+	0xa8, 0xfa, 0x76, 0x3f, 0xe3, 0x9c, 0x34, 0x3f, 0xf9, 0xdc, 0xbb, 0xc7, 0xc7, 0x0b, 0x4f, 0x1d,
+	0x8a, 0x51, 0xe0, 0x4b, 0xcd, 0xb4, 0x59, 0x31, 0xc8, 0x9f, 0x7e, 0xc9, 0xd9, 0x78, 0x73, 0x64,
+	0xea, 0xc5, 0xac, 0x83, 0x34, 0xd3, 0xeb, 0xc3, 0xc5, 0x81, 0xa0, 0xff, 0xfa, 0x13, 0x63, 0xeb,
+	0x17, 0x0d, 0xdd, 0x51, 0xb7, 0xf0, 0xda, 0x49, 0xd3, 0x16, 0x55, 0x26, 0x29, 0xd4, 0x68, 0x9e,
+	0x2b, 0x16, 0xbe, 0x58, 0x7d, 0x47, 0xa1, 0xfc, 0x8f, 0xf8, 0xb8, 0xd1, 0x7a, 0xd0, 0x31, 0xce,
+	0x45, 0xcb, 0x3a, 0x8f, 0x95, 0x16, 0x04, 0x28, 0xaf, 0xd7, 0xfb, 0xca, 0xbb, 0x4b, 0x40, 0x7e,
+}
+
+var initAcc = [accNb]uint64{
+	prime32_3, prime64_1, prime64_2, prime64_3,
+	prime64_4, prime32_2, prime64_5, prime32_1,
+}
+
+func mul128Fold64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func xxh3Avalanche(h uint64) uint64 {
+	h ^= h >> 37
+	h *= primeMx1
+	h ^= h >> 32
+	return h
+}
+
+func rrmxmx(h uint64, n int) uint64 {
+	h ^= rotl64(h, 49) ^ rotl64(h, 24)
+	h *= primeMx2
+	h ^= (h >> 35) + uint64(n)
+	h *= primeMx2
+	h ^= h >> 28
+	return h
+}
+
+func mix16B(p, secret []byte, seed uint64) uint64 {
+	return mul128Fold64(u64(p)^(u64(secret)+seed), u64(p[8:])^(u64(secret[8:])-seed))
+}
+
+// deriveSecret returns the secret used for long inputs hashed with seed.
+func deriveSecret(seed uint64) (secret [secretSize]byte) {
+	if seed == 0 {
+		return kSecret
+	}
+	b := binary.LittleEndian
+	for i := 0; i < secretSize; i += 16 {
+		b.PutUint64(secret[i:], u64(kSecret[i:])+seed)
+		b.PutUint64(secret[i+8:], u64(kSecret[i+8:])-seed)
+	}
+	return secret
+}
+
+//---
+
+// Sum3 returns the XXH3 64 bits sum of data. It is equivalent to the
+// following sequence (without the extra burden and the extra allocation):
+//
+//	hasher := New3(seed)
+//	hasher.Write(data)
+//	return hasher.Sum64()
+func Sum3(data []byte, seed uint64) uint64 {
+	n := len(data)
+	switch {
+	case n <= 16:
+		return xxh3Len0To16(data, seed)
+	case n <= 128:
+		return xxh3Len17To128(data, seed)
+	case n <= midSizeMax:
+		return xxh3Len129To240(data, seed)
+	}
+	secret := deriveSecret(seed)
+	acc := initAcc
+	hashLong(&acc, data, &secret)
+	return mergeAccs(&acc, secret[11:], uint64(n)*prime64_1)
+}
+
+func xxh3Len0To16(p []byte, seed uint64) uint64 {
+	n := len(p)
+	s := kSecret[:]
+	switch {
+	case n > 8:
+		bitflip1 := (u64(s[24:]) ^ u64(s[32:])) + seed
+		bitflip2 := (u64(s[40:]) ^ u64(s[48:])) - seed
+		lo := u64(p) ^ bitflip1
+		hi := u64(p[n-8:]) ^ bitflip2
+		acc := uint64(n) + bits.ReverseBytes64(lo) + hi + mul128Fold64(lo, hi)
+		return xxh3Avalanche(acc)
+	case n >= 4:
+		seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+		bitflip := (u64(s[8:]) ^ u64(s[16:])) - seed
+		input := u32(p[n-4:]) + u32(p)<<32
+		return rrmxmx(input^bitflip, n)
+	case n > 0:
+		combined := uint64(p[0])<<16 | uint64(p[n>>1])<<24 |
+			uint64(p[n-1]) | uint64(n)<<8
+		bitflip := (u32(s) ^ u32(s[4:])) + seed
+		return xxh64Avalanche(combined ^ bitflip)
+	}
+	return xxh64Avalanche(seed ^ u64(s[56:]) ^ u64(s[64:]))
+}
+
+func xxh3Len17To128(p []byte, seed uint64) uint64 {
+	n := len(p)
+	s := kSecret[:]
+	acc := uint64(n) * prime64_1
+	if n > 32 {
+		if n > 64 {
+			if n > 96 {
+				acc += mix16B(p[48:], s[96:], seed)
+				acc += mix16B(p[n-64:], s[112:], seed)
+			}
+			acc += mix16B(p[32:], s[64:], seed)
+			acc += mix16B(p[n-48:], s[80:], seed)
+		}
+		acc += mix16B(p[16:], s[32:], seed)
+		acc += mix16B(p[n-32:], s[48:], seed)
+	}
+	acc += mix16B(p, s, seed)
+	acc += mix16B(p[n-16:], s[16:], seed)
+	return xxh3Avalanche(acc)
+}
+
+func xxh3Len129To240(p []byte, seed uint64) uint64 {
+	n := len(p)
+	s := kSecret[:]
+	acc := uint64(n) * prime64_1
+	for i := 0; i < 8; i++ {
+		acc += mix16B(p[16*i:], s[16*i:], seed)
+	}
+	acc = xxh3Avalanche(acc)
+	for i := 8; i < n/16; i++ {
+		acc += mix16B(p[16*i:], s[16*(i-8)+3:], seed)
+	}
+	acc += mix16B(p[n-16:], s[136-17:], seed)
+	return xxh3Avalanche(acc)
+}
+
+//---
+
+// Sum128 returns the XXH128 sum of data, the 128 bits variant of XXH3.
+// It is equivalent to the following sequence (without the extra burden
+// and the extra allocation):
+//
+//	hasher := New128(seed)
+//	hasher.Write(data)
+//	return hasher.Sum128()
+func Sum128(data []byte, seed uint64) (hi, lo uint64) {
+	n := len(data)
+	switch {
+	case n <= 16:
+		return xxh128Len0To16(data, seed)
+	case n <= 128:
+		return xxh128Len17To128(data, seed)
+	case n <= midSizeMax:
+		return xxh128Len129To240(data, seed)
+	}
+	secret := deriveSecret(seed)
+	acc := initAcc
+	hashLong(&acc, data, &secret)
+	return merge128(&acc, &secret, n)
+}
+
+func xxh128Len0To16(p []byte, seed uint64) (hi, lo uint64) {
+	n := len(p)
+	s := kSecret[:]
+	switch {
+	case n > 8:
+		bitflipl := (u64(s[32:]) ^ u64(s[40:])) - seed
+		bitfliph := (u64(s[48:]) ^ u64(s[56:])) + seed
+		inlo := u64(p)
+		inhi := u64(p[n-8:])
+		mhi, mlo := bits.Mul64(inlo^inhi^bitflipl, prime64_1)
+		mlo += uint64(n-1) << 54
+		inhi ^= bitfliph
+		mhi += inhi + (inhi&0xFFFFFFFF)*(prime32_2-1)
+		mlo ^= bits.ReverseBytes64(mhi)
+		hi, lo = bits.Mul64(mlo, prime64_2)
+		hi += mhi * prime64_2
+		return xxh3Avalanche(hi), xxh3Avalanche(lo)
+	case n >= 4:
+		seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+		input := u32(p) + u32(p[n-4:])<<32
+		bitflip := (u64(s[16:]) ^ u64(s[24:])) + seed
+		hi, lo = bits.Mul64(input^bitflip, prime64_1+uint64(n)<<2)
+		hi += lo << 1
+		lo ^= hi >> 3
+		lo ^= lo >> 35
+		lo *= primeMx2
+		lo ^= lo >> 28
+		return xxh3Avalanche(hi), lo
+	case n > 0:
+		combinedl := uint32(p[0])<<16 | uint32(p[n>>1])<<24 |
+			uint32(p[n-1]) | uint32(n)<<8
+		combinedh := bits.RotateLeft32(bits.ReverseBytes32(combinedl), 13)
+		bitflipl := (u32(s) ^ u32(s[4:])) + seed
+		bitfliph := (u32(s[8:]) ^ u32(s[12:])) - seed
+		return xxh64Avalanche(uint64(combinedh) ^ bitfliph),
+			xxh64Avalanche(uint64(combinedl) ^ bitflipl)
+	}
+	return xxh64Avalanche(seed ^ u64(s[80:]) ^ u64(s[88:])),
+		xxh64Avalanche(seed ^ u64(s[64:]) ^ u64(s[72:]))
+}
+
+func mix32B(acclo, acchi uint64, p1, p2, secret []byte, seed uint64) (uint64, uint64) {
+	acclo += mix16B(p1, secret, seed)
+	acclo ^= u64(p2) + u64(p2[8:])
+	acchi += mix16B(p2, secret[16:], seed)
+	acchi ^= u64(p1) + u64(p1[8:])
+	return acclo, acchi
+}
+
+func finish128(acclo, acchi uint64, n int, seed uint64) (hi, lo uint64) {
+	lo = acclo + acchi
+	hi = acclo*prime64_1 + acchi*prime64_4 + (uint64(n)-seed)*prime64_2
+	return -xxh3Avalanche(hi), xxh3Avalanche(lo)
+}
+
+func xxh128Len17To128(p []byte, seed uint64) (hi, lo uint64) {
+	n := len(p)
+	s := kSecret[:]
+	acclo, acchi := uint64(n)*prime64_1, uint64(0)
+	if n > 32 {
+		if n > 64 {
+			if n > 96 {
+				acclo, acchi = mix32B(acclo, acchi, p[48:], p[n-64:], s[96:], seed)
+			}
+			acclo, acchi = mix32B(acclo, acchi, p[32:], p[n-48:], s[64:], seed)
+		}
+		acclo, acchi = mix32B(acclo, acchi, p[16:], p[n-32:], s[32:], seed)
+	}
+	acclo, acchi = mix32B(acclo, acchi, p, p[n-16:], s, seed)
+	return finish128(acclo, acchi, n, seed)
+}
+
+func xxh128Len129To240(p []byte, seed uint64) (hi, lo uint64) {
+	n := len(p)
+	s := kSecret[:]
+	acclo, acchi := uint64(n)*prime64_1, uint64(0)
+	for i := 0; i < 4; i++ {
+		acclo, acchi = mix32B(acclo, acchi, p[32*i:], p[32*i+16:], s[32*i:], seed)
+	}
+	acclo, acchi = xxh3Avalanche(acclo), xxh3Avalanche(acchi)
+	for i := 4; i < n/32; i++ {
+		acclo, acchi = mix32B(acclo, acchi, p[32*i:], p[32*i+16:], s[32*(i-4)+3:], seed)
+	}
+	acclo, acchi = mix32B(acclo, acchi, p[n-16:], p[n-32:], s[136-17-16:], -seed)
+	return finish128(acclo, acchi, n, seed)
+}
+
+//---
+
+func accumulate512(acc *[accNb]uint64, p, secret []byte) {
+	for i := 0; i < accNb; i++ {
+		v := u64(p[8*i:])
+		k := v ^ u64(secret[8*i:])
+		acc[i^1] += v
+		acc[i] += (k & 0xFFFFFFFF) * (k >> 32)
+	}
+}
+
+func accumulate(acc *[accNb]uint64, p, secret []byte, nbStripes int) {
+	for n := 0; n < nbStripes; n++ {
+		accumulate512(acc, p[n*stripeLen:], secret[n*8:])
+	}
+}
+
+func scramble(acc *[accNb]uint64, secret []byte) {
+	for i := 0; i < accNb; i++ {
+		a := acc[i]
+		a ^= a >> 47
+		a ^= u64(secret[8*i:])
+		acc[i] = a * prime32_1
+	}
+}
+
+// hashLong accumulates every stripe of p, which must be longer than
+// midSizeMax, into acc.
+func hashLong(acc *[accNb]uint64, p []byte, secret *[secretSize]byte) {
+	n := len(p)
+	nbBlocks := (n - 1) / blockLen
+	for i := 0; i < nbBlocks; i++ {
+		accumulate(acc, p[i*blockLen:], secret[:], stripesBlock)
+		scramble(acc, secret[secretSize-stripeLen:])
+	}
+	nbStripes := ((n - 1) - blockLen*nbBlocks) / stripeLen
+	accumulate(acc, p[nbBlocks*blockLen:], secret[:], nbStripes)
+	accumulate512(acc, p[n-stripeLen:], secret[secretSize-stripeLen-7:])
+}
+
+func mergeAccs(acc *[accNb]uint64, secret []byte, start uint64) uint64 {
+	h := start
+	for i := 0; i < 4; i++ {
+		h += mul128Fold64(acc[2*i]^u64(secret[16*i:]), acc[2*i+1]^u64(secret[16*i+8:]))
+	}
+	return xxh3Avalanche(h)
+}
+
+func merge128(acc *[accNb]uint64, secret *[secretSize]byte, n int) (hi, lo uint64) {
+	lo = mergeAccs(acc, secret[11:], uint64(n)*prime64_1)
+	hi = mergeAccs(acc, secret[secretSize-stripeLen-11:], ^(uint64(n) * prime64_2))
+	return hi, lo
+}
+
+//---
+
+// digest3 represents a partial evaluation of a XXH3 hash.
+type digest3 struct {
+	seed      uint64
+	secret    [secretSize]byte
+	acc       [accNb]uint64
+	clen      int // Digested input cumulative length.
+	stripes   int // Stripes accumulated in the current block.
+	buf       [bufSize]byte
+	nbuf      int
+	finalized [accNb]uint64 // Scratch for the final accumulation.
+}
+
+// digest128 is a digest3 producing XXH128 sums.
+type digest128 digest3
+
+// New3 returns a XXH3 64 bits hash.Hash64 using seed.
+func New3(seed uint64) hash.Hash64 {
+	d := &digest3{seed: seed, secret: deriveSecret(seed)}
+	d.Reset()
+	return d
+}
+
+// New128 returns a XXH128 Hash128 using seed.
+func New128(seed uint64) Hash128 {
+	return (*digest128)(New3(seed).(*digest3))
+}
+
+func (d *digest3) Size() int      { return 8 }
+func (d *digest3) BlockSize() int { return stripeLen }
+
+func (d *digest3) Reset() {
+	d.acc = initAcc
+	d.clen = 0
+	d.stripes = 0
+	d.nbuf = 0
+}
+
+// consume accumulates nbStripes stripes of p, scrambling at block ends.
+func (d *digest3) consume(acc *[accNb]uint64, stripes *int, p []byte, nbStripes int) {
+	if left := stripesBlock - *stripes; left <= nbStripes {
+		accumulate(acc, p, d.secret[*stripes*8:], left)
+		scramble(acc, d.secret[secretSize-stripeLen:])
+		accumulate(acc, p[left*stripeLen:], d.secret[:], nbStripes-left)
+		*stripes = nbStripes - left
+	} else {
+		accumulate(acc, p, d.secret[*stripes*8:], nbStripes)
+		*stripes += nbStripes
+	}
+}
+
+func (d *digest3) Write(p []byte) (n int, err error) {
+	n = len(p)
+	d.clen += n
+
+	if len(p) <= bufSize-d.nbuf {
+		d.nbuf += copy(d.buf[d.nbuf:], p)
+		return n, nil
+	}
+	// At least one byte is always kept buffered, so the last stripe is
+	// only consumed once the total length is known.
+	if d.nbuf > 0 {
+		k := copy(d.buf[d.nbuf:], p)
+		p = p[k:]
+		d.consume(&d.acc, &d.stripes, d.buf[:], bufSize/stripeLen)
+		d.nbuf = 0
+	}
+	if len(p) > bufSize {
+		var consumed []byte
+		for len(p) > bufSize {
+			d.consume(&d.acc, &d.stripes, p, bufSize/stripeLen)
+			consumed, p = p[:bufSize], p[bufSize:]
+		}
+		// Keep the last consumed stripe in case the final one needs it.
+		copy(d.buf[bufSize-stripeLen:], consumed[bufSize-stripeLen:])
+	}
+	d.nbuf = copy(d.buf[:], p)
+	return n, nil
+}
+
+// long finishes the accumulation of an input longer than midSizeMax.
+func (d *digest3) long() *[accNb]uint64 {
+	acc := &d.finalized
+	*acc = d.acc
+	var last [stripeLen]byte
+	if d.nbuf >= stripeLen {
+		stripes := d.stripes
+		d.consume(acc, &stripes, d.buf[:], (d.nbuf-1)/stripeLen)
+		copy(last[:], d.buf[d.nbuf-stripeLen:d.nbuf])
+	} else {
+		k := copy(last[:], d.buf[bufSize-(stripeLen-d.nbuf):])
+		copy(last[k:], d.buf[:d.nbuf])
+	}
+	accumulate512(acc, last[:], d.secret[secretSize-stripeLen-7:])
+	return acc
+}
+
+func (d *digest3) Sum(b []byte) []byte {
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], d.Sum64())
+	return append(b, s[:]...)
+}
+
+func (d *digest3) Sum64() uint64 {
+	if d.clen <= midSizeMax {
+		return Sum3(d.buf[:d.nbuf], d.seed)
+	}
+	return mergeAccs(d.long(), d.secret[11:], uint64(d.clen)*prime64_1)
+}
+
+func (d *digest128) Size() int      { return 16 }
+func (d *digest128) BlockSize() int { return stripeLen }
+func (d *digest128) Reset()         { (*digest3)(d).Reset() }
+
+func (d *digest128) Write(p []byte) (n int, err error) {
+	return (*digest3)(d).Write(p)
+}
+
+func (d *digest128) Sum(b []byte) []byte {
+	var s [16]byte
+	hi, lo := d.Sum128()
+	binary.BigEndian.PutUint64(s[:], hi)
+	binary.BigEndian.PutUint64(s[8:], lo)
+	return append(b, s[:]...)
+}
+
+func (d *digest128) Sum128() (hi, lo uint64) {
+	if d.clen <= midSizeMax {
+		return Sum128(d.buf[:d.nbuf], d.seed)
+	}
+	return merge128((*digest3)(d).long(), &d.secret, d.clen)
+}
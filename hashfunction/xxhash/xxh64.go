@@ -0,0 +1,158 @@
+package xxhash
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ hash.Hash   = new(digest64)
+	_ hash.Hash64 = new(digest64)
+)
+
+// digest64 represents a partial evaluation of a xxHash64.
+type digest64 struct {
+	seed uint64
+	v    [4]uint64 // Lanes of the running hash.
+	clen int       // Digested input cumulative length.
+	buf  [32]byte  // Pending bytes not yet forming a full stripe.
+	nbuf int
+}
+
+// New64 returns a xxHash64 hash.Hash64 using seed.
+func New64(seed uint64) hash.Hash64 {
+	d := &digest64{seed: seed}
+	d.Reset()
+	return d
+}
+
+func (d *digest64) Size() int      { return 8 }
+func (d *digest64) BlockSize() int { return 32 }
+
+func (d *digest64) Reset() {
+	d.v = [4]uint64{
+		d.seed + prime64_1 + prime64_2,
+		d.seed + prime64_2,
+		d.seed,
+		d.seed - prime64_1,
+	}
+	d.clen = 0
+	d.nbuf = 0
+}
+
+func (d *digest64) Write(p []byte) (n int, err error) {
+	n = len(p)
+	d.clen += n
+
+	if d.nbuf+len(p) < 32 {
+		d.nbuf += copy(d.buf[d.nbuf:], p)
+		return n, nil
+	}
+	if d.nbuf > 0 {
+		// Complete the pending stripe first.
+		k := copy(d.buf[d.nbuf:], p)
+		p = p[k:]
+		d.stripes(d.buf[:])
+		d.nbuf = 0
+	}
+	p = d.stripes(p)
+	d.nbuf = copy(d.buf[:], p)
+	return n, nil
+}
+
+// Digest as many 32 bytes stripes as possible.
+func (d *digest64) stripes(p []byte) (tail []byte) {
+	v1, v2, v3, v4 := d.v[0], d.v[1], d.v[2], d.v[3]
+	for ; len(p) >= 32; p = p[32:] {
+		v1 = round64(v1, u64(p[0:]))
+		v2 = round64(v2, u64(p[8:]))
+		v3 = round64(v3, u64(p[16:]))
+		v4 = round64(v4, u64(p[24:]))
+	}
+	d.v[0], d.v[1], d.v[2], d.v[3] = v1, v2, v3, v4
+	return p
+}
+
+func (d *digest64) Sum(b []byte) []byte {
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], d.Sum64())
+	return append(b, s[:]...)
+}
+
+func (d *digest64) Sum64() uint64 {
+	var h uint64
+	if d.clen >= 32 {
+		h = converge64(d.v)
+	} else {
+		h = d.seed + prime64_5
+	}
+	return finalize64(h, d.buf[:d.nbuf], d.clen)
+}
+
+func round64(acc, input uint64) uint64 {
+	acc += input * prime64_2
+	acc = rotl64(acc, 31)
+	return acc * prime64_1
+}
+
+func mergeRound64(acc, val uint64) uint64 {
+	acc ^= round64(0, val)
+	return acc*prime64_1 + prime64_4
+}
+
+func converge64(v [4]uint64) uint64 {
+	h := rotl64(v[0], 1) + rotl64(v[1], 7) + rotl64(v[2], 12) + rotl64(v[3], 18)
+	h = mergeRound64(h, v[0])
+	h = mergeRound64(h, v[1])
+	h = mergeRound64(h, v[2])
+	return mergeRound64(h, v[3])
+}
+
+// finalize64 mixes the remaining fewer than 32 bytes in tail into h.
+func finalize64(h uint64, tail []byte, clen int) uint64 {
+	h += uint64(clen)
+	for ; len(tail) >= 8; tail = tail[8:] {
+		h ^= round64(0, u64(tail))
+		h = rotl64(h, 27)*prime64_1 + prime64_4
+	}
+	if len(tail) >= 4 {
+		h ^= u32(tail) * prime64_1
+		h = rotl64(h, 23)*prime64_2 + prime64_3
+		tail = tail[4:]
+	}
+	for _, c := range tail {
+		h ^= uint64(c) * prime64_5
+		h = rotl64(h, 11) * prime64_1
+	}
+	return xxh64Avalanche(h)
+}
+
+// Sum64 returns the xxHash64 sum of data. It is equivalent to the
+// following sequence (without the extra burden and the extra allocation):
+//
+//	hasher := New64(seed)
+//	hasher.Write(data)
+//	return hasher.Sum64()
+func Sum64(data []byte, seed uint64) uint64 {
+	var h uint64
+	n := len(data)
+	if n >= 32 {
+		v := [4]uint64{
+			seed + prime64_1 + prime64_2,
+			seed + prime64_2,
+			seed,
+			seed - prime64_1,
+		}
+		for ; len(data) >= 32; data = data[32:] {
+			v[0] = round64(v[0], u64(data[0:]))
+			v[1] = round64(v[1], u64(data[8:]))
+			v[2] = round64(v[2], u64(data[16:]))
+			v[3] = round64(v[3], u64(data[24:]))
+		}
+		h = converge64(v)
+	} else {
+		h = seed + prime64_5
+	}
+	return finalize64(h, data, n)
+}
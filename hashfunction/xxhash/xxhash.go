@@ -0,0 +1,51 @@
+/*
+Package xxhash implements Yann Collet's non-cryptographic xxHash family:
+the 64-bit xxHash64 (XXH64) and the 64- and 128-bit XXH3 (XXH3_64bits and
+XXH128). XXH3 is considerably faster than MurmurHash3 on short keys.
+
+	Reference implementation and specification:
+	   https://github.com/Cyan4973/xxHash
+	   https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md
+*/
+package xxhash
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	prime32_1 uint64 = 0x9E3779B1
+	prime32_2 uint64 = 0x85EBCA77
+	prime32_3 uint64 = 0xC2B2AE3D
+
+	prime64_1 uint64 = 0x9E3779B185EBCA87
+	prime64_2 uint64 = 0xC2B2AE3D27D4EB4F
+	prime64_3 uint64 = 0x165667B19E3779F9
+	prime64_4 uint64 = 0x85EBCA77C2B2AE63
+	prime64_5 uint64 = 0x27D4EB2F165667C5
+)
+
+// Hash128 is a hash.Hash that also exposes its 128 bits sum as a pair of
+// uint64, the high half first.
+type Hash128 interface {
+	hash.Hash
+	Sum128() (hi, lo uint64)
+}
+
+func u32(b []byte) uint64 { return uint64(binary.LittleEndian.Uint32(b)) }
+func u64(b []byte) uint64 { return binary.LittleEndian.Uint64(b) }
+
+func rotl64(x uint64, r int) uint64 { return bits.RotateLeft64(x, r) }
+
+// xxh64Avalanche is the final mix of XXH64, also used by XXH3 for
+// inputs up to 3 bytes long.
+func xxh64Avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= prime64_2
+	h ^= h >> 29
+	h *= prime64_3
+	h ^= h >> 32
+	return h
+}
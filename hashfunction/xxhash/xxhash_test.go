@@ -0,0 +1,206 @@
+package xxhash
+
+import (
+	"hash"
+	"strings"
+	"testing"
+)
+
+const fox = "The quick brown fox jumps over the lazy dog. "
+
+// Reference values computed with libxxhash 0.8.1.
+var data = []struct {
+	h64    uint64
+	h3     uint64
+	h128hi uint64
+	h128lo uint64
+	seed   uint64
+	s      string
+}{
+	{0xef46db3751d8e999, 0x2d06800538d394c2, 0x99aa06d3014798d8, 0x6001c324468d497f, 0x0, ""},
+	{0xd24ec4f1a98c6e5b, 0xe6c632b61e964e1f, 0xa96faf705af16834, 0xe6c632b61e964e1f, 0x0, "a"},
+	{0x26c7827d889f6da3, 0x9555e8555c62dcfd, 0xb5e9c1ad071b3e7f, 0xc779cfaa5e523818, 0x0, "hello"},
+	{0xb33a384e6d1b1242, 0x302cd5fba73d006c, 0x11c83d9c1ee36816, 0x4c0abe17b55db69c, 0x0, "hello, world"},
+	{0xeccff1e9297033d6, 0x7a208492d4c40a25, 0xfd09ade517079ba6, 0x342dceaeb55f3dff, 0x0, "19 Jan 2038 at 3:14:07 AM"},
+	{0x44ad33705751ad73, 0xb614e0225d51db19, 0xd06a8295313e6a15, 0x3ff8e02db829c73c, 0x0, "The quick brown fox jumps over the lazy dog."},
+	{0x7a4188b7f4d30c05, 0xd71dc49f489eaee2, 0x6c7cd999824749e7, 0xfacd52056df0edf8, 0x0, strings.Repeat(fox, 2)},
+	{0xd1f7e05d0d9070a4, 0x23b6e16a7ca091cb, 0x27544e7646125ff5, 0x493f9fc470370aab, 0x0, strings.Repeat(fox, 4)},
+	{0x3324cb531c3f9f7a, 0x8972e17ef2aaa065, 0x5eed32578bb433d2, 0x8972e17ef2aaa065, 0x0, strings.Repeat(fox, 30)},
+	{0xbc236d94d48e0a0a, 0x815742ab809798ca, 0xc57f4768c7eb3209, 0x815742ab809798ca, 0x0, strings.Repeat(fox, 100)},
+	{0xb28666b87399df0d, 0x6e9b12b33b2ab94d, 0xf78c4fdd5e6775ff, 0xfcb1269091cc778e, 0x2545f4914f6cdd1d, ""},
+	{0x6c5dc3f986d31a6e, 0x34c23ac8dd919edf, 0x6b0df098a17fe2e0, 0x34c23ac8dd919edf, 0x2545f4914f6cdd1d, "a"},
+	{0x4f523bc58db2d53f, 0xb7f17e12f2cd9cdd, 0xee77230d3df337f4, 0xfe581d26a5e3b27b, 0x2545f4914f6cdd1d, "hello"},
+	{0xb52214f557c7209f, 0x775fe91ea6856150, 0xcb8fb807b4163755, 0xb3abd25d8ac80132, 0x2545f4914f6cdd1d, "hello, world"},
+	{0xfe6d4b8a64268227, 0xb998bb673395fc32, 0xda8c4e6c27346939, 0x4979f3bd8568de76, 0x2545f4914f6cdd1d, "19 Jan 2038 at 3:14:07 AM"},
+	{0x10f1381506b410a3, 0x49c5932d328ce8fb, 0xe66644a5538e67fe, 0x80c40cc0ccff0fd3, 0x2545f4914f6cdd1d, "The quick brown fox jumps over the lazy dog."},
+	{0x09544c933232b156, 0xb71fb253db2f6875, 0xa19ade94c22ba702, 0x4283714f55b16069, 0x2545f4914f6cdd1d, strings.Repeat(fox, 2)},
+	{0xf733cd19f47f5eb6, 0xafe1d2dc83d0064b, 0xe2032e781580b654, 0xe45ab1f43b008ff8, 0x2545f4914f6cdd1d, strings.Repeat(fox, 4)},
+	{0xb0547ee4fd1f3ce2, 0x04132fc4b5eae5ed, 0x4f3a9e302bb1c319, 0x04132fc4b5eae5ed, 0x2545f4914f6cdd1d, strings.Repeat(fox, 30)},
+	{0xe980d001847a70e0, 0x8d0ac0331df2ff02, 0x27eea7ebd5cc1ce4, 0x8d0ac0331df2ff02, 0x2545f4914f6cdd1d, strings.Repeat(fox, 100)},
+}
+
+func TestRef(t *testing.T) {
+	for _, elem := range data {
+
+		var h64 hash.Hash64 = New64(elem.seed)
+		h64.Write([]byte(elem.s))
+		if v := h64.Sum64(); v != elem.h64 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h64)
+		}
+
+		if v := Sum64([]byte(elem.s), elem.seed); v != elem.h64 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h64)
+		}
+
+		var h3 hash.Hash64 = New3(elem.seed)
+		h3.Write([]byte(elem.s))
+		if v := h3.Sum64(); v != elem.h3 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h3)
+		}
+
+		if v := Sum3([]byte(elem.s), elem.seed); v != elem.h3 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h3)
+		}
+
+		var h128 Hash128 = New128(elem.seed)
+		h128.Write([]byte(elem.s))
+		if v1, v2 := h128.Sum128(); v1 != elem.h128hi || v2 != elem.h128lo {
+			t.Errorf("'%.20s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h128hi, elem.h128lo)
+		}
+
+		if v1, v2 := Sum128([]byte(elem.s), elem.seed); v1 != elem.h128hi || v2 != elem.h128lo {
+			t.Errorf("'%.20s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h128hi, elem.h128lo)
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	for _, elem := range data {
+		h64 := New64(elem.seed)
+		h3 := New3(elem.seed)
+		h128 := New128(elem.seed)
+		for i, j, k := 0, 0, len(elem.s); i < k; i = j {
+			j = 2*i + 3
+			if j > k {
+				j = k
+			}
+			s := elem.s[i:j]
+			h64.Write([]byte(s))
+			h3.Write([]byte(s))
+			h128.Write([]byte(s))
+		}
+		if v := h64.Sum64(); v != elem.h64 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h64)
+		}
+		if v := h3.Sum64(); v != elem.h3 {
+			t.Errorf("'%.20s': 0x%x (want 0x%x)", elem.s, v, elem.h3)
+		}
+		if v1, v2 := h128.Sum128(); v1 != elem.h128hi || v2 != elem.h128lo {
+			t.Errorf("'%.20s': 0x%x-0x%x (want 0x%x-0x%x)", elem.s, v1, v2, elem.h128hi, elem.h128lo)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	elem := data[len(data)-1]
+	h3 := New3(elem.seed)
+	h3.Write([]byte("garbage"))
+	h3.Reset()
+	h3.Write([]byte(elem.s))
+	if v := h3.Sum64(); v != elem.h3 {
+		t.Errorf("0x%x (want 0x%x)", v, elem.h3)
+	}
+	if b := h3.Sum(nil); len(b) != h3.Size() {
+		t.Error(len(b))
+	}
+}
+
+//---
+
+func bench64(b *testing.B, length int) {
+	buf := make([]byte, length)
+	b.SetBytes(int64(length))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum64(buf, 0)
+	}
+}
+
+func Benchmark64_8(b *testing.B) {
+	bench64(b, 8)
+}
+func Benchmark64_16(b *testing.B) {
+	bench64(b, 16)
+}
+func Benchmark64_32(b *testing.B) {
+	bench64(b, 32)
+}
+func Benchmark64_128(b *testing.B) {
+	bench64(b, 128)
+}
+func Benchmark64_1024(b *testing.B) {
+	bench64(b, 1024)
+}
+func Benchmark64_8192(b *testing.B) {
+	bench64(b, 8192)
+}
+
+//---
+
+func bench3(b *testing.B, length int) {
+	buf := make([]byte, length)
+	b.SetBytes(int64(length))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum3(buf, 0)
+	}
+}
+
+func Benchmark3_8(b *testing.B) {
+	bench3(b, 8)
+}
+func Benchmark3_16(b *testing.B) {
+	bench3(b, 16)
+}
+func Benchmark3_32(b *testing.B) {
+	bench3(b, 32)
+}
+func Benchmark3_128(b *testing.B) {
+	bench3(b, 128)
+}
+func Benchmark3_1024(b *testing.B) {
+	bench3(b, 1024)
+}
+func Benchmark3_8192(b *testing.B) {
+	bench3(b, 8192)
+}
+
+//---
+
+func bench128(b *testing.B, length int) {
+	buf := make([]byte, length)
+	b.SetBytes(int64(length))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum128(buf, 0)
+	}
+}
+
+func Benchmark128_8(b *testing.B) {
+	bench128(b, 8)
+}
+func Benchmark128_16(b *testing.B) {
+	bench128(b, 16)
+}
+func Benchmark128_32(b *testing.B) {
+	bench128(b, 32)
+}
+func Benchmark128_128(b *testing.B) {
+	bench128(b, 128)
+}
+func Benchmark128_1024(b *testing.B) {
+	bench128(b, 1024)
+}
+func Benchmark128_8192(b *testing.B) {
+	bench128(b, 8192)
+}
@@ -0,0 +1,46 @@
+package hyperloglog
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrPrecision is returned when the precision is not between 4 and 16.
+	ErrPrecision = errors.New("hyperloglog: precision must be between 4 and 16")
+	// ErrTooFewSketches is returned when a set operation is given fewer
+	// than 2 HyperLogLogs.
+	ErrTooFewSketches = errors.New("hyperloglog: less than 2 HyperLogLogs were given")
+	// ErrPrecisionMismatch is returned when HyperLogLogs with different
+	// precisions are combined. It is wrapped by PrecisionMismatchError.
+	ErrPrecisionMismatch = errors.New("hyperloglog: precisions do not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// PrecisionMismatchError reports the precisions of two HyperLogLogs that
+// cannot be combined.
+type PrecisionMismatchError struct {
+	P, OtherP uint8
+}
+
+func (e *PrecisionMismatchError) Error() string {
+	return fmt.Sprintf("hyperloglog: precisions do not match: %d != %d", e.P,
+		e.OtherP)
+}
+
+func (e *PrecisionMismatchError) Unwrap() error { return ErrPrecisionMismatch }
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "HyperLogLog", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "HyperLogLog", Reason: reason}
+}
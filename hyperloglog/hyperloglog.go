@@ -7,29 +7,65 @@
 package hyperloglog
 
 import (
-	"errors"
+	"fmt"
 	"math"
+
+	"github.com/ekzhu/go-datasketch"
 )
 
 const two32 = 1 << 32
 
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Digester                          = new(HyperLogLog)
+	_ datasketch.Mergeable[*HyperLogLog]           = new(HyperLogLog)
+	_ datasketch.CardinalityEstimator              = new(HyperLogLog)
+	_ datasketch.SimilarityEstimator[*HyperLogLog] = new(HyperLogLog)
+	_ datasketch.Serializable                      = new(HyperLogLog)
+)
+
 // HyperLogLog data structure
 type HyperLogLog struct {
 	Reg []uint8
 	M   uint32
 	P   uint8
+
+	hasher datasketch.Hasher
+}
+
+// Option configures a HyperLogLog created by New or Deserialize.
+type Option func(*HyperLogLog)
+
+// WithHashFunc makes DigestBytes, DigestString and DigestUint64 hash items
+// with f instead of murmur3.Sum32. HyperLogLogs are only comparable when
+// their items were hashed with the same function.
+func WithHashFunc(f datasketch.HashFunc) Option {
+	return func(h *HyperLogLog) {
+		h.hasher.Func = f
+	}
+}
+
+// WithKey makes DigestBytes, DigestString and DigestUint64 hash items with
+// SipHash keyed with the secret k0 and k1, so an attacker who controls the
+// items cannot make them land in chosen registers. HyperLogLogs are only
+// comparable when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(datasketch.KeyedHashFunc(k0, k1))
 }
 
 // New returns a new initialized HyperLogLog.
-func New(precision uint8) (*HyperLogLog, error) {
+func New(precision uint8, opts ...Option) (*HyperLogLog, error) {
 	if precision > 16 || precision < 4 {
-		return nil, errors.New("precision must be between 4 and 16")
+		return nil, ErrPrecision
 	}
 
 	h := &HyperLogLog{}
 	h.P = precision
 	h.M = 1 << precision
 	h.Reg = make([]uint8, h.M)
+	for _, opt := range opts {
+		opt(h)
+	}
 	return h, nil
 }
 
@@ -40,7 +76,26 @@ func (h *HyperLogLog) Clear() {
 
 // Digest adds a new item to HyperLogLog h.
 func (h *HyperLogLog) Digest(item Hash32) {
-	x := item.Sum32()
+	h.digest(item.Sum32())
+}
+
+// DigestBytes hashes data and adds the hash to HyperLogLog h.
+func (h *HyperLogLog) DigestBytes(data []byte) {
+	h.digest(h.hasher.Sum32(data))
+}
+
+// DigestString hashes the bytes of s and adds the hash to HyperLogLog h.
+func (h *HyperLogLog) DigestString(s string) {
+	h.digest(h.hasher.Sum32String(s))
+}
+
+// DigestUint64 hashes the little-endian encoding of v and adds the hash to
+// HyperLogLog h.
+func (h *HyperLogLog) DigestUint64(v uint64) {
+	h.digest(h.hasher.Sum32Uint64(v))
+}
+
+func (h *HyperLogLog) digest(x uint32) {
 	i := eb32(x, 32, 32-h.P) // {x31,...,x32-p}
 	w := x<<h.P | 1<<(h.P-1) // {x32-p,...,x0}
 
@@ -54,7 +109,7 @@ func (h *HyperLogLog) Digest(item Hash32) {
 // making h the union of both.
 func (h *HyperLogLog) Merge(other *HyperLogLog) error {
 	if h.P != other.P {
-		return errors.New("precisions must be equal")
+		return &PrecisionMismatchError{h.P, other.P}
 	}
 
 	for i, v := range other.Reg {
@@ -79,8 +134,7 @@ func (h *HyperLogLog) ByteSize() int {
 // Serialize the HyperLogLog h into bytes and store in the buffer
 func (h *HyperLogLog) Serialize(buffer []byte) error {
 	if len(buffer) < h.ByteSize() {
-		return errors.New("buffer does not have enough space for holding" +
-			" this HyperLogLog.")
+		return shortBuffer(h.ByteSize(), len(buffer))
 	}
 	buffer[0] = h.P
 	offset := 1
@@ -92,19 +146,29 @@ func (h *HyperLogLog) Serialize(buffer []byte) error {
 }
 
 // Deserialize reconstruct a HyperLogLog from the buffer
-func Deserialize(buffer []byte) (*HyperLogLog, error) {
+func Deserialize(buffer []byte, opts ...Option) (*HyperLogLog, error) {
+	if len(buffer) < 1 {
+		return nil, shortBuffer(1, len(buffer))
+	}
 	p := buffer[0]
-	m := 1 << p
-	if len(buffer) < int(m)+1 {
-		return nil, errors.New("buffer doesn't contain enough space for " +
-			"reconstructing a HyperLogLog.")
+	if p > 16 || p < 4 {
+		return nil, corrupt(fmt.Sprintf("precision %d is not between 4 and 16",
+			p))
+	}
+	if need := 1 + 1<<p; len(buffer) < need {
+		return nil, shortBuffer(need, len(buffer))
 	}
-	h, err := New(p)
+	h, err := New(p, opts...)
 	if err != nil {
 		return nil, err
 	}
+	maxRank := 32 - p + 1
 	offset := 1
 	for i := range h.Reg {
+		if buffer[offset] > maxRank {
+			return nil, corrupt(fmt.Sprintf("register %d holds %d, more "+
+				"than %d", i, buffer[offset], maxRank))
+		}
 		h.Reg[i] = buffer[offset]
 		offset++
 	}
@@ -116,13 +180,12 @@ func Deserialize(buffer []byte) (*HyperLogLog, error) {
 // with others.
 func UnionCount(hlls ...*HyperLogLog) (float64, error) {
 	if hlls == nil || len(hlls) < 2 {
-		return 0.0, errors.New("Less than 2 HyperLogLogs were given.")
+		return 0.0, ErrTooFewSketches
 	}
 	p := hlls[0].P
 	for _, h := range hlls[1:] {
 		if h.P != p {
-			return 0.0, errors.New("Cannot union HyperLogLogs with different" +
-				"precision parameters.")
+			return 0.0, &PrecisionMismatchError{p, h.P}
 		}
 	}
 	inverCount := func(val uint8) float64 {
@@ -167,6 +230,12 @@ func IntersectionCount(h1, h2 *HyperLogLog) (float64, error) {
 	return (h1.Count() + h2.Count() - u), nil
 }
 
+// Jaccard returns the estimated Jaccard similarity between h and other.
+// The value may be negative due to cardinality estimation error
+func (h *HyperLogLog) Jaccard(other *HyperLogLog) (float64, error) {
+	return Jaccard(h, other)
+}
+
 // Jaccard returns the estimated Jaccard similarity between the two HyperLogLogs.
 // The value may be negative due to cardinality estimation error
 func Jaccard(h1, h2 *HyperLogLog) (float64, error) {
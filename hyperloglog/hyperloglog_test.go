@@ -1,6 +1,9 @@
 package hyperloglog
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 type fakeHash32 uint32
 
@@ -75,6 +78,19 @@ func TestHLLMergeError(t *testing.T) {
 	if err == nil {
 		t.Error("different precision should return error")
 	}
+	var pErr *PrecisionMismatchError
+	if !errors.As(err, &pErr) || pErr.P != 16 || pErr.OtherP != 10 {
+		t.Error(err)
+	}
+	if _, err := UnionCount(h, h2); !errors.Is(err, ErrPrecisionMismatch) {
+		t.Error(err)
+	}
+	if _, err := UnionCount(h); !errors.Is(err, ErrTooFewSketches) {
+		t.Error(err)
+	}
+	if _, err := New(2); !errors.Is(err, ErrPrecision) {
+		t.Error(err)
+	}
 }
 
 func TestHLLMerge(t *testing.T) {
@@ -271,3 +287,89 @@ func TestHLLInclusion(t *testing.T) {
 		t.Error(i)
 	}
 }
+
+func TestHLLDeserializeError(t *testing.T) {
+	if _, err := Deserialize(nil); !errors.Is(err, ErrShortBuffer) {
+		t.Error("should return error for an empty buffer")
+	}
+	if _, err := Deserialize([]byte{64}); !errors.Is(err, ErrCorrupt) {
+		t.Error("should return error for an invalid precision")
+	}
+	h, _ := New(4)
+	buf := make([]byte, h.ByteSize())
+	h.Serialize(buf)
+	if _, err := Deserialize(buf[:len(buf)-1]); err == nil {
+		t.Error("should return error if the registers are truncated")
+	}
+	buf[1] = 30
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error("should return error if a register is out of range")
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	h, _ := New(4)
+	h.Digest(fakeHash32(0x00010fff))
+	buf := make([]byte, h.ByteSize())
+	h.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add([]byte{255})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, h.ByteSize())
+		if err := h.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		h.Count()
+	})
+}
+
+func TestHLLDigestTyped(t *testing.T) {
+	h, _ := New(16)
+	h.DigestString("hello")
+	h.DigestBytes([]byte("hello"))
+	h.DigestUint64(42)
+	if n := h.Count(); int(n) != 2 {
+		t.Error(n)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		h.DigestString("hello")
+		h.DigestUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+
+	h2, _ := New(16, WithHashFunc(func(data []byte) uint32 {
+		return 0x00010fff
+	}))
+	h2.DigestString("hello")
+	if n := h2.Reg[1]; n != 5 {
+		t.Error(n)
+	}
+}
+
+func TestHLLWithKey(t *testing.T) {
+	h1, _ := New(8, WithKey(1, 2))
+	h2, _ := New(8, WithKey(1, 2))
+	h3, _ := New(8, WithKey(3, 4))
+	for i := uint64(0); i < 16; i++ {
+		h1.DigestUint64(i)
+		h2.DigestUint64(i)
+		h3.DigestUint64(i)
+	}
+	same, differ := true, false
+	for i := range h1.Reg {
+		same = same && h1.Reg[i] == h2.Reg[i]
+		differ = differ || h1.Reg[i] != h3.Reg[i]
+	}
+	if !same || !differ {
+		t.Error("registers should depend on the key only")
+	}
+}
@@ -0,0 +1,37 @@
+package hyperloglog
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(HyperLogLog)
+	_ driver.Valuer = new(HyperLogLog)
+)
+
+// Value implements driver.Valuer so a HyperLogLog can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized HyperLogLog
+// preceded by a tag identifying it as a HyperLogLog.
+func (h *HyperLogLog) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.HyperLogLog, h)
+}
+
+// Scan implements sql.Scanner, restoring a HyperLogLog written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on h, if any, is kept.
+func (h *HyperLogLog) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.HyperLogLog, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(h.hasher.Func))
+	if err != nil {
+		return err
+	}
+	*h = *other
+	return nil
+}
@@ -0,0 +1,57 @@
+package hyperloglog
+
+import (
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/minhash"
+)
+
+func TestHLLValueScan(t *testing.T) {
+	h, _ := New(8)
+	h.Digest(fakeHash32(0x00010fff))
+	h.Digest(fakeHash32(0x00020fff))
+	v, err := h.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d HyperLogLog
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.P != h.P || d.Count() != h.Count() {
+		t.Error("Did not get back the same HyperLogLog")
+	}
+}
+
+func TestHLLScanError(t *testing.T) {
+	var h HyperLogLog
+	if err := h.Scan(nil); err == nil {
+		t.Error("should return error when scanning NULL")
+	}
+	if err := h.Scan([]byte{}); err == nil {
+		t.Error("should return error when scanning an empty blob")
+	}
+	if err := h.Scan(42); err == nil {
+		t.Error("should return error when scanning a non-binary value")
+	}
+	m, _ := minhash.New(4, 1)
+	v, _ := m.Value()
+	if err := h.Scan(v); err == nil {
+		t.Error("should return error when scanning a MinHash")
+	}
+	b, _ := New(8)
+	v, _ = b.Value()
+	if err := h.Scan(v.([]byte)[:1]); err == nil {
+		t.Error("should return error when scanning a truncated blob")
+	}
+}
+
+func FuzzScan(f *testing.F) {
+	h, _ := New(4)
+	v, _ := h.Value()
+	f.Add(v.([]byte))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var h HyperLogLog
+		h.Scan(data)
+	})
+}
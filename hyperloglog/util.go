@@ -2,12 +2,12 @@ package hyperloglog
 
 import (
 	"math"
+
+	"github.com/ekzhu/go-datasketch"
 )
 
 // Hash32 is a relaxed version of hash.Hash32
-type Hash32 interface {
-	Sum32() uint32
-}
+type Hash32 = datasketch.Hash32
 
 func alpha(m uint32) float64 {
 	if m == 16 {
@@ -0,0 +1,46 @@
+package hyperminhash
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrPrecision is returned when the precision is not between 4 and 16.
+	ErrPrecision = errors.New("hyperminhash: precision must be between 4 and 16")
+	// ErrTooFewSketches is returned when a set operation is given fewer
+	// than 2 HyperMinHashes.
+	ErrTooFewSketches = errors.New("hyperminhash: less than 2 HyperMinHashes were given")
+	// ErrPrecisionMismatch is returned when HyperMinHashes with different
+	// precisions are combined. It is wrapped by PrecisionMismatchError.
+	ErrPrecisionMismatch = errors.New("hyperminhash: precisions do not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// PrecisionMismatchError reports the precisions of two HyperMinHashes that
+// cannot be combined.
+type PrecisionMismatchError struct {
+	P, OtherP uint8
+}
+
+func (e *PrecisionMismatchError) Error() string {
+	return fmt.Sprintf("hyperminhash: precisions do not match: %d != %d", e.P,
+		e.OtherP)
+}
+
+func (e *PrecisionMismatchError) Unwrap() error { return ErrPrecisionMismatch }
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "HyperMinHash", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "HyperMinHash", Reason: reason}
+}
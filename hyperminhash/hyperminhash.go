@@ -0,0 +1,363 @@
+// Package hyperminhash implements HyperMinHash, a HyperLogLog whose
+// registers also keep a few bits of the minimum hash of their bucket. One
+// sketch estimates the cardinality of a set, of unions, and, like MinHash,
+// the Jaccard similarity and intersection of sets, with the accuracy of
+// MinHash rather than that of inclusion–exclusion over HyperLogLogs.
+//
+// HyperMinHash: MinHash in LogLog space:
+// https://arxiv.org/abs/1710.08436
+package hyperminhash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Hash32 is a relaxed version of hash.Hash32
+type Hash32 = datasketch.Hash32
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Digester                           = new(HyperMinHash)
+	_ datasketch.Mergeable[*HyperMinHash]           = new(HyperMinHash)
+	_ datasketch.CardinalityEstimator               = new(HyperMinHash)
+	_ datasketch.SimilarityEstimator[*HyperMinHash] = new(HyperMinHash)
+	_ datasketch.Serializable                       = new(HyperMinHash)
+)
+
+const (
+	// r is the number of bits of the minimum hash kept besides its number
+	// of leading zeros. The leading zeros take the 6 bits above them.
+	r        = 10
+	mantissa = 1<<r - 1
+)
+
+// HashFunc hashes data to the 64-bit hashes a HyperMinHash digests.
+type HashFunc func(data []byte) uint64
+
+// HyperMinHash data structure. Each register holds, for the smallest hash
+// of its bucket, the number of leading zeros plus one in its top 6 bits and
+// the complement of the r bits after the leading one below them, so that
+// the register of the smaller hash is the larger number.
+type HyperMinHash struct {
+	Reg []uint16
+	M   uint32
+	P   uint8
+
+	hash HashFunc
+}
+
+// Option configures a HyperMinHash created by New or Deserialize.
+type Option func(*HyperMinHash)
+
+// WithHashFunc makes DigestBytes, DigestString and DigestUint64 hash items
+// with f instead of murmur3.Sum64WithSeed. HyperMinHashes are only
+// comparable when their items were hashed with the same function.
+func WithHashFunc(f HashFunc) Option {
+	return func(h *HyperMinHash) {
+		h.hash = f
+	}
+}
+
+// WithKey makes DigestBytes, DigestString and DigestUint64 hash items with
+// SipHash keyed with the secret k0 and k1, so an attacker who controls the
+// items cannot make them land in chosen registers. HyperMinHashes are only
+// comparable when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed64(k0, k1))
+}
+
+// New returns a new initialized HyperMinHash with 2^precision registers.
+func New(precision uint8, opts ...Option) (*HyperMinHash, error) {
+	if precision > 16 || precision < 4 {
+		return nil, ErrPrecision
+	}
+	h := &HyperMinHash{}
+	h.P = precision
+	h.M = 1 << precision
+	h.Reg = make([]uint16, h.M)
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// Clear sets HyperMinHash h back to its initial state.
+func (h *HyperMinHash) Clear() {
+	for i := range h.Reg {
+		h.Reg[i] = 0
+	}
+}
+
+// Digest adds an item given by its 32-bit hash. The hash is spread over 64
+// bits, but items whose 32-bit hashes collide are still counted once, so
+// prefer DigestBytes or Digest64 for sets of more than a few million items.
+func (h *HyperMinHash) Digest(item Hash32) {
+	h.Digest64(mix.Mix64(uint64(item.Sum32())))
+}
+
+// DigestBytes hashes data and adds the hash to HyperMinHash h.
+func (h *HyperMinHash) DigestBytes(data []byte) {
+	if h.hash != nil {
+		h.Digest64(h.hash(data))
+		return
+	}
+	h.Digest64(murmur3.Sum64WithSeed(data, 0))
+}
+
+// DigestString hashes the bytes of s and adds the hash to HyperMinHash h.
+func (h *HyperMinHash) DigestString(s string) {
+	if h.hash != nil {
+		h.Digest64(h.hash(unsafe.Slice(unsafe.StringData(s), len(s))))
+		return
+	}
+	h.Digest64(murmur3.Sum64String(s, 0))
+}
+
+// DigestUint64 hashes the little-endian encoding of v and adds the hash to
+// HyperMinHash h.
+func (h *HyperMinHash) DigestUint64(v uint64) {
+	if h.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		h.Digest64(h.hash(b[:]))
+		return
+	}
+	h.Digest64(murmur3.Sum64Uint64(v, 0))
+}
+
+// Digest64 adds an item given by its 64-bit hash to HyperMinHash h.
+func (h *HyperMinHash) Digest64(x uint64) {
+	i := x >> (64 - h.P)
+	w := x << h.P
+	lz := bits.LeadingZeros64(w)
+	if lz > 64-int(h.P) {
+		lz = 64 - int(h.P)
+	}
+	// lz is at most 64-P, at most 60, so the shift stays below 64 and
+	// keeps the r bits following the leading one, all 0 when w is 0.
+	m := (w << (lz + 1)) >> (64 - r)
+	reg := uint16(lz+1)<<r | uint16(^m&mantissa)
+	if reg > h.Reg[i] {
+		h.Reg[i] = reg
+	}
+}
+
+// Merge takes another HyperMinHash and combines it with HyperMinHash h,
+// making h the union of both.
+func (h *HyperMinHash) Merge(other *HyperMinHash) error {
+	if h.P != other.P {
+		return &PrecisionMismatchError{h.P, other.P}
+	}
+	for i, v := range other.Reg {
+		if v > h.Reg[i] {
+			h.Reg[i] = v
+		}
+	}
+	return nil
+}
+
+// Count returns the cardinality estimate, computed by HyperLogLog from the
+// numbers of leading zeros.
+func (h *HyperMinHash) Count() float64 {
+	return count(h.Reg)
+}
+
+func count(reg []uint16) float64 {
+	sum := 0.0
+	var numZero int
+	for _, v := range reg {
+		sum += math.Ldexp(1, -int(v>>r))
+		if v == 0 {
+			numZero++
+		}
+	}
+	m := float64(len(reg))
+	est := alpha(m) * m * m / sum
+	if est <= 2.5*m && numZero != 0 {
+		// Linear counting.
+		return m * math.Log(m/float64(numZero))
+	}
+	return est
+}
+
+func alpha(m float64) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/m)
+}
+
+// UnionCount returns the cardinality of the union of all the
+// HyperMinHashes.
+func UnionCount(hmhs ...*HyperMinHash) (float64, error) {
+	if len(hmhs) < 2 {
+		return 0.0, ErrTooFewSketches
+	}
+	u, err := union(hmhs)
+	if err != nil {
+		return 0.0, err
+	}
+	return count(u), nil
+}
+
+func union(hmhs []*HyperMinHash) ([]uint16, error) {
+	u := make([]uint16, len(hmhs[0].Reg))
+	for _, h := range hmhs {
+		if h.P != hmhs[0].P {
+			return nil, &PrecisionMismatchError{hmhs[0].P, h.P}
+		}
+		for i, v := range h.Reg {
+			if v > u[i] {
+				u[i] = v
+			}
+		}
+	}
+	return u, nil
+}
+
+// Jaccard returns the estimated Jaccard similarity between h and other.
+func (h *HyperMinHash) Jaccard(other *HyperMinHash) (float64, error) {
+	return Jaccard(h, other)
+}
+
+// Jaccard returns the estimated Jaccard similarity between the two
+// HyperMinHashes: the fraction of non-empty registers on which they agree,
+// less the agreements expected by chance between registers of different
+// hashes.
+func Jaccard(h1, h2 *HyperMinHash) (float64, error) {
+	if h1.P != h2.P {
+		return 0.0, &PrecisionMismatchError{h1.P, h2.P}
+	}
+	var agree, nonEmpty int
+	for i, v := range h1.Reg {
+		if v != 0 || h2.Reg[i] != 0 {
+			nonEmpty++
+			if v == h2.Reg[i] {
+				agree++
+			}
+		}
+	}
+	if nonEmpty == 0 {
+		return 1.0, nil
+	}
+	c := float64(agree) - expectedCollisions(h1.Count(), h2.Count(), h1.P)
+	if c <= 0 {
+		return 0.0, nil
+	}
+	return c / float64(nonEmpty), nil
+}
+
+// IntersectionCount returns the cardinality estimation of the intersection
+// of the two HyperMinHashes: their Jaccard similarity times the cardinality
+// of their union.
+func IntersectionCount(h1, h2 *HyperMinHash) (float64, error) {
+	j, err := Jaccard(h1, h2)
+	if err != nil {
+		return 0.0, err
+	}
+	u, err := UnionCount(h1, h2)
+	if err != nil {
+		return 0.0, err
+	}
+	return j * u, nil
+}
+
+// expectedCollisions returns the number of registers expected to agree
+// between the HyperMinHashes of two disjoint sets of n and m items. A
+// register value stands for an interval of the hashes of its bucket; the
+// registers agree when the smallest hash of both sets falls in the same
+// interval.
+func expectedCollisions(n, m float64, p uint8) float64 {
+	buckets := math.Ldexp(1, int(p))
+	var sum float64
+	for lz := 1; lz <= 64-int(p); lz++ {
+		// The hashes of the bucket with lz-1 leading zeros are between
+		// start and 2*start, as fractions of all the hashes.
+		start := math.Ldexp(1, -lz) / buckets
+		width := start / (1 << r)
+		var region float64
+		for j := 0; j <= mantissa; j++ {
+			lo := start + float64(j)*width
+			region += inInterval(n, lo, width) * inInterval(m, lo, width)
+		}
+		sum += region
+		if 2*start*math.Max(n, m) < 1 && region < 1e-12*sum {
+			break
+		}
+	}
+	return buckets * sum
+}
+
+// inInterval returns the probability that the smallest hash of n items is
+// between lo and lo+width: that none is below lo, and not all of them above
+// lo+width.
+func inInterval(n, lo, width float64) float64 {
+	return math.Exp(n*math.Log1p(-lo)) * -math.Expm1(n*math.Log1p(-width/(1-lo)))
+}
+
+// ByteSize returns the size of the HyperMinHash h in bytes
+func (h *HyperMinHash) ByteSize() int {
+	return 1 + 2*int(h.M)
+}
+
+// Serialize the HyperMinHash h into bytes and store in the buffer
+func (h *HyperMinHash) Serialize(buffer []byte) error {
+	if len(buffer) < h.ByteSize() {
+		return shortBuffer(h.ByteSize(), len(buffer))
+	}
+	buffer[0] = h.P
+	offset := 1
+	for _, v := range h.Reg {
+		binary.LittleEndian.PutUint16(buffer[offset:], v)
+		offset += 2
+	}
+	return nil
+}
+
+// Deserialize reconstruct a HyperMinHash from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*HyperMinHash, error) {
+	if len(buffer) < 1 {
+		return nil, shortBuffer(1, len(buffer))
+	}
+	p := buffer[0]
+	if p > 16 || p < 4 {
+		return nil, corrupt(fmt.Sprintf("precision %d is not between 4 and 16",
+			p))
+	}
+	if need := 1 + 2<<p; len(buffer) < need {
+		return nil, shortBuffer(need, len(buffer))
+	}
+	h, err := New(p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	maxRank := uint16(64 - p + 1)
+	offset := 1
+	for i := range h.Reg {
+		v := binary.LittleEndian.Uint16(buffer[offset:])
+		if v>>r > maxRank {
+			return nil, corrupt(fmt.Sprintf("register %d holds rank %d, "+
+				"more than %d", i, v>>r, maxRank))
+		}
+		if v>>r == 0 && v != 0 {
+			return nil, corrupt(fmt.Sprintf("register %d holds bits "+
+				"without a rank", i))
+		}
+		h.Reg[i] = v
+		offset += 2
+	}
+	return h, nil
+}
@@ -0,0 +1,235 @@
+package hyperminhash
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+type fakeHash32 uint32
+
+func (f fakeHash32) Sum32() uint32 { return uint32(f) }
+
+func digestRange(h *HyperMinHash, from, to uint64) {
+	for i := from; i < to; i++ {
+		h.DigestUint64(i)
+	}
+}
+
+func TestHMHDigest(t *testing.T) {
+	h, _ := New(4)
+	// Bucket 1, 3 leading zeros, then the bits 1 and 0101010101.
+	h.Digest64(0x1<<60 | 0x1<<56 | 0x155<<46)
+	if want := uint16(4<<r | 0x2aa); h.Reg[1] != want {
+		t.Errorf("register 0x%x (want 0x%x)", h.Reg[1], want)
+	}
+	// A smaller hash of the same bucket replaces it, a larger one does
+	// not.
+	h.Digest64(0x1<<60 | 0x1<<55)
+	h.Digest64(0x1<<60 | 0x1<<57)
+	if want := uint16(5<<r | 0x3ff); h.Reg[1] != want {
+		t.Errorf("register 0x%x (want 0x%x)", h.Reg[1], want)
+	}
+	// The smallest hash of a bucket.
+	h.Digest64(0x2 << 60)
+	if want := uint16(61<<r | 0x3ff); h.Reg[2] != want {
+		t.Errorf("register 0x%x (want 0x%x)", h.Reg[2], want)
+	}
+	h.Clear()
+	if h.Count() != 0 {
+		t.Error(h.Count())
+	}
+}
+
+func TestHMHCount(t *testing.T) {
+	h, _ := New(14)
+	for _, n := range []uint64{100, 10000, 1000000} {
+		h.Clear()
+		digestRange(h, 0, n)
+		// 4 standard errors of 1.04/sqrt(m).
+		if err := math.Abs(h.Count()-float64(n)) / float64(n); err > 4*1.04/128 {
+			t.Errorf("count %v (want %v)", h.Count(), n)
+		}
+	}
+}
+
+func TestHMHJaccard(t *testing.T) {
+	// Small overlaps between large sets, where inclusion-exclusion over
+	// HyperLogLogs fails.
+	const n, trials = 100000, 4
+	for _, common := range []uint64{0, 100, 1000, 10000, n} {
+		want := float64(common) / float64(2*n-common)
+		var err, hllErr float64
+		for trial := uint64(0); trial < trials; trial++ {
+			a, _ := New(14, WithKey(trial, 1))
+			b, _ := New(14, WithKey(trial, 1))
+			ha, _ := hyperloglog.New(14, hyperloglog.WithKey(trial, 1))
+			hb, _ := hyperloglog.New(14, hyperloglog.WithKey(trial, 1))
+			for i := uint64(0); i < n; i++ {
+				a.DigestUint64(i)
+				b.DigestUint64(i + n - common)
+				ha.DigestUint64(i)
+				hb.DigestUint64(i + n - common)
+			}
+			j, e := a.Jaccard(b)
+			if e != nil {
+				t.Fatal(e)
+			}
+			hj, _ := ha.Jaccard(hb)
+			err += math.Abs(j-want) / trials
+			hllErr += math.Abs(hj-want) / trials
+		}
+		sigma := math.Sqrt(want * (1 - want) / (1 << 14))
+		if err > 4*sigma+0.001 {
+			t.Errorf("J=%.4f: mean error %.5f (want less than %.5f)", want, err,
+				4*sigma+0.001)
+		}
+		if common < n/10 && err > hllErr/2 {
+			t.Errorf("J=%.4f: mean error %.5f, HyperLogLog %.5f", want, err,
+				hllErr)
+		}
+	}
+}
+
+func TestHMHSetOperations(t *testing.T) {
+	a, _ := New(12)
+	b, _ := New(12)
+	digestRange(a, 0, 50000)
+	digestRange(b, 40000, 100000)
+	ic, _ := IntersectionCount(a, b)
+	if math.Abs(ic-10000)/10000 > 0.1 {
+		t.Errorf("intersection count %v (want 10000)", ic)
+	}
+	uc, _ := UnionCount(a, b)
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Count() != uc {
+		t.Errorf("count of the merge %v, union count %v", a.Count(), uc)
+	}
+	if math.Abs(uc-100000)/100000 > 4*1.04/64 {
+		t.Errorf("union count %v (want 100000)", uc)
+	}
+	if j, _ := a.Jaccard(a); j < 0.999 {
+		t.Error(j)
+	}
+	e, _ := New(12)
+	if j, _ := e.Jaccard(e); j != 1.0 {
+		t.Error(j)
+	}
+	// Expected collisions between disjoint sets grow with their size.
+	if x, y := expectedCollisions(1e3, 1e3, 12), expectedCollisions(1e6, 1e6, 12); x < 0 || y <= x {
+		t.Error(x, y)
+	}
+}
+
+func TestHMHError(t *testing.T) {
+	if _, err := New(3); !errors.Is(err, ErrPrecision) {
+		t.Error(err)
+	}
+	a, _ := New(4)
+	b, _ := New(5)
+	var precErr *PrecisionMismatchError
+	if err := a.Merge(b); !errors.As(err, &precErr) || precErr.P != 4 ||
+		precErr.OtherP != 5 {
+		t.Error(err)
+	}
+	if _, err := Jaccard(a, b); !errors.Is(err, ErrPrecisionMismatch) {
+		t.Error(err)
+	}
+	if _, err := UnionCount(a); !errors.Is(err, ErrTooFewSketches) {
+		t.Error(err)
+	}
+	if _, err := IntersectionCount(a, b); !errors.Is(err, ErrPrecisionMismatch) {
+		t.Error(err)
+	}
+}
+
+func TestHMHDigestTyped(t *testing.T) {
+	a, _ := New(8)
+	b, _ := New(8)
+	a.DigestString("hello")
+	a.DigestUint64(42)
+	a.Digest(fakeHash32(7))
+	b.DigestBytes([]byte("hello"))
+	b.DigestUint64(42)
+	b.Digest(fakeHash32(7))
+	for i := range a.Reg {
+		if a.Reg[i] != b.Reg[i] {
+			t.Fatal("typed digests differ from DigestBytes")
+		}
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		a.DigestString("hello")
+		a.DigestUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+	c, _ := New(8, WithKey(1, 2))
+	d, _ := New(8, WithKey(1, 2))
+	c.DigestString("hello")
+	d.DigestBytes([]byte("hello"))
+	if j, _ := c.Jaccard(d); j < 0.99 {
+		t.Error(j)
+	}
+}
+
+func TestHMHSerialization(t *testing.T) {
+	h, _ := New(4)
+	digestRange(h, 0, 100)
+	buf := make([]byte, h.ByteSize())
+	if err := h.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.P != h.P || d.Count() != h.Count() {
+		t.Error("Did not get back the same HyperMinHash")
+	}
+	if err := h.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[2] = 0xff
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[1], buf[2] = 1, 0
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[0] = 17
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	h, _ := New(4)
+	digestRange(h, 0, 10)
+	buf := make([]byte, h.ByteSize())
+	h.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:1])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		h.Count()
+		out := make([]byte, h.ByteSize())
+		if err := h.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package hyperminhash
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(HyperMinHash)
+	_ driver.Valuer = new(HyperMinHash)
+)
+
+// Value implements driver.Valuer so a HyperMinHash can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized HyperMinHash
+// preceded by a tag identifying it as a HyperMinHash.
+func (h *HyperMinHash) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.HyperMinHash, h)
+}
+
+// Scan implements sql.Scanner, restoring a HyperMinHash written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on h, if any, is kept.
+func (h *HyperMinHash) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.HyperMinHash, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(h.hash))
+	if err != nil {
+		return err
+	}
+	*h = *other
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package envelope implements the tagged container used when sketches are
+// stored outside of the process, e.g. in a BYTEA/BLOB database column.
+// An envelope is a single tag byte identifying the sketch type followed by
+// the sketch's own binary serialization.
+package envelope
+
+import (
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Tag identifies the type of sketch held by an envelope.
+type Tag uint8
+
+// Tags of the sketches in this library. Values are persisted and must
+// never be reused or renumbered.
+const (
+	MinHash Tag = iota + 1
+	OneBitMinHash
+	HyperLogLog
+	OnePermMinHash
+	KMV
+	Theta
+	HyperMinHash
+	CountMin
+	CountSketch
+	SpaceSaving
+	MisraGries
+	KLL
+	TDigest
+	DDSketch
+	BloomFilter
+	CountingBloomFilter
+	ScalableBloomFilter
+	CuckooFilter
+	XorFilter
+	BinaryFuseFilter
+	QuotientFilter
+)
+
+var tagNames = map[Tag]string{
+	MinHash:             "MinHash",
+	OneBitMinHash:       "OneBitMinHash",
+	HyperLogLog:         "HyperLogLog",
+	OnePermMinHash:      "OnePermMinHash",
+	KMV:                 "KMV",
+	Theta:               "Theta",
+	HyperMinHash:        "HyperMinHash",
+	CountMin:            "CountMin",
+	CountSketch:         "CountSketch",
+	SpaceSaving:         "SpaceSaving",
+	MisraGries:          "MisraGries",
+	KLL:                 "KLL",
+	TDigest:             "TDigest",
+	DDSketch:            "DDSketch",
+	BloomFilter:         "BloomFilter",
+	CountingBloomFilter: "CountingBloomFilter",
+	ScalableBloomFilter: "ScalableBloomFilter",
+	CuckooFilter:        "CuckooFilter",
+	XorFilter:           "XorFilter",
+	BinaryFuseFilter:    "BinaryFuseFilter",
+	QuotientFilter:      "QuotientFilter",
+}
+
+func (t Tag) String() string {
+	if name, ok := tagNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("sketch with unknown tag %d", uint8(t))
+}
+
+// Wrap serializes s into a newly allocated envelope tagged with tag.
+func Wrap(tag Tag, s datasketch.Serializable) ([]byte, error) {
+	buffer := make([]byte, 1+s.ByteSize())
+	buffer[0] = byte(tag)
+	if err := s.Serialize(buffer[1:]); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// Unwrap checks that src, as handed to sql.Scanner.Scan, is an envelope
+// tagged with tag and returns the serialized sketch it contains.
+func Unwrap(tag Tag, src interface{}) ([]byte, error) {
+	var buffer []byte
+	switch v := src.(type) {
+	case []byte:
+		buffer = v
+	case string:
+		buffer = []byte(v)
+	case nil:
+		return nil, &datasketch.WrongSketchError{Want: tag.String(),
+			Got: "NULL"}
+	default:
+		return nil, &datasketch.WrongSketchError{Want: tag.String(),
+			Got: fmt.Sprintf("a value of type %T", src)}
+	}
+	if len(buffer) == 0 {
+		return nil, &datasketch.WrongSketchError{Want: tag.String(),
+			Got: "an empty blob"}
+	}
+	if got := Tag(buffer[0]); got != tag {
+		return nil, &datasketch.WrongSketchError{Want: tag.String(),
+			Got: "a " + got.String()}
+	}
+	return buffer[1:], nil
+}
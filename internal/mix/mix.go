@@ -0,0 +1,33 @@
+// Package mix implements the hashing helpers shared by the sketches: a
+// 64-bit mixing function, and the keyed hash functions behind their
+// WithKey options.
+package mix
+
+import "github.com/ekzhu/go-datasketch/hashfunction/siphash"
+
+// Mix64 is the finalizer of SplitMix64, a bijection of 64-bit integers
+// whose output bits all depend on all input bits.
+func Mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Keyed64 returns a function computing the 64-bit SipHash-1-3 of data
+// keyed with k0 and k1.
+func Keyed64(k0, k1 uint64) func(data []byte) uint64 {
+	return func(data []byte) uint64 {
+		return siphash.Sum64R13(k0, k1, data)
+	}
+}
+
+// Keyed128 returns a function computing the 128-bit SipHash-1-3 of data
+// keyed with k0 and k1.
+func Keyed128(k0, k1 uint64) func(data []byte) (uint64, uint64) {
+	return func(data []byte) (uint64, uint64) {
+		return siphash.Sum128R13(k0, k1, data)
+	}
+}
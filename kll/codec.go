@@ -0,0 +1,175 @@
+package kll
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// kindOf returns the kind of T, which determines how its values are
+// serialized.
+func kindOf[T Ordered]() reflect.Kind {
+	var zero T
+	return reflect.TypeOf(zero).Kind()
+}
+
+// minItemSize returns the fewest bytes a value of kind k is serialized in.
+func minItemSize(k reflect.Kind) int {
+	switch k {
+	case reflect.Int8, reflect.Uint8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32, reflect.String:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// codec serializes the values of type T. Its conversions are chosen once
+// from the kind of T, so that items are encoded without reflection.
+type codec[T Ordered] struct {
+	kind reflect.Kind
+	// size is the number of bytes of a number, or of the length preceding
+	// a string.
+	size int
+	// toBits and fromBits convert numbers to and from their encoding;
+	// fromBits returns false if the value does not fit in T.
+	toBits   func(x T) uint64
+	fromBits func(bits uint64) (T, bool)
+}
+
+// as reinterprets x as a value of type U, the underlying type of T.
+func as[U, T any](x T) U {
+	return *(*U)(unsafe.Pointer(&x))
+}
+
+func newCodec[T Ordered]() *codec[T] {
+	c := &codec[T]{kind: kindOf[T]()}
+	c.size = minItemSize(c.kind)
+	switch c.kind {
+	case reflect.Int:
+		signed[T, int](c)
+	case reflect.Int8:
+		signed[T, int8](c)
+	case reflect.Int16:
+		signed[T, int16](c)
+	case reflect.Int32:
+		signed[T, int32](c)
+	case reflect.Int64:
+		signed[T, int64](c)
+	case reflect.Uint:
+		unsigned[T, uint](c)
+	case reflect.Uint8:
+		unsigned[T, uint8](c)
+	case reflect.Uint16:
+		unsigned[T, uint16](c)
+	case reflect.Uint32:
+		unsigned[T, uint32](c)
+	case reflect.Uint64:
+		unsigned[T, uint64](c)
+	case reflect.Uintptr:
+		unsigned[T, uintptr](c)
+	case reflect.Float32:
+		c.toBits = func(x T) uint64 {
+			return uint64(math.Float32bits(as[float32](x)))
+		}
+		c.fromBits = func(bits uint64) (T, bool) {
+			return as[T](math.Float32frombits(uint32(bits))), true
+		}
+	case reflect.Float64:
+		c.toBits = func(x T) uint64 {
+			return math.Float64bits(as[float64](x))
+		}
+		c.fromBits = func(bits uint64) (T, bool) {
+			return as[T](math.Float64frombits(bits)), true
+		}
+	}
+	return c
+}
+
+func signed[T Ordered, I int | int8 | int16 | int32 | int64](c *codec[T]) {
+	c.toBits = func(x T) uint64 {
+		return uint64(as[I](x))
+	}
+	shift := 64 - 8*c.size
+	c.fromBits = func(bits uint64) (T, bool) {
+		// Sign-extend the narrower kinds.
+		i := int64(bits<<shift) >> shift
+		return as[T](I(i)), int64(I(i)) == i
+	}
+}
+
+func unsigned[T Ordered, U uint | uint8 | uint16 | uint32 | uint64 |
+	uintptr](c *codec[T]) {
+	c.toBits = func(x T) uint64 {
+		return uint64(as[U](x))
+	}
+	c.fromBits = func(bits uint64) (T, bool) {
+		return as[T](U(bits)), uint64(U(bits)) == bits
+	}
+}
+
+// itemSize returns the number of bytes putItem writes for x.
+func (c *codec[T]) itemSize(x T) int {
+	if c.kind == reflect.String {
+		return 4 + len(as[string](x))
+	}
+	return c.size
+}
+
+// putItem writes x in little-endian order, strings preceded by their
+// length, and returns the number of bytes written.
+func (c *codec[T]) putItem(buffer []byte, x T) int {
+	b := binary.LittleEndian
+	if c.kind == reflect.String {
+		s := as[string](x)
+		b.PutUint32(buffer, uint32(len(s)))
+		return 4 + copy(buffer[4:], s)
+	}
+	bits := c.toBits(x)
+	switch c.size {
+	case 1:
+		buffer[0] = byte(bits)
+	case 2:
+		b.PutUint16(buffer, uint16(bits))
+	case 4:
+		b.PutUint32(buffer, uint32(bits))
+	default:
+		b.PutUint64(buffer, bits)
+	}
+	return c.size
+}
+
+// getItem reads a value written by putItem from buffer, returning it and
+// the number of bytes it takes. It returns false if buffer is shorter than
+// that, or if the value does not fit in T.
+func (c *codec[T]) getItem(buffer []byte) (T, int, bool) {
+	var x T
+	size := c.size
+	if len(buffer) < size {
+		return x, size, false
+	}
+	b := binary.LittleEndian
+	var bits uint64
+	switch size {
+	case 1:
+		bits = uint64(buffer[0])
+	case 2:
+		bits = uint64(b.Uint16(buffer))
+	case 4:
+		bits = uint64(b.Uint32(buffer))
+	default:
+		bits = b.Uint64(buffer)
+	}
+	if c.kind == reflect.String {
+		if uint64(len(buffer)-4) < bits {
+			return x, size + int(bits), false
+		}
+		return as[T](string(buffer[4 : 4+bits])), size + int(bits), true
+	}
+	x, ok := c.fromBits(bits)
+	return x, size, ok
+}
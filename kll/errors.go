@@ -0,0 +1,34 @@
+package kll
+
+import (
+	"errors"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrK is returned when k is not between MinK and MaxK.
+	ErrK = errors.New("kll: k must be between 8 and 65535")
+	// ErrEmpty is returned when the quantiles of an empty Sketch are
+	// queried.
+	ErrEmpty = errors.New("kll: sketch is empty")
+	// ErrQuantile is returned when a quantile is not between 0 and 1.
+	ErrQuantile = errors.New("kll: quantile must be between 0 and 1")
+	// ErrSplits is returned when split points are not increasing or
+	// include NaN.
+	ErrSplits = errors.New("kll: splits must be increasing and not NaN")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "KLL", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "KLL", Reason: reason}
+}
@@ -0,0 +1,469 @@
+// Package kll implements the KLL sketch, which estimates the quantiles and
+// ranks of a stream of ordered values, e.g. latency percentiles, in space
+// independent of the length of the stream.
+//
+// Optimal Quantile Approximation in Streams:
+// https://arxiv.org/pdf/1603.05346.pdf
+//
+// The implementation follows the streaming-quantiles reference code:
+// https://github.com/edoliberty/streaming-quantiles
+package kll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*Sketch[float64]] = new(Sketch[float64])
+	_ datasketch.Serializable                = new(Sketch[float64])
+)
+
+// Ordered is the set of types whose values a Sketch can summarize.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+const (
+	// DefaultK gives a rank error of about 1%.
+	DefaultK = 200
+	// MinK and MaxK bound the accuracy parameter k.
+	MinK = 8
+	MaxK = 1<<16 - 1
+
+	// minWidth is the smallest capacity of a level.
+	minWidth = 8
+	// maxLevels bounds the number of levels, whose items weigh up to
+	// 2^(maxLevels-1).
+	maxLevels = 64
+)
+
+// seeds hands every Sketch a different sequence of coin flips.
+var seeds uint64
+
+// Sketch data structure. Levels[h] holds items standing for 2^h values of
+// the stream each. A level that reaches its capacity is compacted: it is
+// sorted and every other item, starting at a random one, moves up a
+// level. The top level has capacity K and each level below 2/3 of the
+// one above, so the sketch keeps O(K) items.
+type Sketch[T Ordered] struct {
+	K int
+	// N is the number of values summarized.
+	N uint64
+	// Min and Max are the smallest and largest values, valid if N > 0.
+	Min, Max T
+	Levels   [][]T
+
+	size, maxSize int
+	rng           uint64
+	// view is the sorted items with their cumulative weights, built by
+	// queries and dropped by updates.
+	view *view[T]
+}
+
+// New returns an empty Sketch. Ranks are estimated within about 2/k with
+// high probability; k is usually DefaultK.
+func New[T Ordered](k int) (*Sketch[T], error) {
+	if k < MinK || k > MaxK {
+		return nil, ErrK
+	}
+	s := &Sketch[T]{K: k, rng: atomic.AddUint64(&seeds, 1)}
+	s.grow()
+	return s, nil
+}
+
+// Clear sets the Sketch back to its initial state.
+func (s *Sketch[T]) Clear() {
+	var zero T
+	s.N, s.Min, s.Max = 0, zero, zero
+	s.Levels = s.Levels[:0]
+	s.size, s.view = 0, nil
+	s.grow()
+}
+
+// capacity returns the capacity of level h.
+func (s *Sketch[T]) capacity(h int) int {
+	depth := len(s.Levels) - h - 1
+	c := int(math.Ceil(float64(s.K) * math.Pow(2.0/3, float64(depth))))
+	if c < minWidth {
+		return minWidth
+	}
+	return c
+}
+
+// grow adds a level on top and updates maxSize, as the capacities of all
+// levels depend on the number of levels.
+func (s *Sketch[T]) grow() {
+	s.Levels = append(s.Levels, nil)
+	s.maxSize = 0
+	for h := range s.Levels {
+		s.maxSize += s.capacity(h)
+	}
+}
+
+// Update adds x to the Sketch. NaNs are ignored.
+func (s *Sketch[T]) Update(x T) {
+	if x != x {
+		return
+	}
+	if s.N == 0 || x < s.Min {
+		s.Min = x
+	}
+	if s.N == 0 || x > s.Max {
+		s.Max = x
+	}
+	s.N++
+	s.Levels[0] = append(s.Levels[0], x)
+	s.size++
+	s.view = nil
+	if s.size >= s.maxSize {
+		s.compress()
+	}
+}
+
+// compress compacts the lowest levels at capacity until the Sketch is
+// within its size.
+func (s *Sketch[T]) compress() {
+	for s.size >= s.maxSize {
+		for h := range s.Levels {
+			if len(s.Levels[h]) >= s.capacity(h) {
+				if !s.compact(h) {
+					return
+				}
+				break
+			}
+		}
+	}
+}
+
+// compact sorts level h and moves every other item, starting from the
+// first or the second at random, to level h+1. With an odd number of
+// items, the largest stays behind. It returns false if h is the highest
+// level possible.
+func (s *Sketch[T]) compact(h int) bool {
+	if h+1 == len(s.Levels) {
+		if len(s.Levels) == maxLevels {
+			return false
+		}
+		s.grow()
+	}
+	level := s.Levels[h]
+	sort.Slice(level, func(i, j int) bool { return level[i] < level[j] })
+	n := len(level) &^ 1
+	s.rng += 0x9e3779b97f4a7c15
+	for i := int(mix.Mix64(s.rng) & 1); i < n; i += 2 {
+		s.Levels[h+1] = append(s.Levels[h+1], level[i])
+	}
+	s.size -= n / 2
+	if n < len(level) {
+		level[0] = level[n]
+	}
+	s.Levels[h] = level[:len(level)-n]
+	return true
+}
+
+// Merge takes another Sketch and combines it with s, making s the summary
+// of both streams. s keeps its K.
+func (s *Sketch[T]) Merge(other *Sketch[T]) error {
+	if other.N == 0 {
+		return nil
+	}
+	if s.N == 0 || other.Min < s.Min {
+		s.Min = other.Min
+	}
+	if s.N == 0 || other.Max > s.Max {
+		s.Max = other.Max
+	}
+	s.N += other.N
+	for len(s.Levels) < len(other.Levels) {
+		s.grow()
+	}
+	for h, level := range other.Levels {
+		s.Levels[h] = append(s.Levels[h], level...)
+		s.size += len(level)
+	}
+	s.view = nil
+	s.compress()
+	return nil
+}
+
+// view holds the items of a Sketch in increasing order, with the total
+// weight of the items up to each of them.
+type view[T Ordered] struct {
+	items   []T
+	weights []uint64
+}
+
+func (s *Sketch[T]) sorted() *view[T] {
+	if s.view != nil {
+		return s.view
+	}
+	type weighted struct {
+		x T
+		w uint64
+	}
+	all := make([]weighted, 0, s.size)
+	for h, level := range s.Levels {
+		for _, x := range level {
+			all = append(all, weighted{x, 1 << h})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].x < all[j].x })
+	v := &view[T]{items: make([]T, len(all)),
+		weights: make([]uint64, len(all))}
+	var total uint64
+	for i, it := range all {
+		total += it.w
+		v.items[i], v.weights[i] = it.x, total
+	}
+	s.view = v
+	return v
+}
+
+// weight returns the total weight of the items not greater than x.
+func (v *view[T]) weight(x T) uint64 {
+	i := sort.Search(len(v.items), func(i int) bool { return v.items[i] > x })
+	if i == 0 {
+		return 0
+	}
+	return v.weights[i-1]
+}
+
+// Rank returns the estimated fraction of the values not greater than x,
+// or NaN if the Sketch is empty.
+func (s *Sketch[T]) Rank(x T) float64 {
+	if s.N == 0 {
+		return math.NaN()
+	}
+	return float64(s.sorted().weight(x)) / float64(s.N)
+}
+
+// Quantile returns the estimated q-quantile, the smallest value whose
+// Rank is at least q. Quantile(0) and Quantile(1) are Min and Max.
+func (s *Sketch[T]) Quantile(q float64) (T, error) {
+	var zero T
+	if !(q >= 0 && q <= 1) {
+		return zero, ErrQuantile
+	}
+	if s.N == 0 {
+		return zero, ErrEmpty
+	}
+	if q == 0 {
+		return s.Min, nil
+	}
+	if q == 1 {
+		return s.Max, nil
+	}
+	v := s.sorted()
+	target := uint64(math.Ceil(q * float64(s.N)))
+	i := sort.Search(len(v.weights), func(i int) bool {
+		return v.weights[i] >= target
+	})
+	if i == len(v.items) {
+		return s.Max, nil
+	}
+	return v.items[i], nil
+}
+
+// CDF returns the estimated fractions of the values not greater than each
+// of splits, followed by 1. splits must be increasing.
+func (s *Sketch[T]) CDF(splits []T) ([]float64, error) {
+	for i, x := range splits {
+		if x != x || i > 0 && splits[i-1] >= x {
+			return nil, ErrSplits
+		}
+	}
+	if s.N == 0 {
+		return nil, ErrEmpty
+	}
+	v := s.sorted()
+	cdf := make([]float64, len(splits)+1)
+	for i, x := range splits {
+		cdf[i] = float64(v.weight(x)) / float64(s.N)
+	}
+	cdf[len(splits)] = 1
+	return cdf, nil
+}
+
+// PMF returns the estimated fractions of the values in the intervals
+// delimited by splits: not greater than splits[0], then greater than
+// splits[i-1] and not greater than splits[i], then greater than the last
+// split. splits must be increasing.
+func (s *Sketch[T]) PMF(splits []T) ([]float64, error) {
+	pmf, err := s.CDF(splits)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(pmf) - 1; i > 0; i-- {
+		pmf[i] -= pmf[i-1]
+	}
+	return pmf, nil
+}
+
+// headerSize is the size of the serialized Sketch before its level sizes.
+const headerSize = 12
+
+// ByteSize returns the size of the serialized object.
+func (s *Sketch[T]) ByteSize() int {
+	c := newCodec[T]()
+	size := headerSize + 4*len(s.Levels)
+	if s.N > 0 {
+		size += c.itemSize(s.Min) + c.itemSize(s.Max)
+	}
+	for _, level := range s.Levels {
+		if c.kind != reflect.String {
+			size += len(level) * c.size
+			continue
+		}
+		for _, x := range level {
+			size += c.itemSize(x)
+		}
+	}
+	return size
+}
+
+// Serialize the Sketch to bytes stored in buffer: the kind of T, the
+// number of levels, K, N, the size of every level, Min and Max if N > 0,
+// then the items level by level. Numbers are little-endian and strings
+// preceded by their length.
+func (s *Sketch[T]) Serialize(buffer []byte) error {
+	if len(buffer) < s.ByteSize() {
+		return shortBuffer(s.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	c := newCodec[T]()
+	buffer[0] = byte(c.kind)
+	buffer[1] = byte(len(s.Levels))
+	b.PutUint16(buffer[2:], uint16(s.K))
+	b.PutUint64(buffer[4:], s.N)
+	offset := headerSize
+	for _, level := range s.Levels {
+		b.PutUint32(buffer[offset:], uint32(len(level)))
+		offset += 4
+	}
+	if s.N > 0 {
+		offset += c.putItem(buffer[offset:], s.Min)
+		offset += c.putItem(buffer[offset:], s.Max)
+	}
+	for _, level := range s.Levels {
+		for _, x := range level {
+			offset += c.putItem(buffer[offset:], x)
+		}
+	}
+	return nil
+}
+
+// Deserialize reconstructs a Sketch of values of type T from the buffer.
+// The values must have been serialized from a Sketch of a type of the
+// same kind.
+func Deserialize[T Ordered](buffer []byte) (*Sketch[T], error) {
+	if len(buffer) < headerSize {
+		return nil, shortBuffer(headerSize, len(buffer))
+	}
+	b := binary.LittleEndian
+	c := newCodec[T]()
+	if got := reflect.Kind(buffer[0]); got != c.kind {
+		return nil, corrupt(fmt.Sprintf("values are of kind %v, not %v",
+			got, c.kind))
+	}
+	numLevels := int(buffer[1])
+	if numLevels == 0 || numLevels > maxLevels {
+		return nil, corrupt(fmt.Sprintf("%d levels is not between 1 and %d",
+			numLevels, maxLevels))
+	}
+	k := int(b.Uint16(buffer[2:]))
+	if k < MinK {
+		return nil, corrupt(fmt.Sprintf("k %d is less than %d", k, MinK))
+	}
+	n := b.Uint64(buffer[4:])
+	offset := headerSize
+	if need := offset + 4*numLevels; len(buffer) < need {
+		return nil, shortBuffer(need, len(buffer))
+	}
+	// The items of level h weigh 2^h each and add up to N.
+	sizes := make([]int, numLevels)
+	var count, weight uint64
+	for h := range sizes {
+		size := uint64(b.Uint32(buffer[offset:]))
+		offset += 4
+		if size > (n-weight)>>h {
+			return nil, corrupt(fmt.Sprintf("level %d holds more than the "+
+				"%d values summarized", h, n))
+		}
+		weight += size << h
+		count += size
+		sizes[h] = int(size)
+	}
+	if weight != n {
+		return nil, corrupt(fmt.Sprintf("levels sum up to %d values, not %d",
+			weight, n))
+	}
+	if n > 0 {
+		count += 2
+	}
+	// Bound the allocations by the bytes the items take at least.
+	if need := uint64(offset) + count*uint64(c.size); uint64(
+		len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	s, err := New[T](k)
+	if err != nil {
+		return nil, err
+	}
+	for len(s.Levels) < numLevels {
+		s.grow()
+	}
+	s.N = n
+	next := func() (T, error) {
+		x, size, ok := c.getItem(buffer[offset:])
+		if !ok && offset+size > len(buffer) {
+			return x, shortBuffer(offset+size, len(buffer))
+		}
+		if !ok {
+			return x, corrupt(fmt.Sprintf("a value overflows %v",
+				c.kind))
+		}
+		offset += size
+		if x != x {
+			return x, corrupt("a value is NaN")
+		}
+		return x, nil
+	}
+	if n > 0 {
+		if s.Min, err = next(); err != nil {
+			return nil, err
+		}
+		if s.Max, err = next(); err != nil {
+			return nil, err
+		}
+		if s.Min > s.Max {
+			return nil, corrupt("minimum exceeds maximum")
+		}
+	}
+	for h, size := range sizes {
+		s.Levels[h] = make([]T, size)
+		for i := range s.Levels[h] {
+			x, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if x < s.Min || x > s.Max {
+				return nil, corrupt("a value is outside of the minimum " +
+					"and maximum")
+			}
+			s.Levels[h][i] = x
+		}
+		s.size += size
+	}
+	return s, nil
+}
@@ -0,0 +1,340 @@
+package kll
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// maxRankError returns the largest difference between the estimated and
+// the true ranks of the sorted values.
+func maxRankError(s *Sketch[float64], sorted []float64) float64 {
+	var worst float64
+	for i := 0; i < len(sorted); i += len(sorted) / 1000 {
+		rank := float64(i+1) / float64(len(sorted))
+		worst = math.Max(worst, math.Abs(s.Rank(sorted[i])-rank))
+	}
+	return worst
+}
+
+func stream(seed int64, n int) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = r.ExpFloat64()
+	}
+	return values
+}
+
+func TestKLLNew(t *testing.T) {
+	for _, k := range []int{0, MinK - 1, MaxK + 1} {
+		if _, err := New[float64](k); !errors.Is(err, ErrK) {
+			t.Error(k, err)
+		}
+	}
+	s, err := New[float64](DefaultK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(s.Rank(1)) {
+		t.Error(s.Rank(1))
+	}
+	if _, err := s.Quantile(0.5); !errors.Is(err, ErrEmpty) {
+		t.Error(err)
+	}
+	if _, err := s.CDF([]float64{1}); !errors.Is(err, ErrEmpty) {
+		t.Error(err)
+	}
+}
+
+func TestKLLExact(t *testing.T) {
+	// Until the first compaction, the sketch holds every value.
+	s, _ := New[float64](DefaultK)
+	for i := 100; i > 0; i-- {
+		s.Update(float64(i))
+	}
+	s.Update(math.NaN())
+	if s.N != 100 || s.Min != 1 || s.Max != 100 {
+		t.Error(s.N, s.Min, s.Max)
+	}
+	for _, c := range []struct{ q, want float64 }{
+		{0, 1}, {0.005, 1}, {0.01, 1}, {0.5, 50}, {0.501, 51}, {0.99, 99},
+		{1, 100},
+	} {
+		if got, err := s.Quantile(c.q); err != nil || got != c.want {
+			t.Error(c.q, got, err)
+		}
+	}
+	if r := s.Rank(50); r != 0.5 {
+		t.Error(r)
+	}
+	if r := s.Rank(0); r != 0 {
+		t.Error(r)
+	}
+	for _, q := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := s.Quantile(q); !errors.Is(err, ErrQuantile) {
+			t.Error(q, err)
+		}
+	}
+
+	cdf, err := s.CDF([]float64{10, 50.5, 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0.1, 0.5, 0.9, 1}; !equal(cdf, want) {
+		t.Error(cdf)
+	}
+	pmf, err := s.PMF([]float64{10, 50.5, 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0.1, 0.4, 0.4, 0.1}; !equal(pmf, want) {
+		t.Error(pmf)
+	}
+	for _, splits := range [][]float64{{2, 1}, {1, 1}, {math.NaN()}} {
+		if _, err := s.PMF(splits); !errors.Is(err, ErrSplits) {
+			t.Error(splits, err)
+		}
+	}
+
+	s.Clear()
+	if s.N != 0 || s.size != 0 || len(s.Levels) != 1 {
+		t.Error("Clear did not reset the Sketch")
+	}
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-12 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestKLLRankError(t *testing.T) {
+	values := stream(1, 1000000)
+	s, _ := New[float64](DefaultK)
+	for _, v := range values {
+		s.Update(v)
+	}
+	sort.Float64s(values)
+	if s.N != uint64(len(values)) || s.Min != values[0] ||
+		s.Max != values[len(values)-1] {
+		t.Error(s.N, s.Min, s.Max)
+	}
+	// The sketch keeps O(K) items.
+	if s.size > 3*DefaultK+minWidth*len(s.Levels) {
+		t.Errorf("%d items in %d levels", s.size, len(s.Levels))
+	}
+	if e := maxRankError(s, values); e > 0.015 {
+		t.Errorf("rank error %v", e)
+	}
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		x, _ := s.Quantile(q)
+		if r := float64(sort.SearchFloat64s(values, x)) /
+			float64(len(values)); math.Abs(r-q) > 0.015 {
+			t.Errorf("quantile %v has rank %v", q, r)
+		}
+	}
+}
+
+func TestKLLMerge(t *testing.T) {
+	values := stream(1, 200000)
+	a, _ := New[float64](DefaultK)
+	b, _ := New[float64](DefaultK)
+	for i, v := range values {
+		if i%3 == 0 {
+			a.Update(v)
+		} else {
+			b.Update(v)
+		}
+	}
+	empty, _ := New[float64](DefaultK)
+	if err := a.Merge(empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.Merge(a); err != nil {
+		t.Fatal(err)
+	}
+	if empty.N != a.N || empty.Min != a.Min || empty.Max != a.Max {
+		t.Error("merging into an empty sketch lost values")
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	sort.Float64s(values)
+	if a.N != uint64(len(values)) || a.Min != values[0] ||
+		a.Max != values[len(values)-1] {
+		t.Error(a.N, a.Min, a.Max)
+	}
+	if e := maxRankError(a, values); e > 0.015 {
+		t.Errorf("rank error %v", e)
+	}
+	if a.size >= a.maxSize {
+		t.Error(a.size, a.maxSize)
+	}
+
+	// Merging a sketch with itself doubles every value.
+	if err := b.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.N != 2*uint64(len(values)-len(values)/3-1) {
+		t.Error(b.N)
+	}
+}
+
+type latency float64
+
+func TestKLLOrdered(t *testing.T) {
+	s, _ := New[string](MinK)
+	for i := 0; i < 10000; i++ {
+		s.Update(strconv.Itoa(i))
+	}
+	if s.Min != "0" || s.Max != "9999" {
+		t.Error(s.Min, s.Max)
+	}
+	// Strings sort lexicographically: "5" is preceded by the 5 numbers
+	// of each length starting with 0 to 4.
+	if r := s.Rank("5"); math.Abs(r-0.5) > 0.1 {
+		t.Error(r)
+	}
+
+	l, _ := New[latency](DefaultK)
+	for i := 1; i <= 1000; i++ {
+		l.Update(latency(i))
+	}
+	if x, _ := l.Quantile(0.99); math.Abs(float64(x)-990) > 20 {
+		t.Error(x)
+	}
+}
+
+func TestKLLSerialization(t *testing.T) {
+	s, _ := New[float64](DefaultK)
+	for _, v := range stream(1, 10000) {
+		s.Update(v)
+	}
+	buf := make([]byte, s.ByteSize())
+	if err := s.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize[float64](buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.K != s.K || d.N != s.N || d.Min != s.Min || d.Max != s.Max ||
+		d.size != s.size || d.maxSize != s.maxSize {
+		t.Error("Did not get back the same Sketch")
+	}
+	for _, q := range []float64{0.1, 0.5, 0.9} {
+		x, _ := s.Quantile(q)
+		y, _ := d.Quantile(q)
+		if x != y {
+			t.Error(q, x, y)
+		}
+	}
+
+	if err := s.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize[float64](buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize[int64](buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad := append([]byte(nil), buf...)
+	bad[4]++
+	if _, err := Deserialize[float64](bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad = append([]byte(nil), buf...)
+	bad[1] = 0
+	if _, err := Deserialize[float64](bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+
+	// Every kind of value round-trips.
+	i8, _ := New[int8](MinK)
+	for i := -128; i < 128; i++ {
+		i8.Update(int8(i))
+	}
+	buf = make([]byte, i8.ByteSize())
+	i8.Serialize(buf)
+	if d, err := Deserialize[int8](buf); err != nil || d.Min != -128 ||
+		d.Max != 127 {
+		t.Error(err)
+	}
+	u16, _ := New[uint16](MinK)
+	for i := 0; i < 1000; i++ {
+		u16.Update(uint16(i * 65))
+	}
+	buf = make([]byte, u16.ByteSize())
+	u16.Serialize(buf)
+	if d, err := Deserialize[uint16](buf); err != nil || d.Min != 0 ||
+		d.Max != 999*65 {
+		t.Error(err)
+	}
+	// Named types are serialized like their underlying type.
+	l, _ := New[latency](MinK)
+	l.Update(1.5)
+	l.Update(-2)
+	buf = make([]byte, l.ByteSize())
+	l.Serialize(buf)
+	if d, err := Deserialize[latency](buf); err != nil || d.Min != -2 ||
+		d.Max != 1.5 {
+		t.Error(err)
+	}
+	if d, err := Deserialize[float64](buf); err != nil || d.Max != 1.5 {
+		t.Error(err)
+	}
+	str, _ := New[string](MinK)
+	str.Update("hello")
+	str.Update("")
+	buf = make([]byte, str.ByteSize())
+	str.Serialize(buf)
+	if d, err := Deserialize[string](buf); err != nil || d.Min != "" ||
+		d.Max != "hello" {
+		t.Error(err)
+	}
+	if _, err := Deserialize[string](buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	s, _ := New[float64](MinK)
+	for _, v := range stream(1, 100) {
+		s.Update(v)
+	}
+	buf := make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:20])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := Deserialize[float64](data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		for i := 0; i < 100; i++ {
+			s.Update(float64(i))
+		}
+		s.Quantile(0.5)
+	})
+}
@@ -0,0 +1,37 @@
+package kll
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(Sketch[float64])
+	_ driver.Valuer = new(Sketch[float64])
+)
+
+// Value implements driver.Valuer so a Sketch can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized Sketch preceded
+// by a tag identifying it as a KLL sketch.
+func (s *Sketch[T]) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.KLL, s)
+}
+
+// Scan implements sql.Scanner, restoring a Sketch written by Value.
+// Blobs holding any other kind of sketch, or values of another kind, are
+// rejected.
+func (s *Sketch[T]) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.KLL, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize[T](buffer)
+	if err != nil {
+		return err
+	}
+	*s = *other
+	return nil
+}
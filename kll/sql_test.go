@@ -0,0 +1,42 @@
+package kll
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestKLLValueScan(t *testing.T) {
+	s, _ := New[float64](DefaultK)
+	for i := 0; i < 1000; i++ {
+		s.Update(float64(i))
+	}
+	v, err := s.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Sketch[float64]
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.N != s.N || d.Min != s.Min || d.Max != s.Max {
+		t.Error("Did not get back the same Sketch")
+	}
+	var other Sketch[string]
+	if err := other.Scan(v); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func TestKLLScanError(t *testing.T) {
+	var s Sketch[float64]
+	if err := s.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	h, _ := hyperloglog.New(4)
+	v, _ := h.Value()
+	if err := s.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
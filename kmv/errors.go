@@ -0,0 +1,43 @@
+package kmv
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrK is returned when k is less than 2.
+	ErrK = errors.New("kmv: k must be at least 2")
+	// ErrTooFewSketches is returned when a set operation is given fewer
+	// than 2 KMVs.
+	ErrTooFewSketches = errors.New("kmv: less than 2 KMVs were given")
+	// ErrSizeMismatch is returned when KMVs with different k are
+	// combined. It is wrapped by SizeMismatchError.
+	ErrSizeMismatch = errors.New("kmv: k do not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// SizeMismatchError reports the k of two KMVs that cannot be combined.
+type SizeMismatchError struct {
+	K, OtherK int
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("kmv: k do not match: %d != %d", e.K, e.OtherK)
+}
+
+func (e *SizeMismatchError) Unwrap() error { return ErrSizeMismatch }
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "KMV", Need: need, Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "KMV", Reason: reason}
+}
@@ -0,0 +1,326 @@
+// Package kmv implements the bottom-k, or K-Minimum-Values, sketch. It
+// keeps the k smallest 64-bit hashes of a set, which is enough to estimate
+// both its cardinality and its overlap with other sets.
+//
+// On Synopses for Distinct-Value Estimation Under Multiset Operations:
+// http://dl.acm.org/citation.cfm?id=1247504
+package kmv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Hash32 is a relaxed version of hash.Hash32
+type Hash32 = datasketch.Hash32
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Digester                  = new(KMV)
+	_ datasketch.Mergeable[*KMV]           = new(KMV)
+	_ datasketch.CardinalityEstimator      = new(KMV)
+	_ datasketch.SimilarityEstimator[*KMV] = new(KMV)
+	_ datasketch.Serializable              = new(KMV)
+)
+
+// HashFunc hashes data to the 64-bit hashes a KMV keeps.
+type HashFunc func(data []byte) uint64
+
+// KMV keeps the K smallest distinct hashes digested, in increasing order.
+type KMV struct {
+	K      int
+	Hashes []uint64
+
+	hash HashFunc
+}
+
+// Option configures a KMV created by New or Deserialize.
+type Option func(*KMV)
+
+// WithHashFunc makes DigestBytes, DigestString and DigestUint64 hash items
+// with f instead of murmur3.Sum64WithSeed. Sketches are only comparable
+// when their items were hashed with the same function.
+func WithHashFunc(f HashFunc) Option {
+	return func(s *KMV) {
+		s.hash = f
+	}
+}
+
+// WithKey makes DigestBytes, DigestString and DigestUint64 hash items with
+// SipHash keyed with the secret k0 and k1, so an attacker who controls the
+// items cannot choose which of them are kept. Sketches are only comparable
+// when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed64(k0, k1))
+}
+
+// New returns a KMV keeping the k smallest hashes, k at least 2. The
+// relative error of its estimates is about 1/sqrt(k).
+func New(k int, opts ...Option) (*KMV, error) {
+	if k < 2 || k > math.MaxInt32 {
+		return nil, ErrK
+	}
+	s := &KMV{K: k}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Clear sets the KMV back to its initial state.
+func (s *KMV) Clear() {
+	s.Hashes = s.Hashes[:0]
+}
+
+// Digest adds an item given by its 32-bit hash. The hash is spread over 64
+// bits, but items whose 32-bit hashes collide are still counted once, so
+// prefer DigestBytes or Digest64 for sets of more than a few million items.
+func (s *KMV) Digest(item Hash32) {
+	s.Digest64(mix.Mix64(uint64(item.Sum32())))
+}
+
+// DigestBytes hashes data and adds the hash.
+func (s *KMV) DigestBytes(data []byte) {
+	if s.hash != nil {
+		s.Digest64(s.hash(data))
+		return
+	}
+	s.Digest64(murmur3.Sum64WithSeed(data, 0))
+}
+
+// DigestString hashes the bytes of s and adds the hash.
+func (s *KMV) DigestString(str string) {
+	if s.hash != nil {
+		s.Digest64(s.hash(unsafe.Slice(unsafe.StringData(str), len(str))))
+		return
+	}
+	s.Digest64(murmur3.Sum64String(str, 0))
+}
+
+// DigestUint64 hashes the little-endian encoding of v and adds the hash.
+func (s *KMV) DigestUint64(v uint64) {
+	if s.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		s.Digest64(s.hash(b[:]))
+		return
+	}
+	s.Digest64(murmur3.Sum64Uint64(v, 0))
+}
+
+// Digest64 adds an item given by its 64-bit hash.
+func (s *KMV) Digest64(h uint64) {
+	n := len(s.Hashes)
+	if n == s.K && h >= s.Hashes[n-1] {
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return s.Hashes[i] >= h })
+	if i < n && s.Hashes[i] == h {
+		return
+	}
+	if n < s.K {
+		s.Hashes = append(s.Hashes, 0)
+	}
+	copy(s.Hashes[i+1:], s.Hashes[i:])
+	s.Hashes[i] = h
+}
+
+// Count returns the estimated number of distinct items digested. It is
+// exact until K items have been seen.
+func (s *KMV) Count() float64 {
+	n := len(s.Hashes)
+	if n < s.K {
+		return float64(n)
+	}
+	return float64(s.K-1) / unit(s.Hashes[n-1])
+}
+
+// Merge takes another KMV and combines it with s, making s the union of
+// both.
+func (s *KMV) Merge(other *KMV) error {
+	if s.K != other.K {
+		return &SizeMismatchError{s.K, other.K}
+	}
+	s.Hashes = union(s.K, s.Hashes, other.Hashes)
+	return nil
+}
+
+// Jaccard estimates the Jaccard similarity between s and other.
+func (s *KMV) Jaccard(other *KMV) (float64, error) {
+	u, common, err := overlap(s, other)
+	if err != nil || len(u) == 0 {
+		return 0.0, err
+	}
+	return float64(common) / float64(len(u)), nil
+}
+
+// Containment estimates the fraction of the items of s that were also
+// digested by other.
+func (s *KMV) Containment(other *KMV) (float64, error) {
+	count := s.Count()
+	if count == 0 {
+		return 0.0, nil
+	}
+	c, err := IntersectionCount(s, other)
+	if err != nil {
+		return 0.0, err
+	}
+	return math.Min(c/count, 1.0), nil
+}
+
+// Union returns a new KMV of the union of sketches.
+func Union(sketches ...*KMV) (*KMV, error) {
+	if len(sketches) < 2 {
+		return nil, ErrTooFewSketches
+	}
+	u := &KMV{K: sketches[0].K, hash: sketches[0].hash}
+	for _, s := range sketches {
+		if err := u.Merge(s); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// UnionCount returns the estimated number of distinct items digested by
+// any of sketches.
+func UnionCount(sketches ...*KMV) (float64, error) {
+	u, err := Union(sketches...)
+	if err != nil {
+		return 0.0, err
+	}
+	return u.Count(), nil
+}
+
+// IntersectionCount returns the estimated number of distinct items
+// digested by all of sketches: the fraction of the K smallest hashes of
+// their union that every sketch kept, times the count of the union.
+func IntersectionCount(sketches ...*KMV) (float64, error) {
+	u, common, err := overlap(sketches...)
+	if err != nil || len(u) == 0 {
+		return 0.0, err
+	}
+	k := &KMV{K: sketches[0].K, Hashes: u}
+	return float64(common) / float64(len(u)) * k.Count(), nil
+}
+
+// overlap returns the K smallest hashes of the union of sketches and how
+// many of them every sketch kept. A hash among the K smallest of the union
+// is among the K smallest of every sketch that digested it, so the count
+// is exact for the hashes returned.
+func overlap(sketches ...*KMV) ([]uint64, int, error) {
+	if len(sketches) < 2 {
+		return nil, 0, ErrTooFewSketches
+	}
+	k := sketches[0].K
+	var u []uint64
+	for _, s := range sketches {
+		if s.K != k {
+			return nil, 0, &SizeMismatchError{k, s.K}
+		}
+		u = union(k, u, s.Hashes)
+	}
+	common := 0
+	for _, h := range u {
+		all := true
+		for _, s := range sketches {
+			i := sort.Search(len(s.Hashes), func(i int) bool {
+				return s.Hashes[i] >= h
+			})
+			if i == len(s.Hashes) || s.Hashes[i] != h {
+				all = false
+				break
+			}
+		}
+		if all {
+			common++
+		}
+	}
+	return u, common, nil
+}
+
+// union returns the k smallest distinct hashes of the sorted a and b.
+func union(k int, a, b []uint64) []uint64 {
+	u := make([]uint64, 0, k)
+	for len(u) < k && (len(a) > 0 || len(b) > 0) {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			u = append(u, a[0])
+			a = a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			u = append(u, b[0])
+			b = b[1:]
+		default:
+			u = append(u, a[0])
+			a, b = a[1:], b[1:]
+		}
+	}
+	return u
+}
+
+// unit maps a hash to (0, 1].
+func unit(h uint64) float64 {
+	return (float64(h) + 1) / (1 << 64)
+}
+
+// ByteSize returns the size of the serialized object.
+func (s *KMV) ByteSize() int {
+	return 4 + 4 + 8*len(s.Hashes)
+}
+
+// Serialize the KMV to bytes stored in buffer: K, the number of hashes
+// kept and the hashes in increasing order.
+func (s *KMV) Serialize(buffer []byte) error {
+	if len(buffer) < s.ByteSize() {
+		return shortBuffer(s.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint32(buffer, uint32(s.K))
+	b.PutUint32(buffer[4:], uint32(len(s.Hashes)))
+	offset := 8
+	for _, h := range s.Hashes {
+		b.PutUint64(buffer[offset:], h)
+		offset += 8
+	}
+	return nil
+}
+
+// Deserialize reconstructs a KMV from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*KMV, error) {
+	if len(buffer) < 8 {
+		return nil, shortBuffer(8, len(buffer))
+	}
+	b := binary.LittleEndian
+	k := b.Uint32(buffer)
+	n := b.Uint32(buffer[4:])
+	if k < 2 || k > math.MaxInt32 {
+		return nil, corrupt(fmt.Sprintf("k %d is out of range", k))
+	}
+	if n > k {
+		return nil, corrupt(fmt.Sprintf("%d hashes are more than k %d", n, k))
+	}
+	if need := 8 + 8*uint64(n); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	s, err := New(int(k), opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.Hashes = make([]uint64, n)
+	offset := 8
+	for i := range s.Hashes {
+		s.Hashes[i] = b.Uint64(buffer[offset:])
+		if i > 0 && s.Hashes[i] <= s.Hashes[i-1] {
+			return nil, corrupt("hashes are not in increasing order")
+		}
+		offset += 8
+	}
+	return s, nil
+}
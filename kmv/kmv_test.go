@@ -0,0 +1,219 @@
+package kmv
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type fakeHash32 uint32
+
+func (f fakeHash32) Sum32() uint32 { return uint32(f) }
+
+func TestKMVDigest(t *testing.T) {
+	s, _ := New(4)
+	for _, h := range []uint64{50, 10, 40, 10, 30, 20, 60, 5} {
+		s.Digest64(h)
+	}
+	want := []uint64{5, 10, 20, 30}
+	if len(s.Hashes) != len(want) {
+		t.Fatal(s.Hashes)
+	}
+	for i := range want {
+		if s.Hashes[i] != want[i] {
+			t.Fatal(s.Hashes)
+		}
+	}
+	s.Clear()
+	if s.Count() != 0 {
+		t.Error(s.Count())
+	}
+}
+
+func TestKMVCount(t *testing.T) {
+	s, _ := New(1024)
+	for i := uint64(0); i < 1000; i++ {
+		s.DigestUint64(i)
+		s.DigestUint64(i)
+	}
+	if s.Count() != 1000 {
+		t.Errorf("exact count %v (want 1000)", s.Count())
+	}
+	for _, n := range []uint64{10000, 1000000} {
+		s.Clear()
+		for i := uint64(0); i < n; i++ {
+			s.DigestUint64(i)
+		}
+		// 4 standard errors of 1/sqrt(k-2).
+		if err := math.Abs(s.Count()-float64(n)) / float64(n); err > 4/math.Sqrt(1022) {
+			t.Errorf("count %v (want %v)", s.Count(), n)
+		}
+	}
+}
+
+func TestKMVSimilarity(t *testing.T) {
+	const k = 1024
+	a, _ := New(k)
+	b, _ := New(k)
+	// a holds [0, 20000), b [10000, 40000): 10000 in common, 40000 in the
+	// union.
+	for i := uint64(0); i < 20000; i++ {
+		a.DigestUint64(i)
+	}
+	for i := uint64(10000); i < 40000; i++ {
+		b.DigestUint64(i)
+	}
+	tol := 4 / math.Sqrt(k)
+	if j, _ := a.Jaccard(b); math.Abs(j-0.25) > tol {
+		t.Errorf("Jaccard %v (want 0.25)", j)
+	}
+	if c, _ := a.Containment(b); math.Abs(c-0.5) > tol {
+		t.Errorf("Containment %v (want 0.5)", c)
+	}
+	if c, _ := b.Containment(a); math.Abs(c-1.0/3) > tol {
+		t.Errorf("Containment %v (want 0.33)", c)
+	}
+	if n, _ := IntersectionCount(a, b); math.Abs(n-10000)/10000 > 2*tol {
+		t.Errorf("IntersectionCount %v (want 10000)", n)
+	}
+	if n, _ := UnionCount(a, b); math.Abs(n-40000)/40000 > tol {
+		t.Errorf("UnionCount %v (want 40000)", n)
+	}
+
+	u, _ := Union(a, b)
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := a.Jaccard(u); j != 1.0 {
+		t.Error(j)
+	}
+
+	// Below k items the estimates are exact.
+	c, _ := New(k)
+	d, _ := New(k)
+	for i := uint64(0); i < 300; i++ {
+		c.DigestUint64(i)
+		d.DigestUint64(i + 200)
+	}
+	if j, _ := c.Jaccard(d); j != 100.0/500 {
+		t.Error(j)
+	}
+	if n, _ := IntersectionCount(c, d); n != 100 {
+		t.Error(n)
+	}
+	e, _ := New(k)
+	if j, _ := e.Jaccard(e); j != 0 {
+		t.Error(j)
+	}
+}
+
+func TestKMVError(t *testing.T) {
+	if _, err := New(1); !errors.Is(err, ErrK) {
+		t.Error(err)
+	}
+	a, _ := New(8)
+	b, _ := New(16)
+	var sizeErr *SizeMismatchError
+	if err := a.Merge(b); !errors.As(err, &sizeErr) || sizeErr.K != 8 ||
+		sizeErr.OtherK != 16 {
+		t.Error(err)
+	}
+	if _, err := a.Jaccard(b); !errors.Is(err, ErrSizeMismatch) {
+		t.Error(err)
+	}
+	if _, err := Union(a); !errors.Is(err, ErrTooFewSketches) {
+		t.Error(err)
+	}
+	if _, err := IntersectionCount(a); !errors.Is(err, ErrTooFewSketches) {
+		t.Error(err)
+	}
+}
+
+func TestKMVDigestTyped(t *testing.T) {
+	a, _ := New(16)
+	b, _ := New(16)
+	a.DigestString("hello")
+	a.DigestUint64(42)
+	a.Digest(fakeHash32(7))
+	b.DigestBytes([]byte("hello"))
+	b.DigestUint64(42)
+	b.Digest(fakeHash32(7))
+	if j, _ := a.Jaccard(b); j != 1.0 {
+		t.Error(j)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		a.DigestString("hello")
+		a.DigestUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+
+	c, _ := New(16, WithKey(1, 2))
+	d, _ := New(16, WithKey(1, 2))
+	c.DigestString("hello")
+	d.DigestUint64(1)
+	if c.Hashes[0] == a.Hashes[0] {
+		t.Error("WithKey did not change the hash")
+	}
+	d.DigestBytes([]byte("hello"))
+	if j, _ := c.Jaccard(d); j != 0.5 {
+		t.Error(j)
+	}
+}
+
+func TestKMVSerialization(t *testing.T) {
+	s, _ := New(16)
+	for i := uint64(0); i < 100; i++ {
+		s.DigestUint64(i)
+	}
+	buf := make([]byte, s.ByteSize())
+	if err := s.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.K != s.K || d.Count() != s.Count() {
+		t.Error("Did not get back the same KMV")
+	}
+	if err := s.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	copy(buf[16:24], buf[8:16])
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[4] = 17
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	s, _ := New(4)
+	s.Digest(fakeHash32(0x00010fff))
+	s.Digest(fakeHash32(0x00020fff))
+	buf := make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:8])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		s.Count()
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package kmv
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(KMV)
+	_ driver.Valuer = new(KMV)
+)
+
+// Value implements driver.Valuer so a KMV can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized KMV preceded by
+// a tag identifying it as a KMV.
+func (s *KMV) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.KMV, s)
+}
+
+// Scan implements sql.Scanner, restoring a KMV written by Value. Blobs
+// holding any other kind of sketch are rejected. The hash function set on
+// s, if any, is kept.
+func (s *KMV) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.KMV, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(s.hash))
+	if err != nil {
+		return err
+	}
+	*s = *other
+	return nil
+}
@@ -0,0 +1,37 @@
+package kmv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestKMVValueScan(t *testing.T) {
+	s, _ := New(8)
+	s.Digest(fakeHash32(0x00010fff))
+	s.Digest(fakeHash32(0x00020fff))
+	v, err := s.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d KMV
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := s.Jaccard(&d); d.K != s.K || j != 1.0 {
+		t.Error("Did not get back the same KMV")
+	}
+}
+
+func TestKMVScanError(t *testing.T) {
+	var s KMV
+	if err := s.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	h, _ := hyperloglog.New(4)
+	v, _ := h.Value()
+	if err := s.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,81 @@
+package minhash
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrNumPerm is returned when the number of permutations is not
+	// positive or exceeds MaxNumPerm.
+	ErrNumPerm = errors.New("minhash: number of permutations must be between 1 and 16777215")
+	// ErrVersion is returned when New is given an unknown Version.
+	ErrVersion = errors.New("minhash: unknown version")
+	// ErrTooFewSignatures is returned when a comparison is given fewer
+	// than 2 signatures.
+	ErrTooFewSignatures = errors.New("minhash: less than 2 signatures were given")
+	// ErrSeedMismatch is returned when signatures with different seeds are
+	// combined or compared. It is wrapped by SeedMismatchError.
+	ErrSeedMismatch = errors.New("minhash: seeds do not match")
+	// ErrSizeMismatch is returned when signatures with different numbers
+	// of permutations are compared. It is wrapped by SizeMismatchError.
+	ErrSizeMismatch = errors.New("minhash: numbers of permutations do not match")
+	// ErrVersionMismatch is returned when signatures with different
+	// versions are combined or compared. It is wrapped by
+	// VersionMismatchError.
+	ErrVersionMismatch = errors.New("minhash: versions do not match")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// SeedMismatchError reports the seeds of two signatures that cannot be
+// combined.
+type SeedMismatchError struct {
+	Seed, OtherSeed int64
+}
+
+func (e *SeedMismatchError) Error() string {
+	return fmt.Sprintf("minhash: seeds do not match: %d != %d", e.Seed,
+		e.OtherSeed)
+}
+
+func (e *SeedMismatchError) Unwrap() error { return ErrSeedMismatch }
+
+// SizeMismatchError reports the numbers of permutations of two signatures
+// that cannot be compared.
+type SizeMismatchError struct {
+	Size, OtherSize int
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("minhash: numbers of permutations do not match: %d != %d",
+		e.Size, e.OtherSize)
+}
+
+func (e *SizeMismatchError) Unwrap() error { return ErrSizeMismatch }
+
+// VersionMismatchError reports the versions of two signatures that cannot
+// be combined.
+type VersionMismatchError struct {
+	Version, OtherVersion Version
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("minhash: versions do not match: %d != %d",
+		e.Version, e.OtherVersion)
+}
+
+func (e *VersionMismatchError) Unwrap() error { return ErrVersionMismatch }
+
+func shortBuffer(sketch string, need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: sketch, Need: need, Have: have}
+}
+
+func corrupt(sketch, reason string) error {
+	return &datasketch.CorruptError{Sketch: sketch, Reason: reason}
+}
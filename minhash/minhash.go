@@ -11,34 +11,62 @@ package minhash
 
 import (
 	"encoding/binary"
-	"errors"
+	"fmt"
 	"math"
-	"math/rand"
+
+	"github.com/ekzhu/go-datasketch"
 )
 
 // Hash32 is a relaxed version of hash.Hash32
-type Hash32 interface {
-	Sum32() uint32
-}
+type Hash32 = datasketch.Hash32
 
-const (
-	mersennePrime = (1 << 61) - 1
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Digester                      = new(MinHash)
+	_ datasketch.Mergeable[*MinHash]           = new(MinHash)
+	_ datasketch.SimilarityEstimator[*MinHash] = new(MinHash)
+	_ datasketch.Serializable                  = new(MinHash)
 )
 
-// http://en.wikipedia.org/wiki/Universal_hashing
-type permutation func(uint32) uint32
-
-func createPermutation(a, b uint32, p uint64, m int) permutation {
-	return func(x uint32) uint32 {
-		return uint32((uint(a*x+b) % uint(p)) % uint(m))
-	}
-}
-
 // The MinHash signagure
 type MinHash struct {
 	Permutations []permutation
 	HashValues   []uint32
 	Seed         int64
+	Version      Version
+	hasher       datasketch.Hasher
+	// Coefficients of the permutations, laid out flat so that digesting
+	// does not go through a closure call per permutation.
+	a, b []uint64
+}
+
+// Option configures a MinHash created by New or Deserialize.
+type Option func(*MinHash)
+
+// WithHashFunc makes DigestBytes, DigestString and DigestUint64 hash items
+// with f instead of murmur3.Sum32. Signatures are only comparable when
+// their items were hashed with the same function.
+func WithHashFunc(f datasketch.HashFunc) Option {
+	return func(sig *MinHash) {
+		sig.hasher.Func = f
+	}
+}
+
+// WithVersion makes New draw the permutations from the family of version v
+// instead of Version2. Use Version1 to keep comparing with signatures
+// built by earlier releases.
+func WithVersion(v Version) Option {
+	return func(sig *MinHash) {
+		sig.Version = v
+	}
+}
+
+// WithKey makes DigestBytes, DigestString and DigestUint64 hash items with
+// SipHash keyed with the secret k0 and k1, so an attacker who controls the
+// items cannot force hash collisions. Signatures are only comparable when
+// built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(datasketch.KeyedHashFunc(k0, k1))
 }
 
 // New creates a new MinHash signature.
@@ -47,26 +75,30 @@ type MinHash struct {
 // be generated.
 // Higher number of permutations results in better estimation,
 // but reduces performance. 128 is a good number to start.
-func New(numPerm int, seed int64) (*MinHash, error) {
-	if numPerm <= 0 {
-		return nil, errors.New("Cannot have non-positive number of permutations")
+func New(numPerm int, seed int64, opts ...Option) (*MinHash, error) {
+	if numPerm <= 0 || numPerm > MaxNumPerm {
+		return nil, ErrNumPerm
 	}
 	s := new(MinHash)
+	s.Version = Version2
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.Version != Version1 && s.Version != Version2 {
+		return nil, ErrVersion
+	}
 	s.HashValues = make([]uint32, numPerm)
 	s.Permutations = make([]permutation, numPerm)
 	s.Seed = seed
-	rand.Seed(s.Seed)
-	var a uint32
+	s.a, s.b = coefficients(s.Version, seed, numPerm)
 	for i := 0; i < numPerm; i++ {
 		s.HashValues[i] = math.MaxUint32
-		for {
-			a = rand.Uint32()
-			if a != 0 {
-				break
-			}
+		if s.Version == Version1 {
+			s.Permutations[i] = createPermutation(uint32(s.a[i]),
+				uint32(s.b[i]), mersennePrime, (1 << 32))
+		} else {
+			s.Permutations[i] = createPermutation61(s.a[i], s.b[i])
 		}
-		s.Permutations[i] = createPermutation(a,
-			rand.Uint32(), mersennePrime, (1 << 32))
 	}
 	return s, nil
 }
@@ -84,21 +116,101 @@ func (sig *MinHash) Clear() {
 // http://programmers.stackexchange.com/a/145633.
 // You can use the murmur3 hash function in /hashfunc/murmur3 directory.
 func (sig *MinHash) Digest(item Hash32) {
-	hv := item.Sum32()
-	var phv uint32
-	for i := range sig.Permutations {
-		phv = (sig.Permutations[i])(hv)
-		if phv < sig.HashValues[i] {
-			sig.HashValues[i] = phv
+	sig.digest(item.Sum32())
+}
+
+// DigestBytes hashes data and digests the hash.
+func (sig *MinHash) DigestBytes(data []byte) {
+	sig.digest(sig.hasher.Sum32(data))
+}
+
+// DigestString hashes the bytes of s and digests the hash.
+func (sig *MinHash) DigestString(s string) {
+	sig.digest(sig.hasher.Sum32String(s))
+}
+
+// DigestUint64 hashes the little-endian encoding of v and digests the hash.
+func (sig *MinHash) DigestUint64(v uint64) {
+	sig.digest(sig.hasher.Sum32Uint64(v))
+}
+
+func (sig *MinHash) digest(hv uint32) {
+	a, b := sig.a, sig.b[:len(sig.a)]
+	hvs := sig.HashValues[:len(a)]
+	if sig.Version == Version1 {
+		for i := range a {
+			if phv := uint32(a[i])*hv + uint32(b[i]); phv < hvs[i] {
+				hvs[i] = phv
+			}
+		}
+		return
+	}
+	for i := range a {
+		if phv := uint32(mulAddMod61(a[i], hv, b[i])); phv < hvs[i] {
+			hvs[i] = phv
 		}
 	}
 }
 
+// batchSize is the number of hash values DigestBatch runs through every
+// permutation at a time. The block stays in L1 cache across permutations.
+const batchSize = 512
+
+// DigestBatch digests many 32-bit hashes at once. It produces the same
+// signature as calling Digest for each of them, faster for large
+// documents: about 1.5 times with Version1 permutations, and 1.3 times
+// with Version2, whose 128-bit products bound the gain.
+func (sig *MinHash) DigestBatch(hvs []uint32) {
+	for len(hvs) > batchSize {
+		sig.digestBlock(hvs[:batchSize])
+		hvs = hvs[batchSize:]
+	}
+	sig.digestBlock(hvs)
+}
+
+// DigestMany digests the hashes of items, as DigestBatch does.
+func (sig *MinHash) DigestMany(items []Hash32) {
+	var block [batchSize]uint32
+	for len(items) > 0 {
+		n := 0
+		for n < len(block) && n < len(items) {
+			block[n] = items[n].Sum32()
+			n++
+		}
+		sig.digestBlock(block[:n])
+		items = items[n:]
+	}
+}
+
+// digestBlock keeps the permutation in the outer loop so that its
+// coefficients and running minimum live in registers.
+func (sig *MinHash) digestBlock(hvs []uint32) {
+	if len(hvs) == 0 {
+		return
+	}
+	if sig.Version == Version1 {
+		for i := range sig.a {
+			sig.HashValues[i] = min32Block(uint32(sig.a[i]), uint32(sig.b[i]),
+				sig.HashValues[i], hvs)
+		}
+		return
+	}
+	for i := range sig.a {
+		sig.HashValues[i] = min61Block(sig.a[i], sig.b[i], sig.HashValues[i], hvs)
+	}
+}
+
 // Merge takes another MinHash and combines it with MinHash sig,
 // making sig the union of both.
 func (sig *MinHash) Merge(other *MinHash) error {
 	if sig.Seed != other.Seed {
-		return errors.New("Cannot merge MinHashs with different seed.")
+		return &SeedMismatchError{sig.Seed, other.Seed}
+	}
+	if sig.Version != other.Version {
+		return &VersionMismatchError{sig.Version, other.Version}
+	}
+	if len(sig.HashValues) != len(other.HashValues) {
+		return &SizeMismatchError{len(sig.HashValues), len(other.HashValues)}
 	}
 	for i, v := range other.HashValues {
 		if v < sig.HashValues[i] {
@@ -113,15 +225,16 @@ func (sig *MinHash) ByteSize() int {
 	return 8 + 4 + 4*len(sig.HashValues)
 }
 
-// Serialize the MinHash signature to bytes stored in buffer
+// Serialize the MinHash signature to bytes stored in buffer.
+// The version is stored in the top byte of the number of permutations,
+// left 0 for Version1 so that its layout is the one of earlier releases.
 func (sig *MinHash) Serialize(buffer []byte) error {
 	if len(buffer) < sig.ByteSize() {
-		return errors.New("The buffer does not have enough space to " +
-			"hold the MinHash signature.")
+		return shortBuffer("MinHash", sig.ByteSize(), len(buffer))
 	}
 	b := binary.LittleEndian
 	b.PutUint64(buffer, uint64(sig.Seed))
-	b.PutUint32(buffer[8:], uint32(len(sig.HashValues)))
+	b.PutUint32(buffer[8:], packVersion(sig.Version, len(sig.HashValues)))
 	offset := 8 + 4
 	for _, v := range sig.HashValues {
 		b.PutUint32(buffer[offset:], v)
@@ -131,20 +244,29 @@ func (sig *MinHash) Serialize(buffer []byte) error {
 }
 
 // Deserialize reconstructs a MinHash signature from the buffer
-func Deserialize(buffer []byte) (*MinHash, error) {
+func Deserialize(buffer []byte, opts ...Option) (*MinHash, error) {
 	if len(buffer) < 12 {
-		return nil, errors.New("The buffer does not contain enough bytes to " +
-			"reconstruct a MinHash.")
+		return nil, shortBuffer("MinHash", 12, len(buffer))
 	}
 	b := binary.LittleEndian
 	seed := int64(b.Uint64(buffer))
-	numPerm := int(b.Uint32(buffer[8:]))
+	version, numPerm, err := unpackVersion("MinHash", b.Uint32(buffer[8:]))
+	if err != nil {
+		return nil, err
+	}
 	offset := 12
-	if len(buffer[offset:]) < numPerm {
-		return nil, errors.New("The buffer does not contain enough bytes to " +
-			"reconstruct a MinHash.")
+	if numPerm == 0 {
+		return nil, corrupt("MinHash", "number of permutations is 0")
 	}
-	m, err := New(numPerm, seed)
+	// Checking the length before calling New bounds the allocation by the
+	// size of the buffer rather than by the untrusted header.
+	if need := uint64(offset) + 4*uint64(numPerm); uint64(len(buffer)) < need {
+		return nil, shortBuffer("MinHash", int(need), len(buffer))
+	}
+	// The full slice expression makes append copy opts rather than write
+	// to the spare capacity of the caller's slice.
+	opts = append(opts[:len(opts):len(opts)], WithVersion(version))
+	m, err := New(int(numPerm), seed, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -155,21 +277,48 @@ func Deserialize(buffer []byte) (*MinHash, error) {
 	return m, nil
 }
 
+// packVersion combines a version and a number of permutations into the
+// 32 bits of the serialized number of permutations.
+func packVersion(v Version, n int) uint32 {
+	if v == Version1 {
+		v = 0
+	}
+	return uint32(v)<<24 | uint32(n)
+}
+
+// unpackVersion splits what packVersion combined.
+func unpackVersion(sketch string, x uint32) (Version, uint32, error) {
+	switch v := Version(x >> 24); v {
+	case 0:
+		return Version1, x & MaxNumPerm, nil
+	case Version2:
+		return v, x & MaxNumPerm, nil
+	default:
+		return 0, 0, corrupt(sketch, fmt.Sprintf("unknown version %d", v))
+	}
+}
+
+// Jaccard estimates the Jaccard similarity between sig and other.
+func (sig *MinHash) Jaccard(other *MinHash) (float64, error) {
+	return Jaccard(sig, other)
+}
+
 // Jaccard computes the estimation of Jaccard Similarity among
 // MinHash signatures.
 func Jaccard(sigs ...*MinHash) (float64, error) {
 	if sigs == nil || len(sigs) < 2 {
-		return 0.0, errors.New("Less than 2 MinHash signatures were given")
+		return 0.0, ErrTooFewSignatures
 	}
 	numPerm := len(sigs[0].Permutations)
 	for _, sig := range sigs[1:] {
 		if sigs[0].Seed != sig.Seed {
-			return 0.0, errors.New("Cannot compare MinHash signatures with " +
-				"different seed")
+			return 0.0, &SeedMismatchError{sigs[0].Seed, sig.Seed}
+		}
+		if sigs[0].Version != sig.Version {
+			return 0.0, &VersionMismatchError{sigs[0].Version, sig.Version}
 		}
 		if numPerm != len(sig.Permutations) {
-			return 0.0, errors.New("Cannot compare MinHash signatures with " +
-				"different numbers of permutations")
+			return 0.0, &SizeMismatchError{numPerm, len(sig.Permutations)}
 		}
 	}
 	intersection := 0
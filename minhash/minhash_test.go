@@ -1,6 +1,14 @@
 package minhash
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
 
 type fakeHash32 uint32
 
@@ -87,3 +95,372 @@ func TestMinHashError(t *testing.T) {
 		t.Error("should return error if number of permutations don't match")
 	}
 }
+
+func TestMinHashErrorValues(t *testing.T) {
+	if _, err := New(0, 0); !errors.Is(err, ErrNumPerm) {
+		t.Error(err)
+	}
+	m1, _ := New(4, 1)
+	m2, _ := New(4, 2)
+	var seedErr *SeedMismatchError
+	if err := m1.Merge(m2); !errors.As(err, &seedErr) ||
+		seedErr.Seed != 1 || seedErr.OtherSeed != 2 {
+		t.Error(err)
+	}
+	if _, err := Jaccard(m1, m2); !errors.Is(err, ErrSeedMismatch) {
+		t.Error(err)
+	}
+	m3, _ := New(8, 1)
+	var sizeErr *SizeMismatchError
+	if _, err := Jaccard(m1, m3); !errors.As(err, &sizeErr) ||
+		sizeErr.Size != 4 || sizeErr.OtherSize != 8 {
+		t.Error(err)
+	}
+	if err := m1.Merge(m3); !errors.As(err, &sizeErr) ||
+		sizeErr.Size != 4 || sizeErr.OtherSize != 8 {
+		t.Error(err)
+	}
+	if _, err := Jaccard(m1); !errors.Is(err, ErrTooFewSignatures) {
+		t.Error(err)
+	}
+	if _, err := EstimateJaccardOneBit(m1.ExportOneBit()); !errors.Is(err,
+		ErrTooFewSignatures) {
+		t.Error(err)
+	}
+	if err := m1.Serialize(make([]byte, 4)); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(make([]byte, 12)); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	var o OneBitMinHash
+	v, _ := m1.Value()
+	if err := o.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
+
+func TestMinHashDeserializeError(t *testing.T) {
+	m, _ := New(4, 1)
+	buf := make([]byte, m.ByteSize())
+	m.Serialize(buf)
+	if _, err := Deserialize(buf[:len(buf)-1]); err == nil {
+		t.Error("should return error if the hash values are truncated")
+	}
+	buf[8], buf[9], buf[10], buf[11] = 0xff, 0xff, 0xff, 0xff
+	if _, err := Deserialize(buf); err == nil {
+		t.Error("should return error if the number of permutations is too large")
+	}
+	buf[8], buf[9], buf[10], buf[11] = 0, 0, 0, 0
+	if _, err := Deserialize(buf); err == nil {
+		t.Error("should return error if the number of permutations is 0")
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	m, _ := New(4, 1)
+	m.Digest(fakeHash32(0x00010fff))
+	buf := make([]byte, m.ByteSize())
+	m.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:12])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, m.ByteSize())
+		if err := m.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
+
+func FuzzDeserializeOneBit(f *testing.F) {
+	m, _ := New(100, 1)
+	m.Digest(fakeHash32(0x00010fff))
+	o := m.ExportOneBit()
+	buf := make([]byte, o.ByteSize())
+	o.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:12])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		o, err := DeserializeOneBit(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, o.ByteSize())
+		if err := o.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
+
+func TestMinHashDigestTyped(t *testing.T) {
+	m1, _ := New(128, 1)
+	m2, _ := New(128, 1)
+	m1.DigestString("hello")
+	m1.DigestUint64(42)
+	m2.DigestBytes([]byte("hello"))
+	m2.DigestUint64(42)
+	if est, _ := Jaccard(m1, m2); est != 1.0 {
+		t.Error(est)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		m1.DigestString("hello")
+		m1.DigestUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+
+	m3, _ := New(128, 1, WithHashFunc(func(data []byte) uint32 {
+		return 0x00010fff
+	}))
+	m4, _ := New(128, 1)
+	m3.DigestString("hello")
+	m4.Digest(fakeHash32(0x00010fff))
+	if est, _ := Jaccard(m3, m4); est != 1.0 {
+		t.Error(est)
+	}
+}
+
+func TestMinHashDigestBatch(t *testing.T) {
+	hvs := make([]uint32, 2000)
+	items := make([]Hash32, len(hvs))
+	for i := range hvs {
+		hvs[i] = uint32(i) * 0x9e3779b9
+		items[i] = fakeHash32(hvs[i])
+	}
+	for _, v := range []Version{Version1, Version2} {
+		for _, n := range []int{0, 1, 3, 4, 5, batchSize, batchSize + 1, len(hvs)} {
+			m1, _ := New(128, 1, WithVersion(v))
+			m2, _ := New(128, 1, WithVersion(v))
+			m3, _ := New(128, 1, WithVersion(v))
+			for _, hv := range hvs[:n] {
+				m1.Digest(fakeHash32(hv))
+			}
+			m2.DigestBatch(hvs[:n])
+			m3.DigestMany(items[:n])
+			for i := range m1.HashValues {
+				if m1.HashValues[i] != m2.HashValues[i] || m1.HashValues[i] != m3.HashValues[i] {
+					t.Fatalf("version %d, %d hashes, permutation %d: got %d and %d, want %d",
+						v, n, i, m2.HashValues[i], m3.HashValues[i], m1.HashValues[i])
+				}
+			}
+		}
+		// The flat coefficients must agree with the exported permutations.
+		for _, hv := range hvs[:10] {
+			m, _ := New(128, 1, WithVersion(v))
+			m.Digest(fakeHash32(hv))
+			for i, perm := range m.Permutations {
+				if perm(hv) != m.HashValues[i] {
+					t.Fatalf("version %d: permutation %d differs on %d", v, i, hv)
+				}
+			}
+		}
+	}
+}
+
+func benchmarkHashes() []uint32 {
+	hvs := make([]uint32, 10000)
+	for i := range hvs {
+		hvs[i] = uint32(i) * 0x9e3779b9
+	}
+	return hvs
+}
+
+func BenchmarkDigest(b *testing.B) {
+	for _, v := range []Version{Version1, Version2} {
+		b.Run(fmt.Sprintf("Version%d", v), func(b *testing.B) {
+			m, _ := New(128, 1, WithVersion(v))
+			hvs := benchmarkHashes()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, hv := range hvs {
+					m.Digest(fakeHash32(hv))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDigestBatch(b *testing.B) {
+	for _, v := range []Version{Version1, Version2} {
+		b.Run(fmt.Sprintf("Version%d", v), func(b *testing.B) {
+			m, _ := New(128, 1, WithVersion(v))
+			hvs := benchmarkHashes()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.DigestBatch(hvs)
+			}
+		})
+	}
+}
+
+func TestMulAddMod61(t *testing.T) {
+	p := new(big.Int).SetUint64(mersennePrime)
+	r := rand.New(rand.NewSource(1))
+	as := []uint64{1, 2, mersennePrime - 1}
+	bs := []uint64{0, 1, mersennePrime - 2, mersennePrime - 1}
+	xs := []uint32{0, 1, math.MaxUint32}
+	for i := 0; i < 100; i++ {
+		as = append(as, uint64(r.Int63n(mersennePrime-1))+1)
+		bs = append(bs, uint64(r.Int63n(mersennePrime)))
+		xs = append(xs, r.Uint32())
+	}
+	want := new(big.Int)
+	for _, a := range as {
+		for _, b := range bs {
+			for _, x := range xs[:10] {
+				want.SetUint64(a)
+				want.Mul(want, new(big.Int).SetUint64(uint64(x)))
+				want.Add(want, new(big.Int).SetUint64(b))
+				want.Mod(want, p)
+				if got := mulAddMod61(a, x, b); got != want.Uint64() {
+					t.Fatalf("(%d*%d+%d) mod p: %d (want %d)", a, x, b, got, want)
+				}
+				got := min61Block(a, b, math.MaxUint32, []uint32{x})
+				if got != uint32(want.Uint64()) {
+					t.Fatalf("(%d*%d+%d) mod p in a block: %d (want %d)", a, x,
+						b, got, uint32(want.Uint64()))
+				}
+			}
+		}
+	}
+}
+
+func TestMinHashVersion(t *testing.T) {
+	// A signature serialized before versions were introduced.
+	old := []byte{0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0, 0x0,
+		0xdc, 0x4f, 0x80, 0xa, 0xe8, 0x4d, 0x34, 0x6b, 0xa, 0xb8, 0xd0, 0x9e,
+		0xe6, 0x2e, 0x1d, 0x6b}
+	d, err := Deserialize(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != Version1 {
+		t.Errorf("version %d (want %d)", d.Version, Version1)
+	}
+	opts := make([]Option, 0, 1)
+	if _, err := Deserialize(old, opts...); err != nil || opts[:1][0] != nil {
+		t.Error("Deserialize wrote to the options of the caller", err)
+	}
+	m, _ := New(4, 1, WithVersion(Version1))
+	m.Digest(fakeHash32(0x00010fff))
+	m.Digest(fakeHash32(0x02010fff))
+	buf := make([]byte, m.ByteSize())
+	m.Serialize(buf)
+	if !bytes.Equal(buf, old) {
+		t.Errorf("Version1 serializes to %x (want %x)", buf, old)
+	}
+	if est, err := Jaccard(m, d); err != nil || est != 1.0 {
+		t.Error(est, err)
+	}
+
+	m2, _ := New(4, 1)
+	if m2.Version != Version2 {
+		t.Errorf("default version %d (want %d)", m2.Version, Version2)
+	}
+	m2.Digest(fakeHash32(0x00010fff))
+	m2.Serialize(buf)
+	d2, err := Deserialize(buf)
+	if err != nil || d2.Version != Version2 {
+		t.Fatal(d2, err)
+	}
+	if est, err := Jaccard(m2, d2); err != nil || est != 1.0 {
+		t.Error(est, err)
+	}
+	var versionErr *VersionMismatchError
+	if err := m2.Merge(m); !errors.As(err, &versionErr) ||
+		versionErr.Version != Version2 || versionErr.OtherVersion != Version1 {
+		t.Error(err)
+	}
+	if _, err := Jaccard(m, m2); !errors.Is(err, ErrVersionMismatch) {
+		t.Error(err)
+	}
+	if _, err := EstimateJaccardOneBit(m.ExportOneBit(),
+		m2.ExportOneBit()); !errors.Is(err, ErrVersionMismatch) {
+		t.Error(err)
+	}
+	o := m2.ExportOneBit()
+	obuf := make([]byte, o.ByteSize())
+	o.Serialize(obuf)
+	if do, err := DeserializeOneBit(obuf); err != nil || do.Version != Version2 {
+		t.Error(do, err)
+	}
+
+	if _, err := New(4, 1, WithVersion(3)); !errors.Is(err, ErrVersion) {
+		t.Error(err)
+	}
+	if _, err := New(MaxNumPerm+1, 1); !errors.Is(err, ErrNumPerm) {
+		t.Error(err)
+	}
+	buf[11] = 3
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+// jaccardTrials returns the mean of the estimates of the Jaccard similarity
+// of two sets of n items sharing k, over trials signatures with different
+// seeds.
+func jaccardTrials(v Version, n, k, trials int, item func(trial, i int) uint64) float64 {
+	var sum float64
+	for trial := 0; trial < trials; trial++ {
+		m1, _ := New(128, int64(trial), WithVersion(v))
+		m2, _ := New(128, int64(trial), WithVersion(v))
+		for i := 0; i < n; i++ {
+			m1.DigestUint64(item(trial, i))
+			m2.DigestUint64(item(trial, n-k+i))
+		}
+		est, _ := Jaccard(m1, m2)
+		sum += est
+	}
+	return sum / float64(trials)
+}
+
+func TestMinHashUnbiased(t *testing.T) {
+	const n, trials = 500, 200
+	item := func(trial, i int) uint64 { return uint64(trial)<<32 | uint64(i) }
+	for _, k := range []int{0, 50, 250, 333, 450, 500} {
+		j := float64(k) / float64(2*n-k)
+		mean := jaccardTrials(Version2, n, k, trials, item)
+		// Each estimate is the mean of 128 Bernoulli trials of
+		// probability j, allow 4 standard deviations of their mean.
+		sigma := math.Sqrt(j * (1 - j) / (128 * trials))
+		if math.Abs(mean-j) > 4*sigma+1e-9 {
+			t.Errorf("k=%d: mean estimate %.4f (want %.4f +/- %.4f)", k, mean,
+				j, 4*sigma)
+		}
+	}
+}
+
+func TestMinHashVersion1Collisions(t *testing.T) {
+	// Disjoint sets whose hashes differ only in the top bit. Version1
+	// maps them to the same values under every permutation with an even
+	// a, about half of them.
+	m1, _ := New(128, 1, WithVersion(Version1))
+	m2, _ := New(128, 1, WithVersion(Version1))
+	m3, _ := New(128, 1)
+	m4, _ := New(128, 1)
+	for i := uint32(0); i < 100; i++ {
+		m1.Digest(fakeHash32(i * 0x9e3779b9 >> 1))
+		m2.Digest(fakeHash32(i*0x9e3779b9>>1 | 1<<31))
+		m3.Digest(fakeHash32(i * 0x9e3779b9 >> 1))
+		m4.Digest(fakeHash32(i*0x9e3779b9>>1 | 1<<31))
+	}
+	if est, _ := Jaccard(m1, m2); est < 0.3 {
+		t.Errorf("Version1 estimate %v, expected the collisions to show", est)
+	}
+	if est, _ := Jaccard(m3, m4); est > 0.05 {
+		t.Errorf("Version2 estimate %v (want about 0)", est)
+	}
+}
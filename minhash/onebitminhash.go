@@ -1,8 +1,17 @@
 package minhash
 
 import (
-	"errors"
+	"encoding/binary"
+	"fmt"
 	"math/big"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.SimilarityEstimator[*OneBitMinHash] = new(OneBitMinHash)
+	_ datasketch.Serializable                        = new(OneBitMinHash)
 )
 
 const (
@@ -37,6 +46,7 @@ type OneBitMinHash struct {
 	Size     int
 	BitArray *big.Int
 	Seed     int64
+	Version  Version
 }
 
 // ExportOneBit exports the full MinHash signature to OneBitMinHash.
@@ -56,6 +66,7 @@ func (sig *MinHash) ExportOneBit() *OneBitMinHash {
 		BitArray: big.NewInt(0),
 		Seed:     sig.Seed,
 		Size:     numExportedHashValues,
+		Version:  sig.Version,
 	}
 	for i := 0; i < numExportedHashValues; i++ {
 		sigOneBit.BitArray.SetBit(sigOneBit.BitArray, i,
@@ -64,19 +75,25 @@ func (sig *MinHash) ExportOneBit() *OneBitMinHash {
 	return &sigOneBit
 }
 
+// Jaccard estimates the Jaccard similarity between sig and other.
+func (sig *OneBitMinHash) Jaccard(other *OneBitMinHash) (float64, error) {
+	return EstimateJaccardOneBit(sig, other)
+}
+
 // EstimateJaccardOneBit estimates Jaccard similarity of OneBitMinHash signatures
 func EstimateJaccardOneBit(sigs ...*OneBitMinHash) (float64, error) {
-	if sigs == nil || len(sigs) == 0 {
-		return 0.0, errors.New("Less than 2 OneBitMinHash signatures were given")
+	if sigs == nil || len(sigs) < 2 {
+		return 0.0, ErrTooFewSignatures
 	}
 	for _, sig := range sigs[1:] {
 		if sigs[0].Seed != sig.Seed {
-			return 0.0, errors.New("Cannot compare OneBitMinHash signatures " +
-				"with different seed")
+			return 0.0, &SeedMismatchError{sigs[0].Seed, sig.Seed}
+		}
+		if sigs[0].Version != sig.Version {
+			return 0.0, &VersionMismatchError{sigs[0].Version, sig.Version}
 		}
 		if sigs[0].Size != sig.Size {
-			return 0.0, errors.New("Cannot compare OneBitMinHash signatures " +
-				"with different numbers of permutations")
+			return 0.0, &SizeMismatchError{sigs[0].Size, sig.Size}
 		}
 	}
 	commonBits := big.NewInt(0)
@@ -86,3 +103,50 @@ func EstimateJaccardOneBit(sigs ...*OneBitMinHash) (float64, error) {
 	return 2.0 * (float64((sigs[0].Size-popCountBig(commonBits)))/
 		float64(sigs[0].Size) - 0.5), nil
 }
+
+// ByteSize returns the size of the serialized object.
+func (sig *OneBitMinHash) ByteSize() int {
+	return 8 + 4 + (sig.Size+7)/8
+}
+
+// Serialize the OneBitMinHash signature to bytes stored in buffer
+func (sig *OneBitMinHash) Serialize(buffer []byte) error {
+	if len(buffer) < sig.ByteSize() {
+		return shortBuffer("OneBitMinHash", sig.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint64(buffer, uint64(sig.Seed))
+	b.PutUint32(buffer[8:], packVersion(sig.Version, sig.Size))
+	sig.BitArray.FillBytes(buffer[12:sig.ByteSize()])
+	return nil
+}
+
+// DeserializeOneBit reconstructs a OneBitMinHash signature from the buffer
+func DeserializeOneBit(buffer []byte) (*OneBitMinHash, error) {
+	if len(buffer) < 12 {
+		return nil, shortBuffer("OneBitMinHash", 12, len(buffer))
+	}
+	b := binary.LittleEndian
+	version, size, err := unpackVersion("OneBitMinHash", b.Uint32(buffer[8:]))
+	if err != nil {
+		return nil, err
+	}
+	sig := &OneBitMinHash{
+		Seed:    int64(b.Uint64(buffer)),
+		Size:    int(size),
+		Version: version,
+	}
+	if sig.Size <= 0 || sig.Size > bitArraySize {
+		return nil, corrupt("OneBitMinHash",
+			fmt.Sprintf("size %d is not between 1 and %d", sig.Size,
+				bitArraySize))
+	}
+	if len(buffer) < sig.ByteSize() {
+		return nil, shortBuffer("OneBitMinHash", sig.ByteSize(), len(buffer))
+	}
+	sig.BitArray = new(big.Int).SetBytes(buffer[12:sig.ByteSize()])
+	if sig.BitArray.BitLen() > sig.Size {
+		return nil, corrupt("OneBitMinHash", "bits are set beyond its size")
+	}
+	return sig, nil
+}
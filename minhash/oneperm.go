@@ -0,0 +1,244 @@
+package minhash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Digester                             = new(OnePermMinHash)
+	_ datasketch.Mergeable[*OnePermMinHash]           = new(OnePermMinHash)
+	_ datasketch.SimilarityEstimator[*OnePermMinHash] = new(OnePermMinHash)
+	_ datasketch.Serializable                         = new(OnePermMinHash)
+)
+
+// OnePermMinHash is a MinHash signature computed with One Permutation
+// Hashing: every item is hashed once and kept in one of k bins, so that
+// Digest costs O(1) instead of O(numPerm). Bins left empty, common for
+// sets smaller than a few times k, are filled with optimal densification
+// when the signature is read.
+//
+// One Permutation Hashing:
+// https://papers.nips.cc/paper/4778-one-permutation-hashing.pdf
+//
+// Optimal Densification for Fast and Accurate Minwise Hashing:
+// https://arxiv.org/abs/1703.04664
+type OnePermMinHash struct {
+	// HashValues holds the minimum of every bin, math.MaxUint32 if the
+	// bin is empty. Use Signature for the densified values.
+	HashValues []uint32
+	Seed       int64
+	hasher     datasketch.Hasher
+	// Keys mixing the items into bins and choosing the bins that empty
+	// bins borrow from, both derived from Seed.
+	itemKey, binKey uint64
+}
+
+// NewOnePerm creates a new OnePermMinHash signature of k bins. Its
+// estimates are as accurate as those of a MinHash with k permutations.
+// `seed` is used to generate the hash functions. Of the options, only
+// WithHashFunc and WithKey apply.
+func NewOnePerm(k int, seed int64, opts ...Option) (*OnePermMinHash, error) {
+	if k <= 0 || k > MaxNumPerm {
+		return nil, ErrNumPerm
+	}
+	// The options configure a MinHash, only its hash function is kept.
+	var m MinHash
+	for _, opt := range opts {
+		opt(&m)
+	}
+	s := &OnePermMinHash{
+		HashValues: make([]uint32, k),
+		Seed:       seed,
+		hasher:     m.hasher,
+		itemKey:    mix.Mix64(uint64(seed)),
+		binKey:     mix.Mix64(^uint64(seed)),
+	}
+	s.Clear()
+	return s, nil
+}
+
+// Clear sets the OnePermMinHash back to initial state
+func (sig *OnePermMinHash) Clear() {
+	for i := range sig.HashValues {
+		sig.HashValues[i] = math.MaxUint32
+	}
+}
+
+// Digest consumes a 32-bit hash, and retains it in its bin if it is the
+// minimum of the bin.
+func (sig *OnePermMinHash) Digest(item Hash32) {
+	sig.digest(item.Sum32())
+}
+
+// DigestBytes hashes data and digests the hash.
+func (sig *OnePermMinHash) DigestBytes(data []byte) {
+	sig.digest(sig.hasher.Sum32(data))
+}
+
+// DigestString hashes the bytes of s and digests the hash.
+func (sig *OnePermMinHash) DigestString(s string) {
+	sig.digest(sig.hasher.Sum32String(s))
+}
+
+// DigestUint64 hashes the little-endian encoding of v and digests the hash.
+func (sig *OnePermMinHash) DigestUint64(v uint64) {
+	sig.digest(sig.hasher.Sum32Uint64(v))
+}
+
+func (sig *OnePermMinHash) digest(hv uint32) {
+	// Mix64 is a bijection, so distinct hashes never share both their bin
+	// and their value.
+	h := mix.Mix64(uint64(hv) ^ sig.itemKey)
+	bin := (h >> 32) * uint64(len(sig.HashValues)) >> 32
+	if v := uint32(h); v < sig.HashValues[bin] {
+		sig.HashValues[bin] = v
+	}
+}
+
+// Signature returns the densified signature: every empty bin takes the
+// value of the first non-empty bin of its own pseudo-random probe
+// sequence. Signatures with the same seed and number of bins are compared
+// position by position, like MinHash hash values.
+func (sig *OnePermMinHash) Signature() []uint32 {
+	k := uint64(len(sig.HashValues))
+	out := make([]uint32, k)
+	copy(out, sig.HashValues)
+	empty := 0
+	for _, v := range sig.HashValues {
+		if v == math.MaxUint32 {
+			empty++
+		}
+	}
+	if empty == 0 || empty == len(out) {
+		return out
+	}
+	for i, v := range sig.HashValues {
+		if v != math.MaxUint32 {
+			continue
+		}
+		for attempt := uint64(0); ; attempt++ {
+			h := mix.Mix64(sig.binKey + uint64(i)<<32 + attempt)
+			if u := sig.HashValues[(h>>32)*k>>32]; u != math.MaxUint32 {
+				out[i] = u
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Merge takes another OnePermMinHash and combines it with sig, making sig
+// the union of both.
+func (sig *OnePermMinHash) Merge(other *OnePermMinHash) error {
+	if sig.Seed != other.Seed {
+		return &SeedMismatchError{sig.Seed, other.Seed}
+	}
+	if len(sig.HashValues) != len(other.HashValues) {
+		return &SizeMismatchError{len(sig.HashValues), len(other.HashValues)}
+	}
+	for i, v := range other.HashValues {
+		if v < sig.HashValues[i] {
+			sig.HashValues[i] = v
+		}
+	}
+	return nil
+}
+
+// Jaccard estimates the Jaccard similarity between sig and other.
+func (sig *OnePermMinHash) Jaccard(other *OnePermMinHash) (float64, error) {
+	return JaccardOnePerm(sig, other)
+}
+
+// JaccardOnePerm computes the estimation of Jaccard Similarity among
+// OnePermMinHash signatures.
+func JaccardOnePerm(sigs ...*OnePermMinHash) (float64, error) {
+	if len(sigs) < 2 {
+		return 0.0, ErrTooFewSignatures
+	}
+	k := len(sigs[0].HashValues)
+	for _, sig := range sigs[1:] {
+		if sigs[0].Seed != sig.Seed {
+			return 0.0, &SeedMismatchError{sigs[0].Seed, sig.Seed}
+		}
+		if k != len(sig.HashValues) {
+			return 0.0, &SizeMismatchError{k, len(sig.HashValues)}
+		}
+	}
+	first := sigs[0].Signature()
+	agree := make([]bool, k)
+	for i := range agree {
+		agree[i] = true
+	}
+	for _, sig := range sigs[1:] {
+		for i, v := range sig.Signature() {
+			if v != first[i] {
+				agree[i] = false
+			}
+		}
+	}
+	intersection := 0
+	for _, a := range agree {
+		if a {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(k), nil
+}
+
+// ByteSize returns the size of the serialized object.
+func (sig *OnePermMinHash) ByteSize() int {
+	return 8 + 4 + 4*len(sig.HashValues)
+}
+
+// Serialize the OnePermMinHash signature to bytes stored in buffer. The
+// bins are stored before densification, so the result can still be
+// merged.
+func (sig *OnePermMinHash) Serialize(buffer []byte) error {
+	if len(buffer) < sig.ByteSize() {
+		return shortBuffer("OnePermMinHash", sig.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint64(buffer, uint64(sig.Seed))
+	b.PutUint32(buffer[8:], uint32(len(sig.HashValues)))
+	offset := 8 + 4
+	for _, v := range sig.HashValues {
+		b.PutUint32(buffer[offset:], v)
+		offset += 4
+	}
+	return nil
+}
+
+// DeserializeOnePerm reconstructs a OnePermMinHash signature from the
+// buffer
+func DeserializeOnePerm(buffer []byte, opts ...Option) (*OnePermMinHash, error) {
+	if len(buffer) < 12 {
+		return nil, shortBuffer("OnePermMinHash", 12, len(buffer))
+	}
+	b := binary.LittleEndian
+	seed := int64(b.Uint64(buffer))
+	k := b.Uint32(buffer[8:])
+	offset := 12
+	if k == 0 || k > MaxNumPerm {
+		return nil, corrupt("OnePermMinHash",
+			fmt.Sprintf("number of bins %d is not between 1 and %d", k,
+				MaxNumPerm))
+	}
+	if need := uint64(offset) + 4*uint64(k); uint64(len(buffer)) < need {
+		return nil, shortBuffer("OnePermMinHash", int(need), len(buffer))
+	}
+	m, err := NewOnePerm(int(k), seed, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range m.HashValues {
+		m.HashValues[i] = b.Uint32(buffer[offset:])
+		offset += 4
+	}
+	return m, nil
+}
@@ -0,0 +1,180 @@
+package minhash
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestOnePermMinHash(t *testing.T) {
+	m1, _ := NewOnePerm(128, 1)
+	m2, _ := NewOnePerm(128, 1)
+	m1.Digest(fakeHash32(0x00010fff))
+	m2.Digest(fakeHash32(0x00010fff))
+	if est, _ := JaccardOnePerm(m1, m2); est != 1.0 {
+		t.Error(est)
+	}
+	// A single item fills every bin through densification.
+	for i, v := range m1.Signature() {
+		if v == math.MaxUint32 {
+			t.Fatalf("bin %d left empty", i)
+		}
+	}
+
+	m3, _ := NewOnePerm(128, 1)
+	m3.Digest(fakeHash32(0x00010fff))
+	m2.Digest(fakeHash32(0x01001fff))
+	if est, _ := JaccardOnePerm(m1, m2, m3); est == 1.0 {
+		t.Error(est)
+	}
+	m1.Clear()
+	if est, _ := m1.Jaccard(m3); est != 0.0 {
+		t.Error(est)
+	}
+}
+
+func TestOnePermMinHashUnbiased(t *testing.T) {
+	// Sets smaller than the number of bins rely on densification.
+	const k, trials = 256, 200
+	for _, n := range []int{20, 200, 2000} {
+		for _, c := range []int{0, n / 10, n / 2, n} {
+			j := float64(c) / float64(2*n-c)
+			var sum float64
+			for trial := 0; trial < trials; trial++ {
+				m1, _ := NewOnePerm(k, int64(trial))
+				m2, _ := NewOnePerm(k, int64(trial))
+				for i := 0; i < n; i++ {
+					m1.DigestUint64(uint64(trial)<<32 | uint64(i))
+					m2.DigestUint64(uint64(trial)<<32 | uint64(n-c+i))
+				}
+				est, _ := m1.Jaccard(m2)
+				sum += est
+			}
+			mean := sum / trials
+			// The bins of a densified signature are not independent, so
+			// allow more than the 4 standard deviations used for MinHash.
+			sigma := math.Sqrt(j * (1 - j) / (k * trials))
+			if math.Abs(mean-j) > 6*sigma+1e-9 {
+				t.Errorf("n=%d, c=%d: mean estimate %.4f (want %.4f +/- %.4f)",
+					n, c, mean, j, 6*sigma)
+			}
+		}
+	}
+}
+
+func TestOnePermMinHashMerge(t *testing.T) {
+	m1, _ := NewOnePerm(64, 1)
+	m2, _ := NewOnePerm(64, 1)
+	u, _ := NewOnePerm(64, 1)
+	for i := uint64(0); i < 100; i++ {
+		m1.DigestUint64(i)
+		m2.DigestUint64(i + 1000)
+		u.DigestUint64(i)
+		u.DigestUint64(i + 1000)
+	}
+	if err := m1.Merge(m2); err != nil {
+		t.Fatal(err)
+	}
+	if est, _ := m1.Jaccard(u); est != 1.0 {
+		t.Error(est)
+	}
+
+	m3, _ := NewOnePerm(64, 2)
+	if err := m1.Merge(m3); !errors.Is(err, ErrSeedMismatch) {
+		t.Error(err)
+	}
+	m4, _ := NewOnePerm(32, 1)
+	var sizeErr *SizeMismatchError
+	if _, err := m1.Jaccard(m4); !errors.As(err, &sizeErr) ||
+		sizeErr.Size != 64 || sizeErr.OtherSize != 32 {
+		t.Error(err)
+	}
+	if _, err := JaccardOnePerm(m1); !errors.Is(err, ErrTooFewSignatures) {
+		t.Error(err)
+	}
+	if _, err := NewOnePerm(0, 1); !errors.Is(err, ErrNumPerm) {
+		t.Error(err)
+	}
+}
+
+func TestOnePermMinHashSerialization(t *testing.T) {
+	m, _ := NewOnePerm(16, 1)
+	m.DigestString("hello")
+	m.DigestString("world")
+	buf := make([]byte, m.ByteSize())
+	if err := m.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeOnePerm(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Seed != m.Seed {
+		t.Error("Did not get back the same seed")
+	}
+	if est, _ := m.Jaccard(d); est != 1.0 {
+		t.Error(est)
+	}
+	if err := m.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := DeserializeOnePerm(buf[:len(buf)-1]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[8], buf[9], buf[10], buf[11] = 0, 0, 0, 0
+	if _, err := DeserializeOnePerm(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+
+	v, err := m.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s OnePermMinHash
+	if err := s.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if est, _ := m.Jaccard(&s); est != 1.0 {
+		t.Error(est)
+	}
+	mh, _ := New(4, 1)
+	v, _ = mh.Value()
+	if err := s.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserializeOnePerm(f *testing.F) {
+	m, _ := NewOnePerm(4, 1)
+	m.Digest(fakeHash32(0x00010fff))
+	buf := make([]byte, m.ByteSize())
+	m.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:12])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := DeserializeOnePerm(data)
+		if err != nil {
+			return
+		}
+		m.Signature()
+		out := make([]byte, m.ByteSize())
+		if err := m.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
+
+func BenchmarkOnePermDigest(b *testing.B) {
+	m, _ := NewOnePerm(128, 1)
+	hvs := benchmarkHashes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, hv := range hvs {
+			m.Digest(fakeHash32(hv))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package minhash
+
+import (
+	"math/bits"
+	"math/rand"
+)
+
+// Version identifies the family of permutations a MinHash draws from.
+// Signatures are only comparable when built with the same version.
+type Version uint8
+
+const (
+	// Version1 permutes hashes with a*x+b mod 2^32, where a and b are 32
+	// bits. The product overflows before any reduction by the Mersenne
+	// prime, so the family is not universal: with an even a, hashes
+	// differing only in their top bit always collide. It is kept so
+	// signatures serialized by earlier releases stay usable.
+	Version1 Version = iota + 1
+	// Version2 permutes hashes with (a*x+b mod 2^61-1) mod 2^32, where a
+	// and b are drawn below 2^61-1 and the arithmetic is done in 64 bits.
+	// This is the default.
+	Version2
+)
+
+// MaxNumPerm is the largest number of permutations of a signature. The
+// serialized number of permutations shares its 32 bits with the version.
+const MaxNumPerm = 1<<24 - 1
+
+const (
+	mersennePrime = (1 << 61) - 1
+)
+
+// http://en.wikipedia.org/wiki/Universal_hashing
+type permutation func(uint32) uint32
+
+// createPermutation returns a Version1 permutation. a*x+b is evaluated in
+// 32 bits, so both reductions are the identity.
+func createPermutation(a, b uint32, p uint64, m int) permutation {
+	return func(x uint32) uint32 {
+		return uint32((uint(a*x+b) % uint(p)) % uint(m))
+	}
+}
+
+// createPermutation61 returns a Version2 permutation.
+func createPermutation61(a, b uint64) permutation {
+	return func(x uint32) uint32 {
+		return uint32(mulAddMod61(a, x, b))
+	}
+}
+
+// mulAddMod61 returns (a*x+b) mod 2^61-1 for a and b below 2^61-1. The
+// 93 bits product is folded with 2^61 = 1 (mod 2^61-1) instead of being
+// divided.
+func mulAddMod61(a uint64, x uint32, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, uint64(x))
+	v := (lo & mersennePrime) + (lo>>61 | hi<<3) + b
+	v = (v & mersennePrime) + v>>61
+	if v >= mersennePrime {
+		v -= mersennePrime
+	}
+	return v
+}
+
+// coefficients draws the coefficients of numPerm permutations of version
+// from seed.
+func coefficients(version Version, seed int64, numPerm int) (a, b []uint64) {
+	// For Version1 this is the sequence earlier releases drew from the
+	// global source after seeding it.
+	r := rand.New(rand.NewSource(seed))
+	a = make([]uint64, numPerm)
+	b = make([]uint64, numPerm)
+	for i := range a {
+		if version == Version1 {
+			for a[i] == 0 {
+				a[i] = uint64(r.Uint32())
+			}
+			b[i] = uint64(r.Uint32())
+			continue
+		}
+		a[i] = uint64(r.Int63n(mersennePrime-1)) + 1
+		b[i] = uint64(r.Int63n(mersennePrime))
+	}
+	return a, b
+}
+
+// min32Block returns the minimum of m and the Version1 permutation of hvs.
+// The loop is split over four independent minima the CPU can compute in
+// parallel.
+func min32Block(a, b uint32, m uint32, hvs []uint32) uint32 {
+	m1, m2, m3 := ^uint32(0), ^uint32(0), ^uint32(0)
+	j := 0
+	for ; j+4 <= len(hvs); j += 4 {
+		x := hvs[j : j+4 : j+4]
+		m = min32(m, a*x[0]+b)
+		m1 = min32(m1, a*x[1]+b)
+		m2 = min32(m2, a*x[2]+b)
+		m3 = min32(m3, a*x[3]+b)
+	}
+	for ; j < len(hvs); j++ {
+		m = min32(m, a*hvs[j]+b)
+	}
+	return min32(min32(m, m1), min32(m2, m3))
+}
+
+// min61Block is min32Block for Version2 permutations, split over four
+// minima in the same way. Each step also costs less than mulAddMod61:
+// multiplying x by a<<3 rather than by a leaves (a*x) mod 2^61 and
+// (a*x)>>61 in the two words of the product without shifting them, and
+// only the low 32 bits of the result are computed.
+func min61Block(a, b uint64, m uint32, hvs []uint32) uint32 {
+	a8 := a << 3
+	m1, m2, m3 := ^uint32(0), ^uint32(0), ^uint32(0)
+	j := 0
+	for ; j+4 <= len(hvs); j += 4 {
+		x := hvs[j : j+4 : j+4]
+		m = min32(m, mulAddMod61Low32(a8, x[0], b))
+		m1 = min32(m1, mulAddMod61Low32(a8, x[1], b))
+		m2 = min32(m2, mulAddMod61Low32(a8, x[2], b))
+		m3 = min32(m3, mulAddMod61Low32(a8, x[3], b))
+	}
+	for ; j < len(hvs); j++ {
+		m = min32(m, mulAddMod61Low32(a8, hvs[j], b))
+	}
+	return min32(min32(m, m1), min32(m2, m3))
+}
+
+// mulAddMod61Low32 returns uint32(mulAddMod61(a8>>3, x, b)). v below is
+// congruent to the result and below 2^63, so r = (v&p)+v>>61 is below p+4,
+// and the low 32 bits of r are those of v plus v>>61. r is reduced unless
+// the low 61 bits of v are nearly all ones, in which case subtracting p
+// adds 1 to its low 32 bits, p being -1 mod 2^32.
+func mulAddMod61Low32(a8 uint64, x uint32, b uint64) uint32 {
+	hi, lo := bits.Mul64(a8, uint64(x))
+	v := lo>>3 + hi + b
+	r := uint32(v) + uint32(v>>61)
+	if v&mersennePrime >= mersennePrime-3 {
+		if v&mersennePrime+v>>61 >= mersennePrime {
+			r++
+		}
+	}
+	return r
+}
+
+func min32(x, y uint32) uint32 {
+	if y < x {
+		return y
+	}
+	return x
+}
@@ -0,0 +1,84 @@
+package minhash
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(MinHash)
+	_ driver.Valuer = new(MinHash)
+	_ sql.Scanner   = new(OneBitMinHash)
+	_ driver.Valuer = new(OneBitMinHash)
+	_ sql.Scanner   = new(OnePermMinHash)
+	_ driver.Valuer = new(OnePermMinHash)
+)
+
+// Value implements driver.Valuer so a MinHash can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized signature
+// preceded by a tag identifying it as a MinHash.
+func (sig *MinHash) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.MinHash, sig)
+}
+
+// Scan implements sql.Scanner, restoring a MinHash written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on sig, if any, is kept.
+func (sig *MinHash) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.MinHash, src)
+	if err != nil {
+		return err
+	}
+	m, err := Deserialize(buffer, WithHashFunc(sig.hasher.Func))
+	if err != nil {
+		return err
+	}
+	*sig = *m
+	return nil
+}
+
+// Value implements driver.Valuer so a OneBitMinHash can be stored in a
+// binary (BYTEA/BLOB) column.
+func (sig *OneBitMinHash) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.OneBitMinHash, sig)
+}
+
+// Scan implements sql.Scanner, restoring a OneBitMinHash written by Value.
+// Blobs holding any other kind of sketch are rejected.
+func (sig *OneBitMinHash) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.OneBitMinHash, src)
+	if err != nil {
+		return err
+	}
+	m, err := DeserializeOneBit(buffer)
+	if err != nil {
+		return err
+	}
+	*sig = *m
+	return nil
+}
+
+// Value implements driver.Valuer so a OnePermMinHash can be stored in a
+// binary (BYTEA/BLOB) column.
+func (sig *OnePermMinHash) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.OnePermMinHash, sig)
+}
+
+// Scan implements sql.Scanner, restoring a OnePermMinHash written by
+// Value. Blobs holding any other kind of sketch are rejected. The hash
+// function set on sig, if any, is kept.
+func (sig *OnePermMinHash) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.OnePermMinHash, src)
+	if err != nil {
+		return err
+	}
+	m, err := DeserializeOnePerm(buffer, WithHashFunc(sig.hasher.Func))
+	if err != nil {
+		return err
+	}
+	*sig = *m
+	return nil
+}
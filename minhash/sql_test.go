@@ -0,0 +1,72 @@
+package minhash
+
+import "testing"
+
+func TestMinHashValueScan(t *testing.T) {
+	m, _ := New(4, 1)
+	m.Digest(fakeHash32(0x00010fff))
+	m.Digest(fakeHash32(0x02010fff))
+	v, err := m.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d MinHash
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if est, _ := Jaccard(m, &d); est != 1.0 {
+		t.Error(est)
+	}
+}
+
+func TestOneBitMinHashValueScan(t *testing.T) {
+	m, _ := New(100, 1)
+	m.Digest(fakeHash32(0x00010fff))
+	m.Digest(fakeHash32(0x02010fff))
+	o := m.ExportOneBit()
+	v, err := o.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d OneBitMinHash
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Seed != o.Seed || d.Size != o.Size || d.BitArray.Cmp(o.BitArray) != 0 {
+		t.Error("Did not get back the same OneBitMinHash")
+	}
+}
+
+func TestMinHashScanError(t *testing.T) {
+	var m MinHash
+	if err := m.Scan(nil); err == nil {
+		t.Error("should return error when scanning NULL")
+	}
+	if err := m.Scan([]byte{}); err == nil {
+		t.Error("should return error when scanning an empty blob")
+	}
+	o, _ := New(4, 1)
+	v, _ := o.ExportOneBit().Value()
+	if err := m.Scan(v); err == nil {
+		t.Error("should return error when scanning a OneBitMinHash")
+	}
+	var ob OneBitMinHash
+	v, _ = o.Value()
+	if err := ob.Scan(v); err == nil {
+		t.Error("should return error when scanning a MinHash")
+	}
+}
+
+func FuzzScan(f *testing.F) {
+	m, _ := New(4, 1)
+	v, _ := m.Value()
+	f.Add(v.([]byte))
+	v, _ = m.ExportOneBit().Value()
+	f.Add(v.([]byte))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var sig MinHash
+		sig.Scan(data)
+		var ob OneBitMinHash
+		ob.Scan(data)
+	})
+}
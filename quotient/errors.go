@@ -0,0 +1,59 @@
+package quotient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrCapacity is returned when the number of items is 0, or so large
+	// that the filter would not fit in memory.
+	ErrCapacity = errors.New("quotient: number of items is out of range")
+	// ErrFPRate is returned when the false positive rate is not between 0
+	// and 1.
+	ErrFPRate = errors.New("quotient: false positive rate must be between " +
+		"0 and 1")
+	// ErrSize is returned when the quotient or remainder size is out of
+	// range.
+	ErrSize = errors.New("quotient: quotient and remainder bits are out " +
+		"of range")
+	// ErrFull is returned by Insert when the filter has no room left for
+	// the item and cannot grow.
+	ErrFull = errors.New("quotient: filter is full")
+	// ErrFingerprintMismatch is returned when merging a filter whose
+	// fingerprints are shorter. It is wrapped by FingerprintMismatchError.
+	ErrFingerprintMismatch = errors.New("quotient: fingerprints are too " +
+		"short")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+// FingerprintMismatchError reports the fingerprint sizes of two filters
+// that cannot be merged.
+type FingerprintMismatchError struct {
+	Bits      uint8
+	OtherBits uint8
+}
+
+func (e *FingerprintMismatchError) Error() string {
+	return fmt.Sprintf("quotient: fingerprints are too short: %d bits < %d",
+		e.OtherBits, e.Bits)
+}
+
+func (e *FingerprintMismatchError) Unwrap() error {
+	return ErrFingerprintMismatch
+}
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "QuotientFilter", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "QuotientFilter", Reason: reason}
+}
@@ -0,0 +1,527 @@
+// Package quotient implements the quotient filter, which tests whether an
+// item is in a set with no false negatives and a bounded rate of false
+// positives. It stores a p-bit fingerprint of every item in a compact hash
+// table, so unlike a Bloom filter it can grow, and filters can be merged
+// by reading their fingerprints back in order.
+//
+// Don't Thrash: How to Cache Your Hash on Flash:
+// https://www.vldb.org/pvldb/vol5/p1627_michaelabender_vldb2012.pdf
+package quotient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*QuotientFilter] = new(QuotientFilter)
+	_ datasketch.CardinalityEstimator       = new(QuotientFilter)
+	_ datasketch.Serializable               = new(QuotientFilter)
+)
+
+// HashFunc hashes data to the 64-bit hash whose high bits are the
+// fingerprint of an item.
+type HashFunc func(data []byte) uint64
+
+const (
+	// maxQ bounds the number of slots.
+	maxQ = 32
+	// maxR bounds the size of the remainders so that a slot fits in a
+	// word.
+	maxR = 64 - metadataBits - 1
+	// maxBits bounds the size of the table so that the serialized filter
+	// fits in memory.
+	maxBits = 1 << 36
+	// maxLoad is the load factor beyond which a filter grows, as runs of
+	// slots to scan get long.
+	maxLoad = 0.75
+)
+
+// Metadata bits of a slot, below its remainder.
+const (
+	// occupied is set on slot i if some fingerprint has quotient i.
+	occupied = 1 << iota
+	// continuation is set on the remainders that are not the first of
+	// their run.
+	continuation
+	// shifted is set on the remainders that are not in their canonical
+	// slot.
+	shifted
+	metadataBits = 3
+)
+
+// QuotientFilter data structure. The fingerprint of an item is the top
+// Q+R bits of its hash. Its Q high bits, the quotient, give its canonical
+// slot among 2^Q, and Slots holds its R low bits, the remainder, with 3
+// bits of metadata per slot. Remainders with the same quotient are sorted
+// in a run, which starts in their canonical slot or is shifted after the
+// runs of the preceding quotients.
+type QuotientFilter struct {
+	Slots []uint64
+	Q, R  uint8
+	// N is the number of distinct fingerprints in the filter.
+	N uint64
+
+	hash HashFunc
+}
+
+// Option configures a QuotientFilter created by one of the New functions
+// or deserialized.
+type Option func(*QuotientFilter)
+
+// WithHashFunc makes QuotientFilter hash items with f instead of
+// murmur3.Sum64. Filters are only comparable when their items were hashed
+// with the same function.
+func WithHashFunc(f HashFunc) Option {
+	return func(q *QuotientFilter) {
+		q.hash = f
+	}
+}
+
+// WithKey makes QuotientFilter hash items with SipHash keyed with the
+// secret k0 and k1, so an attacker who controls the items cannot choose
+// ones that are false positives or that fill a run. Filters are only
+// comparable when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed64(k0, k1))
+}
+
+// New returns a QuotientFilter whose false positive rate is below fpRate
+// with n items. It grows when more items are inserted, its false positive
+// rate growing in proportion, but its fingerprints keep their Q+R bits:
+// every doubling takes a bit from the remainders. It therefore holds at
+// most 2^(Q+R-1) fingerprints, with a false positive rate near 50%, and
+// Insert returns ErrFull beyond; for n = 1000 and fpRate = 0.01 the
+// fingerprints take 18 bits and the filter is full after about 180000
+// distinct items. Use NewWithSize with a larger r to leave room for more.
+func New(n uint64, fpRate float64, opts ...Option) (*QuotientFilter, error) {
+	if n == 0 {
+		return nil, ErrCapacity
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, ErrFPRate
+	}
+	q := math.Max(1, math.Ceil(math.Log2(float64(n)/maxLoad)))
+	r := math.Max(1, math.Ceil(-math.Log2(fpRate)))
+	if r > maxR {
+		return nil, ErrFPRate
+	}
+	if q > maxQ || q+r > 64 || math.Exp2(q)*(r+metadataBits) > maxBits {
+		return nil, ErrCapacity
+	}
+	return NewWithSize(uint8(q), uint8(r), opts...)
+}
+
+// NewWithSize returns a QuotientFilter of 2^q slots and fingerprints of
+// q+r bits, whose false positive rate is about n/2^(q+r) with n items.
+func NewWithSize(q, r uint8, opts ...Option) (*QuotientFilter, error) {
+	if !validSize(q, r) {
+		return nil, ErrSize
+	}
+	f := &QuotientFilter{Q: q, R: r}
+	f.Slots = make([]uint64, (uint64(r+metadataBits)<<q+63)/64)
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
+}
+
+// validSize reports whether q and r are in range.
+func validSize(q, r uint8) bool {
+	return q > 0 && q <= maxQ && r > 0 && r <= maxR && q+r <= 64 &&
+		uint64(r+metadataBits)<<q <= maxBits
+}
+
+// Clear sets QuotientFilter f back to its initial state.
+func (f *QuotientFilter) Clear() {
+	for i := range f.Slots {
+		f.Slots[i] = 0
+	}
+	f.N = 0
+}
+
+func (f *QuotientFilter) sum64(data []byte) uint64 {
+	if f.hash != nil {
+		return f.hash(data)
+	}
+	return murmur3.Sum64(data)
+}
+
+func (f *QuotientFilter) sum64String(s string) uint64 {
+	if f.hash != nil {
+		return f.hash(unsafe.Slice(unsafe.StringData(s), len(s)))
+	}
+	return murmur3.Sum64String(s, 0)
+}
+
+func (f *QuotientFilter) sum64Uint64(v uint64) uint64 {
+	if f.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return f.hash(b[:])
+	}
+	return murmur3.Sum64Uint64(v, 0)
+}
+
+// fingerprint returns the fingerprint of the item hashed to h.
+func (f *QuotientFilter) fingerprint(h uint64) uint64 {
+	return h >> (64 - f.Q - f.R)
+}
+
+// get returns slot i: its remainder followed by its metadata bits.
+func (f *QuotientFilter) get(i uint64) uint64 {
+	w := uint64(f.R + metadataBits)
+	pos := i * w
+	j, s := pos/64, pos%64
+	v := f.Slots[j] >> s
+	if s+w > 64 {
+		v |= f.Slots[j+1] << (64 - s)
+	}
+	return v & (1<<w - 1)
+}
+
+// set sets slot i to v.
+func (f *QuotientFilter) set(i, v uint64) {
+	w := uint64(f.R + metadataBits)
+	pos := i * w
+	j, s := pos/64, pos%64
+	mask := uint64(1)<<w - 1
+	f.Slots[j] = f.Slots[j]&^(mask<<s) | v<<s
+	if s+w > 64 {
+		f.Slots[j+1] = f.Slots[j+1]&^(mask>>(64-s)) | v>>(64-s)
+	}
+}
+
+func (f *QuotientFilter) next(i uint64) uint64 {
+	return (i + 1) & (1<<f.Q - 1)
+}
+
+func (f *QuotientFilter) prev(i uint64) uint64 {
+	return (i - 1) & (1<<f.Q - 1)
+}
+
+// runStart returns the slot of the first remainder of the run of
+// quotient fq, which must be occupied: the runs of the cluster of fq,
+// started at its last unshifted slot, are skipped until that of fq.
+func (f *QuotientFilter) runStart(fq uint64) uint64 {
+	b := fq
+	for f.get(b)&shifted != 0 {
+		b = f.prev(b)
+	}
+	s := b
+	for b != fq {
+		for s = f.next(s); f.get(s)&continuation != 0; s = f.next(s) {
+		}
+		for b = f.next(b); f.get(b)&occupied == 0; b = f.next(b) {
+		}
+	}
+	return s
+}
+
+// Insert adds item to the set. It returns ErrFull if there is no room
+// left and the filter cannot grow, the set then being unchanged.
+func (f *QuotientFilter) Insert(item []byte) error {
+	return f.insert(f.fingerprint(f.sum64(item)))
+}
+
+// InsertString adds the bytes of s to the set.
+func (f *QuotientFilter) InsertString(s string) error {
+	return f.insert(f.fingerprint(f.sum64String(s)))
+}
+
+// InsertUint64 adds the little-endian encoding of v to the set.
+func (f *QuotientFilter) InsertUint64(v uint64) error {
+	return f.insert(f.fingerprint(f.sum64Uint64(v)))
+}
+
+// Lookup reports whether item may be in the set. It is certainly not if
+// Lookup returns false.
+func (f *QuotientFilter) Lookup(item []byte) bool {
+	return f.lookup(f.fingerprint(f.sum64(item)))
+}
+
+// LookupString reports whether the bytes of s may be in the set.
+func (f *QuotientFilter) LookupString(s string) bool {
+	return f.lookup(f.fingerprint(f.sum64String(s)))
+}
+
+// LookupUint64 reports whether the little-endian encoding of v may be in
+// the set.
+func (f *QuotientFilter) LookupUint64(v uint64) bool {
+	return f.lookup(f.fingerprint(f.sum64Uint64(v)))
+}
+
+// insert adds fingerprint fp, growing the filter beyond maxLoad. One slot
+// is always left empty, so that every cluster ends.
+func (f *QuotientFilter) insert(fp uint64) error {
+	if float64(f.N+1) > maxLoad*math.Exp2(float64(f.Q)) {
+		if err := f.grow(); err != nil && f.N+1 >= 1<<f.Q {
+			if f.lookup(fp) {
+				return nil
+			}
+			return ErrFull
+		}
+	}
+	f.place(fp>>f.R, fp&(1<<f.R-1))
+	return nil
+}
+
+// place stores remainder fr in the run of quotient fq, unless it is
+// already there, shifting the following remainders of the cluster.
+func (f *QuotientFilter) place(fq, fr uint64) {
+	canonical := f.get(fq)
+	entry := fr << metadataBits
+	if canonical&(occupied|continuation|shifted) == 0 {
+		f.set(fq, entry|occupied)
+		f.N++
+		return
+	}
+	f.set(fq, canonical|occupied)
+	start := f.runStart(fq)
+	s := start
+	if canonical&occupied != 0 {
+		// Find where fr goes in the sorted run.
+		for {
+			rem := f.get(s) >> metadataBits
+			if rem == fr {
+				return
+			}
+			if rem > fr {
+				break
+			}
+			if s = f.next(s); f.get(s)&continuation == 0 {
+				break
+			}
+		}
+		if s == start {
+			// The old start of the run becomes a continuation.
+			f.set(start, f.get(start)|continuation)
+		} else {
+			entry |= continuation
+		}
+	}
+	if s != fq {
+		entry |= shifted
+	}
+	// Shift the remainders from s to the next empty slot. The occupied
+	// bits belong to the slots, not to the remainders, and stay in place.
+	for {
+		prev := f.get(s)
+		empty := prev&(occupied|continuation|shifted) == 0
+		if !empty {
+			prev |= shifted
+			if prev&occupied != 0 {
+				entry |= occupied
+				prev &^= occupied
+			}
+		}
+		f.set(s, entry)
+		if empty {
+			break
+		}
+		entry = prev
+		s = f.next(s)
+	}
+	f.N++
+}
+
+func (f *QuotientFilter) lookup(fp uint64) bool {
+	fq, fr := fp>>f.R, fp&(1<<f.R-1)
+	if f.get(fq)&occupied == 0 {
+		return false
+	}
+	s := f.runStart(fq)
+	for {
+		rem := f.get(s) >> metadataBits
+		if rem == fr {
+			return true
+		}
+		if rem > fr {
+			return false
+		}
+		if s = f.next(s); f.get(s)&continuation == 0 {
+			return false
+		}
+	}
+}
+
+// fingerprints calls fn with every fingerprint of the filter, after
+// checking that the metadata bits are consistent, so that no cluster
+// wraps around the table and the runs of every cluster match its
+// occupied bits.
+func (f *QuotientFilter) fingerprints(fn func(fp uint64)) error {
+	size := uint64(1) << f.Q
+	// Start after an empty slot, at the start of a cluster.
+	var start uint64
+	for ; start < size; start++ {
+		if f.get(start)&(occupied|continuation|shifted) == 0 {
+			break
+		}
+	}
+	if start == size {
+		return corrupt("no slot is empty")
+	}
+	// pending holds the occupied quotients whose run has not started.
+	var pending []uint64
+	var fq, prev uint64
+	for k := uint64(1); k <= size; k++ {
+		i := (start + k) & (size - 1)
+		v := f.get(i)
+		if v&(occupied|continuation|shifted) == 0 {
+			if len(pending) != 0 {
+				return corrupt(fmt.Sprintf("run of quotient %d is missing",
+					pending[0]))
+			}
+			continue
+		}
+		if v&occupied != 0 {
+			pending = append(pending, i)
+		}
+		rem := v >> metadataBits
+		if v&continuation != 0 {
+			if v&shifted == 0 || rem <= prev ||
+				f.get(f.prev(i))&(occupied|continuation|shifted) == 0 {
+				return corrupt(fmt.Sprintf("slot %d continues no run", i))
+			}
+		} else {
+			if len(pending) == 0 {
+				return corrupt(fmt.Sprintf("slot %d starts a run of no "+
+					"quotient", i))
+			}
+			fq, pending = pending[0], pending[1:]
+			if (fq != i) != (v&shifted != 0) {
+				return corrupt(fmt.Sprintf("slot %d has a wrong shifted bit",
+					i))
+			}
+		}
+		prev = rem
+		fn(fq<<f.R | rem)
+	}
+	return nil
+}
+
+// grow doubles the number of slots, taking a bit from the remainders so
+// that the fingerprints stay the same.
+func (f *QuotientFilter) grow() error {
+	if f.R == 1 {
+		return ErrFull
+	}
+	g, err := NewWithSize(f.Q+1, f.R-1, WithHashFunc(f.hash))
+	if err != nil {
+		return ErrFull
+	}
+	f.fingerprints(func(fp uint64) {
+		g.place(fp>>g.R, fp&(1<<g.R-1))
+	})
+	*f = *g
+	return nil
+}
+
+// Merge takes another QuotientFilter and adds its fingerprints to f,
+// making f the union of both sets. The fingerprints of other must be at
+// least as long as those of f, and are truncated to their length. If f
+// cannot grow enough, Merge returns ErrFull with only part of other
+// merged.
+func (f *QuotientFilter) Merge(other *QuotientFilter) error {
+	bits, otherBits := f.Q+f.R, other.Q+other.R
+	if otherBits < bits {
+		return &FingerprintMismatchError{bits, otherBits}
+	}
+	for float64(f.N+other.N) > maxLoad*math.Exp2(float64(f.Q)) {
+		if f.grow() != nil {
+			break
+		}
+	}
+	var err error
+	other.fingerprints(func(fp uint64) {
+		if err == nil {
+			err = f.insert(fp >> (otherBits - bits))
+		}
+	})
+	return err
+}
+
+// Count returns the number of distinct fingerprints in the filter, a
+// slight underestimate of the number of distinct items.
+func (f *QuotientFilter) Count() float64 {
+	return float64(f.N)
+}
+
+// LoadFactor returns the fraction of the slots that are used.
+func (f *QuotientFilter) LoadFactor() float64 {
+	return float64(f.N) / math.Exp2(float64(f.Q))
+}
+
+// FPRate returns the estimated false positive rate: the probability that
+// one of the N fingerprints is that of an item not in the set.
+func (f *QuotientFilter) FPRate() float64 {
+	return -math.Expm1(-float64(f.N) / math.Exp2(float64(f.Q+f.R)))
+}
+
+// headerSize is the size of the serialized QuotientFilter before its
+// slots.
+const headerSize = 1 + 1 + 8
+
+// ByteSize returns the size of the QuotientFilter f in bytes
+func (f *QuotientFilter) ByteSize() int {
+	return headerSize + 8*len(f.Slots)
+}
+
+// Serialize the QuotientFilter f into bytes and store in the buffer: Q,
+// R, N, then the slots in 64-bit little-endian words.
+func (f *QuotientFilter) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer(f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	buffer[0], buffer[1] = f.Q, f.R
+	b.PutUint64(buffer[2:], f.N)
+	for i, w := range f.Slots {
+		b.PutUint64(buffer[headerSize+8*i:], w)
+	}
+	return nil
+}
+
+// Deserialize reconstruct a QuotientFilter from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*QuotientFilter, error) {
+	if len(buffer) < headerSize {
+		return nil, shortBuffer(headerSize, len(buffer))
+	}
+	b := binary.LittleEndian
+	q, r := buffer[0], buffer[1]
+	if !validSize(q, r) {
+		return nil, corrupt(fmt.Sprintf("%d quotient and %d remainder bits "+
+			"is out of range", q, r))
+	}
+	bits := uint64(r+metadataBits) << q
+	if need := headerSize + 8*((bits+63)/64); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	f, err := NewWithSize(q, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	f.N = b.Uint64(buffer[2:])
+	for i := range f.Slots {
+		f.Slots[i] = b.Uint64(buffer[headerSize+8*i:])
+	}
+	if s := bits % 64; s != 0 && f.Slots[len(f.Slots)-1]>>s != 0 {
+		return nil, corrupt("bits beyond the last slot are set")
+	}
+	var n uint64
+	if err := f.fingerprints(func(uint64) { n++ }); err != nil {
+		return nil, err
+	}
+	if n != f.N {
+		return nil, corrupt(fmt.Sprintf("%d fingerprints are stored, not %d",
+			n, f.N))
+	}
+	return f, nil
+}
@@ -0,0 +1,292 @@
+package quotient
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestQuotientFilterNew(t *testing.T) {
+	f, err := New(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Q != 11 || f.R != 7 || len(f.Slots) != 320 {
+		t.Error(f.Q, f.R, len(f.Slots))
+	}
+	if _, err := New(0, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	if _, err := New(1<<40, 0.01); !errors.Is(err, ErrCapacity) {
+		t.Error(err)
+	}
+	for _, p := range []float64{0, 1, math.NaN(), 1e-30} {
+		if _, err := New(1000, p); !errors.Is(err, ErrFPRate) {
+			t.Error(p, err)
+		}
+	}
+	for _, s := range [][2]uint8{{0, 8}, {8, 0}, {33, 8}, {8, 61}, {4, 255}} {
+		if _, err := NewWithSize(s[0], s[1]); !errors.Is(err, ErrSize) {
+			t.Error(s, err)
+		}
+	}
+}
+
+// sortedFingerprints returns the fingerprints of f in increasing order.
+func sortedFingerprints(t *testing.T, f *QuotientFilter) []uint64 {
+	var fps []uint64
+	if err := f.fingerprints(func(fp uint64) {
+		fps = append(fps, fp)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(fps, func(i, j int) bool { return fps[i] < fps[j] })
+	return fps
+}
+
+func TestQuotientFilterLayout(t *testing.T) {
+	// Small tables make runs shift and wrap around the table.
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		f, _ := NewWithSize(4, 3)
+		want := make(map[uint64]bool)
+		for len(want) < 15 {
+			fp := r.Uint64() >> 57
+			f.place(fp>>f.R, fp&(1<<f.R-1))
+			want[fp] = true
+		}
+		if f.N != 15 {
+			t.Fatal(f.N)
+		}
+		fps := sortedFingerprints(t, f)
+		if len(fps) != len(want) {
+			t.Fatal(len(fps), len(want))
+		}
+		for _, fp := range fps {
+			if !want[fp] {
+				t.Fatalf("%x was not inserted", fp)
+			}
+			if !f.lookup(fp) {
+				t.Fatalf("%x is a false negative", fp)
+			}
+		}
+		for fp := uint64(0); fp < 1<<7; fp++ {
+			if f.lookup(fp) != want[fp] {
+				t.Fatalf("lookup of %x is %v", fp, !want[fp])
+			}
+		}
+	}
+}
+
+func TestQuotientFilterFPRate(t *testing.T) {
+	const n, p = 100000, 0.01
+	f, _ := New(n, p)
+	for i := uint64(0); i < n; i++ {
+		if err := f.InsertUint64(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.Q != 18 || f.N > n || f.N < n-300 {
+		t.Error(f.Q, f.N)
+	}
+	for i := uint64(0); i < n; i++ {
+		if !f.LookupUint64(i) {
+			t.Fatalf("%d is a false negative", i)
+		}
+	}
+	var fp int
+	for i := uint64(n); i < 11*n; i++ {
+		if f.LookupUint64(i) {
+			fp++
+		}
+	}
+	rate := float64(fp) / (10 * n)
+	if rate > p || math.Abs(rate-f.FPRate()) > 0.1*f.FPRate() {
+		t.Errorf("false positive rate %v (estimated %v)", rate, f.FPRate())
+	}
+	if l := f.LoadFactor(); l > maxLoad || l < 0.38 {
+		t.Error(l)
+	}
+}
+
+func TestQuotientFilterGrow(t *testing.T) {
+	f, _ := New(100, 0.001, WithKey(1, 2))
+	q, r := f.Q, f.R
+	for i := uint64(0); i < 10000; i++ {
+		if err := f.InsertUint64(i); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+	if f.Q != q+6 || f.R != r-6 || f.Count() < 9700 {
+		t.Error(f.Q, f.R, f.Count())
+	}
+	for i := uint64(0); i < 10000; i++ {
+		if !f.LookupUint64(i) {
+			t.Fatalf("%d is a false negative", i)
+		}
+	}
+
+	// Once the remainders cannot shrink, one slot is left empty.
+	g, _ := NewWithSize(3, 1)
+	var i uint64
+	for ; g.InsertUint64(i) == nil; i++ {
+	}
+	if g.Q != 3 || g.N != 7 {
+		t.Error(g.Q, g.N)
+	}
+	if !errors.Is(g.InsertUint64(i), ErrFull) {
+		t.Error("insertion in a full filter succeeded")
+	}
+	for j := uint64(0); j < i; j++ {
+		if g.InsertUint64(j) != nil {
+			t.Errorf("inserting %d again failed", j)
+		}
+	}
+
+	// The capacity stated by New: 2^17 fingerprints of 18 bits.
+	h, _ := New(1000, 0.01)
+	if h.Q+h.R != 18 {
+		t.Fatal(h.Q, h.R)
+	}
+	for i = 0; h.InsertUint64(i) == nil; i++ {
+	}
+	if h.Q != 17 || h.N != 1<<17-1 || i < 170000 || i > 190000 {
+		t.Error(h.Q, h.N, i)
+	}
+}
+
+func TestQuotientFilterTyped(t *testing.T) {
+	f, _ := New(100, 0.01)
+	f.Insert([]byte("hello"))
+	f.InsertString("hello")
+	if f.N != 1 || !f.LookupString("hello") || !f.Lookup([]byte("hello")) {
+		t.Error("hello is not in the set once")
+	}
+	if f.LookupString("world") {
+		t.Error("world is in the set")
+	}
+	f.InsertUint64(42)
+	if !f.Lookup([]byte{42, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Error("42 is not in the set")
+	}
+	f.Clear()
+	if f.N != 0 || f.LookupString("hello") {
+		t.Error("Clear did not reset the QuotientFilter")
+	}
+}
+
+func TestQuotientFilterMerge(t *testing.T) {
+	a, _ := New(1000, 0.001)
+	b, _ := New(3000, 0.0001)
+	all, _ := New(1000, 0.001)
+	for i := uint64(0); i < 1000; i++ {
+		a.InsertUint64(i)
+		all.InsertUint64(i)
+	}
+	for i := uint64(500); i < 3000; i++ {
+		b.InsertUint64(i)
+		all.InsertUint64(i)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	// a grows ahead of the merge as if the sets were disjoint.
+	if a.Q != all.Q+1 || a.N != all.N {
+		t.Error(a.Q, all.Q, a.N, all.N)
+	}
+	fps, allFps := sortedFingerprints(t, a), sortedFingerprints(t, all)
+	for i := range fps {
+		if fps[i] != allFps[i] {
+			t.Fatal("merge differs from inserting both sets")
+		}
+	}
+	var mismatch *FingerprintMismatchError
+	if err := b.Merge(a); !errors.As(err, &mismatch) ||
+		mismatch.OtherBits != a.Q+a.R {
+		t.Error(err)
+	}
+	if err := b.Merge(a); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Error(err)
+	}
+	full, _ := NewWithSize(2, 1)
+	other, _ := NewWithSize(4, 4)
+	for i := uint64(0); i < 10; i++ {
+		other.InsertUint64(i)
+	}
+	if err := full.Merge(other); !errors.Is(err, ErrFull) {
+		t.Error(err)
+	}
+}
+
+func TestQuotientFilterSerialization(t *testing.T) {
+	f, _ := New(100, 0.01)
+	for i := uint64(0); i < 80; i++ {
+		f.InsertUint64(i)
+	}
+	buf := make([]byte, f.ByteSize())
+	if err := f.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Q != f.Q || d.R != f.R || d.N != f.N || !d.LookupUint64(42) {
+		t.Error("Did not get back the same QuotientFilter")
+	}
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[2]++
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[2]--
+	for i := headerSize; i < len(buf); i++ {
+		buf[i] = 0xff
+	}
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	buf[1] = 62
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	q, _ := NewWithSize(4, 4)
+	for i := uint64(0); i < 12; i++ {
+		q.InsertUint64(i)
+	}
+	buf := make([]byte, q.ByteSize())
+	q.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		q, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, q.ByteSize())
+		if err := q.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		n := q.N
+		if q.InsertString("hello") == nil && !q.LookupString("hello") {
+			t.Error("hello is not in the set")
+		}
+		if q.N > n+1 {
+			t.Error(q.N, n)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package quotient
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(QuotientFilter)
+	_ driver.Valuer = new(QuotientFilter)
+)
+
+// Value implements driver.Valuer so a QuotientFilter can be stored in a
+// binary (BYTEA/BLOB) column. The stored value is the serialized filter
+// preceded by a tag identifying it as a QuotientFilter.
+func (f *QuotientFilter) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.QuotientFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring a QuotientFilter written by Value.
+// Blobs holding any other kind of sketch are rejected. The hash function
+// set on f, if any, is kept.
+func (f *QuotientFilter) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.QuotientFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(f.hash))
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
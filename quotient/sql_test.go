@@ -0,0 +1,36 @@
+package quotient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/cuckoo"
+)
+
+func TestQuotientFilterValueScan(t *testing.T) {
+	f, _ := New(100, 0.01)
+	f.InsertString("hello")
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d QuotientFilter
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Q != f.Q || d.R != f.R || !d.LookupString("hello") {
+		t.Error("Did not get back the same QuotientFilter")
+	}
+}
+
+func TestQuotientFilterScanError(t *testing.T) {
+	var q QuotientFilter
+	if err := q.Scan(nil); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+	c, _ := cuckoo.New(100)
+	v, _ := c.Value()
+	if err := q.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,37 @@
+package datasketch
+
+// Hash32 is a relaxed version of hash.Hash32. It is the input consumed by
+// the Digest method of every sketch.
+type Hash32 interface {
+	Sum32() uint32
+}
+
+// Digester is implemented by sketches that consume hashed items.
+type Digester interface {
+	Digest(item Hash32)
+}
+
+// Mergeable is implemented by sketches that can absorb another sketch of
+// the same type T, becoming the union of both.
+type Mergeable[T any] interface {
+	Merge(other T) error
+}
+
+// CardinalityEstimator is implemented by sketches that estimate the number
+// of distinct items digested.
+type CardinalityEstimator interface {
+	Count() float64
+}
+
+// SimilarityEstimator is implemented by sketches that estimate the Jaccard
+// similarity between the sets summarized by two sketches of type T.
+type SimilarityEstimator[T any] interface {
+	Jaccard(other T) (float64, error)
+}
+
+// Serializable is implemented by sketches with a binary representation.
+// ByteSize returns the number of bytes Serialize writes into buffer.
+type Serializable interface {
+	ByteSize() int
+	Serialize(buffer []byte) error
+}
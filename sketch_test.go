@@ -0,0 +1,61 @@
+package datasketch_test
+
+import (
+	"testing"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+	"github.com/ekzhu/go-datasketch/minhash"
+)
+
+type fakeHash32 uint32
+
+func (f fakeHash32) Sum32() uint32 { return uint32(f) }
+
+// union digests each batch into its own sketch and merges them into the
+// first, the way a generic aggregator would.
+func union[T interface {
+	datasketch.Digester
+	datasketch.Mergeable[T]
+}](sketches []T, batches [][]uint32) (T, error) {
+	for i, batch := range batches {
+		for _, v := range batch {
+			sketches[i].Digest(fakeHash32(v))
+		}
+	}
+	for _, s := range sketches[1:] {
+		if err := sketches[0].Merge(s); err != nil {
+			return sketches[0], err
+		}
+	}
+	return sketches[0], nil
+}
+
+func TestGenericUnion(t *testing.T) {
+	batches := [][]uint32{
+		{0x00010fff, 0x00020fff},
+		{0x00020fff, 0x00030fff},
+	}
+
+	h1, _ := hyperloglog.New(16)
+	h2, _ := hyperloglog.New(16)
+	h, err := union([]*hyperloglog.HyperLogLog{h1, h2}, batches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c datasketch.CardinalityEstimator = h
+	if n := c.Count(); int(n) != 3 {
+		t.Error(n)
+	}
+
+	m1, _ := minhash.New(128, 1)
+	m2, _ := minhash.New(128, 1)
+	m, err := union([]*minhash.MinHash{m1, m2}, batches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s datasketch.SimilarityEstimator[*minhash.MinHash] = m
+	if est, _ := s.Jaccard(m); est != 1.0 {
+		t.Error(est)
+	}
+}
@@ -0,0 +1,34 @@
+package tdigest
+
+import (
+	"errors"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrCompression is returned when the compression is not between
+	// MinCompression and MaxCompression.
+	ErrCompression = errors.New("tdigest: compression must be between 10 " +
+		"and 100000")
+	// ErrEmpty is returned when the quantiles of an empty TDigest are
+	// queried.
+	ErrEmpty = errors.New("tdigest: digest is empty")
+	// ErrQuantile is returned when a quantile is not between 0 and 1, or
+	// the bounds of a trimmed mean are not increasing.
+	ErrQuantile = errors.New("tdigest: quantile must be between 0 and 1")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "TDigest", Need: need,
+		Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "TDigest", Reason: reason}
+}
@@ -0,0 +1,36 @@
+package tdigest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(TDigest)
+	_ driver.Valuer = new(TDigest)
+)
+
+// Value implements driver.Valuer so a TDigest can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized TDigest
+// preceded by a tag identifying it as a TDigest.
+func (t *TDigest) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.TDigest, t)
+}
+
+// Scan implements sql.Scanner, restoring a TDigest written by Value.
+// Blobs holding any other kind of sketch are rejected.
+func (t *TDigest) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.TDigest, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer)
+	if err != nil {
+		return err
+	}
+	*t = *other
+	return nil
+}
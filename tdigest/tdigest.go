@@ -0,0 +1,409 @@
+// Package tdigest implements the merging t-digest, which estimates
+// quantiles of a stream of numbers. Its clusters, or centroids, are small
+// near the extreme quantiles, so tail quantiles such as p99.9 are far
+// more accurate than in sketches with a uniform rank error.
+//
+// Computing Extremely Accurate Quantiles Using t-Digests:
+// https://arxiv.org/pdf/1902.04023.pdf
+//
+// The implementation follows the MergingDigest of the reference code:
+// https://github.com/tdunning/t-digest
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Mergeable[*TDigest] = new(TDigest)
+	_ datasketch.Serializable        = new(TDigest)
+)
+
+const (
+	// DefaultCompression keeps about 100 centroids and estimates the
+	// median within about 1% and p99.9 within about 0.01% of the rank.
+	DefaultCompression = 100
+	// MinCompression and MaxCompression bound the compression.
+	MinCompression = 10
+	MaxCompression = 100000
+)
+
+// Centroid is a cluster of values, summarized by their mean and total
+// weight.
+type Centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+// TDigest data structure. Values are buffered and merged into the
+// centroids when the buffer fills up. A centroid at quantile q holds at
+// most about q(1-q)*Compression/(4*log(N/Compression)+24) of the total
+// weight, so the centroids at the extremes hold single values.
+//
+// Quantile, CDF and the other methods reading the centroids first merge
+// the buffered values into them, so they modify the TDigest: like Add,
+// they must not be called concurrently with any other method.
+type TDigest struct {
+	Compression float64
+	// N is the total weight of the values.
+	N float64
+	// Min and Max are the smallest and largest values, valid if N > 0.
+	Min, Max float64
+
+	centroids []Centroid
+	buffer    []Centroid
+	// scratch is reused by flush to avoid allocations.
+	scratch []Centroid
+}
+
+// New returns an empty TDigest. Its size and accuracy grow with
+// compression, usually DefaultCompression.
+func New(compression float64) (*TDigest, error) {
+	if !(compression >= MinCompression && compression <= MaxCompression) {
+		return nil, ErrCompression
+	}
+	return &TDigest{
+		Compression: compression,
+		buffer:      make([]Centroid, 0, int(5*compression)),
+	}, nil
+}
+
+// Clear sets the TDigest back to its initial state.
+func (t *TDigest) Clear() {
+	t.N, t.Min, t.Max = 0, 0, 0
+	t.centroids = t.centroids[:0]
+	t.buffer = t.buffer[:0]
+}
+
+// Add adds the value x. NaNs and infinities are ignored.
+func (t *TDigest) Add(x float64) {
+	t.AddWeighted(x, 1)
+}
+
+// AddWeighted adds the value x with weight w, as if x were added w times.
+// NaNs and infinities, and weights that are not positive and finite, are
+// ignored.
+func (t *TDigest) AddWeighted(x, w float64) {
+	if math.IsNaN(x) || math.IsInf(x, 0) || !(w > 0) || math.IsInf(w, 0) {
+		return
+	}
+	if t.N == 0 || x < t.Min {
+		t.Min = x
+	}
+	if t.N == 0 || x > t.Max {
+		t.Max = x
+	}
+	t.N += w
+	t.buffer = append(t.buffer, Centroid{x, w})
+	if len(t.buffer) >= int(5*t.Compression) {
+		t.flush()
+	}
+}
+
+// flush merges the buffered values into the centroids: all of them are
+// sorted by mean, and each centroid absorbs the following ones as long as
+// it stays within the size allowed at both of its ends.
+func (t *TDigest) flush() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.scratch[:0], t.centroids...)
+	all = append(all, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Mean < all[j].Mean })
+
+	// The k2 scale function k(q) = log(q/(1-q))/normalizer allows a
+	// centroid at quantile q to hold q(1-q)/normalizer of the weight.
+	normalizer := t.Compression /
+		(4*math.Log(math.Max(t.N/t.Compression, 1)) + 24)
+	out := t.centroids[:0]
+	cur := all[0]
+	var weightSoFar float64
+	for _, c := range all[1:] {
+		proposed := cur.Weight + c.Weight
+		q0 := weightSoFar / t.N
+		q2 := (weightSoFar + proposed) / t.N
+		if proposed <= t.N*math.Min(q0*(1-q0), q2*(1-q2))/normalizer {
+			cur.Weight = proposed
+			cur.Mean += (c.Mean - cur.Mean) * c.Weight / cur.Weight
+			continue
+		}
+		weightSoFar += cur.Weight
+		out = append(out, cur)
+		cur = c
+	}
+	t.centroids = append(out, cur)
+	t.scratch = all
+	t.buffer = t.buffer[:0]
+}
+
+// Centroids returns a copy of the centroids, in increasing order of mean.
+func (t *TDigest) Centroids() []Centroid {
+	t.flush()
+	return append([]Centroid(nil), t.centroids...)
+}
+
+// Quantile returns the estimated q-quantile, interpolating between the
+// means of the centroids. Centroids of weight 1 are exact values.
+func (t *TDigest) Quantile(q float64) (float64, error) {
+	if !(q >= 0 && q <= 1) {
+		return 0, ErrQuantile
+	}
+	if t.N == 0 {
+		return 0, ErrEmpty
+	}
+	t.flush()
+	cs := t.centroids
+	if len(cs) == 1 {
+		return cs[0].Mean, nil
+	}
+	index := q * t.N
+	if index < 1 {
+		return t.Min, nil
+	}
+	if index > t.N-1 {
+		return t.Max, nil
+	}
+	// Between Min and the first mean, half of the first centroid's values.
+	first := cs[0]
+	if first.Weight > 2 && index < first.Weight/2 {
+		return t.Min + (index-1)/(first.Weight/2-1)*(first.Mean-t.Min), nil
+	}
+	last := cs[len(cs)-1]
+	if last.Weight > 2 && index > t.N-last.Weight/2 {
+		return t.Max - (t.N-1-index)/(last.Weight/2-1)*(t.Max-last.Mean), nil
+	}
+	weightSoFar := first.Weight / 2
+	for i := 0; i < len(cs)-1; i++ {
+		dw := (cs[i].Weight + cs[i+1].Weight) / 2
+		if weightSoFar+dw <= index {
+			weightSoFar += dw
+			continue
+		}
+		var leftUnit, rightUnit float64
+		if cs[i].Weight == 1 {
+			if index-weightSoFar < 0.5 {
+				return cs[i].Mean, nil
+			}
+			leftUnit = 0.5
+		}
+		if cs[i+1].Weight == 1 {
+			if weightSoFar+dw-index <= 0.5 {
+				return cs[i+1].Mean, nil
+			}
+			rightUnit = 0.5
+		}
+		z1 := index - weightSoFar - leftUnit
+		z2 := weightSoFar + dw - index - rightUnit
+		return weightedAverage(cs[i].Mean, z2, cs[i+1].Mean, z1), nil
+	}
+	return last.Mean, nil
+}
+
+// weightedAverage returns the average of x1 and x2 weighted by w1 and w2,
+// kept between them despite rounding.
+func weightedAverage(x1, w1, x2, w2 float64) float64 {
+	if w1+w2 <= 0 {
+		return x1
+	}
+	x := (x1*w1 + x2*w2) / (w1 + w2)
+	return math.Max(math.Min(x1, x2), math.Min(x, math.Max(x1, x2)))
+}
+
+// CDF returns the estimated fraction of the values not greater than x, or
+// NaN if the TDigest is empty.
+func (t *TDigest) CDF(x float64) float64 {
+	if t.N == 0 || math.IsNaN(x) {
+		return math.NaN()
+	}
+	if x < t.Min {
+		return 0
+	}
+	if x >= t.Max {
+		return 1
+	}
+	t.flush()
+	cs := t.centroids
+	if len(cs) == 1 {
+		return (x - t.Min) / (t.Max - t.Min)
+	}
+	first, last := cs[0], cs[len(cs)-1]
+	if x < first.Mean {
+		// Min is one value, the others up to the first mean are spread
+		// evenly.
+		w := 1 + (x-t.Min)/(first.Mean-t.Min)*(first.Weight/2-1)
+		return clamp(w / t.N)
+	}
+	if x >= last.Mean {
+		w := 1 + (t.Max-x)/(t.Max-last.Mean)*(last.Weight/2-1)
+		return clamp(1 - w/t.N)
+	}
+	weightSoFar := first.Weight / 2
+	for i := 0; i < len(cs)-1; i++ {
+		dw := (cs[i].Weight + cs[i+1].Weight) / 2
+		if x >= cs[i+1].Mean {
+			weightSoFar += dw
+			continue
+		}
+		var leftUnit, rightUnit float64
+		if cs[i].Weight == 1 {
+			leftUnit = 0.5
+		}
+		if cs[i+1].Weight == 1 {
+			rightUnit = 0.5
+		}
+		dw -= leftUnit + rightUnit
+		frac := (x - cs[i].Mean) / (cs[i+1].Mean - cs[i].Mean)
+		return clamp((weightSoFar + leftUnit + dw*frac) / t.N)
+	}
+	return 1
+}
+
+// clamp returns q kept between 0 and 1 despite rounding and fractional
+// weights.
+func clamp(q float64) float64 {
+	return math.Max(0, math.Min(q, 1))
+}
+
+// TrimmedMean returns the estimated mean of the values between the lo and
+// hi quantiles, e.g. TrimmedMean(0.01, 0.99) leaves out the 1% smallest
+// and largest values.
+func (t *TDigest) TrimmedMean(lo, hi float64) (float64, error) {
+	if !(lo >= 0 && lo < hi && hi <= 1) {
+		return 0, ErrQuantile
+	}
+	if t.N == 0 {
+		return 0, ErrEmpty
+	}
+	t.flush()
+	lower, upper := lo*t.N, hi*t.N
+	var sum, weight, weightSoFar float64
+	for _, c := range t.centroids {
+		start := weightSoFar
+		weightSoFar += c.Weight
+		overlap := math.Min(weightSoFar, upper) - math.Max(start, lower)
+		if overlap > 0 {
+			sum += c.Mean * overlap
+			weight += overlap
+		}
+	}
+	if weight == 0 {
+		return t.Quantile(lo)
+	}
+	return sum / weight, nil
+}
+
+// Merge takes another TDigest and combines it with t, making t the
+// summary of both streams. t keeps its Compression.
+func (t *TDigest) Merge(other *TDigest) error {
+	if other.N == 0 {
+		return nil
+	}
+	if t.N == 0 || other.Min < t.Min {
+		t.Min = other.Min
+	}
+	if t.N == 0 || other.Max > t.Max {
+		t.Max = other.Max
+	}
+	t.N += other.N
+	incoming := make([]Centroid, 0, len(other.centroids)+len(other.buffer))
+	incoming = append(incoming, other.centroids...)
+	incoming = append(incoming, other.buffer...)
+	t.buffer = append(t.buffer, incoming...)
+	t.flush()
+	return nil
+}
+
+// headerSize is the size of the serialized TDigest before its centroids.
+const headerSize = 8 + 4 + 8 + 8
+
+// ByteSize returns the size of the serialized object. It merges the
+// buffered values into the centroids.
+func (t *TDigest) ByteSize() int {
+	t.flush()
+	return headerSize + 16*len(t.centroids)
+}
+
+// Serialize the TDigest to bytes stored in buffer: Compression, the
+// number of centroids, Min, Max, then the mean and weight of every
+// centroid. It merges the buffered values into the centroids.
+func (t *TDigest) Serialize(buffer []byte) error {
+	if len(buffer) < t.ByteSize() {
+		return shortBuffer(t.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint64(buffer, math.Float64bits(t.Compression))
+	b.PutUint32(buffer[8:], uint32(len(t.centroids)))
+	b.PutUint64(buffer[12:], math.Float64bits(t.Min))
+	b.PutUint64(buffer[20:], math.Float64bits(t.Max))
+	offset := headerSize
+	for _, c := range t.centroids {
+		b.PutUint64(buffer[offset:], math.Float64bits(c.Mean))
+		b.PutUint64(buffer[offset+8:], math.Float64bits(c.Weight))
+		offset += 16
+	}
+	return nil
+}
+
+// Deserialize reconstructs a TDigest from the buffer
+func Deserialize(buffer []byte) (*TDigest, error) {
+	if len(buffer) < headerSize {
+		return nil, shortBuffer(headerSize, len(buffer))
+	}
+	b := binary.LittleEndian
+	compression := math.Float64frombits(b.Uint64(buffer))
+	if !(compression >= MinCompression && compression <= MaxCompression) {
+		return nil, corrupt(fmt.Sprintf("compression %v is not between %d "+
+			"and %d", compression, MinCompression, MaxCompression))
+	}
+	n := b.Uint32(buffer[8:])
+	if need := uint64(headerSize) + 16*uint64(n); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	t, err := New(compression)
+	if err != nil {
+		return nil, err
+	}
+	t.Min = math.Float64frombits(b.Uint64(buffer[12:]))
+	t.Max = math.Float64frombits(b.Uint64(buffer[20:]))
+	if n == 0 {
+		if t.Min != 0 || t.Max != 0 {
+			return nil, corrupt("an empty digest has a minimum or maximum")
+		}
+		return t, nil
+	}
+	if !(t.Min <= t.Max) || math.IsInf(t.Min, 0) || math.IsInf(t.Max, 0) {
+		return nil, corrupt("minimum and maximum are not finite and ordered")
+	}
+	t.centroids = make([]Centroid, n)
+	offset := headerSize
+	for i := range t.centroids {
+		c := Centroid{
+			Mean:   math.Float64frombits(b.Uint64(buffer[offset:])),
+			Weight: math.Float64frombits(b.Uint64(buffer[offset+8:])),
+		}
+		offset += 16
+		if !(c.Mean >= t.Min && c.Mean <= t.Max) {
+			return nil, corrupt(fmt.Sprintf("mean %v is outside of the "+
+				"minimum and maximum", c.Mean))
+		}
+		if i > 0 && c.Mean < t.centroids[i-1].Mean {
+			return nil, corrupt("centroids are out of order")
+		}
+		if !(c.Weight > 0) || math.IsInf(c.Weight, 0) {
+			return nil, corrupt(fmt.Sprintf("weight %v is not positive and "+
+				"finite", c.Weight))
+		}
+		t.centroids[i] = c
+		t.N += c.Weight
+	}
+	if math.IsInf(t.N, 0) {
+		return nil, corrupt("total weight is not finite")
+	}
+	return t, nil
+}
@@ -0,0 +1,274 @@
+package tdigest
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func stream(seed int64, n int) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = r.ExpFloat64()
+	}
+	return values
+}
+
+// rankOf returns the fraction of the sorted values not greater than x.
+func rankOf(sorted []float64, x float64) float64 {
+	return float64(sort.Search(len(sorted), func(i int) bool {
+		return sorted[i] > x
+	})) / float64(len(sorted))
+}
+
+func TestTDigestNew(t *testing.T) {
+	for _, c := range []float64{0, MinCompression - 1, MaxCompression + 1,
+		math.NaN()} {
+		if _, err := New(c); !errors.Is(err, ErrCompression) {
+			t.Error(c, err)
+		}
+	}
+	d, err := New(DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Quantile(0.5); !errors.Is(err, ErrEmpty) {
+		t.Error(err)
+	}
+	if _, err := d.TrimmedMean(0.1, 0.9); !errors.Is(err, ErrEmpty) {
+		t.Error(err)
+	}
+	if !math.IsNaN(d.CDF(1)) {
+		t.Error(d.CDF(1))
+	}
+}
+
+func TestTDigestSmall(t *testing.T) {
+	d, _ := New(DefaultCompression)
+	for i := 10; i >= 1; i-- {
+		d.Add(float64(i))
+	}
+	d.Add(math.NaN())
+	d.Add(math.Inf(1))
+	d.AddWeighted(5, 0)
+	if d.N != 10 || d.Min != 1 || d.Max != 10 {
+		t.Error(d.N, d.Min, d.Max)
+	}
+	// Few values are kept exactly.
+	for _, c := range []struct{ q, want float64 }{
+		{0, 1}, {0.05, 1}, {0.15, 2}, {0.55, 6}, {0.95, 10}, {1, 10},
+	} {
+		if got, err := d.Quantile(c.q); err != nil || got != c.want {
+			t.Error(c.q, got, err)
+		}
+	}
+	if c := d.CDF(0); c != 0 {
+		t.Error(c)
+	}
+	if c := d.CDF(10); c != 1 {
+		t.Error(c)
+	}
+	if c := d.CDF(5.5); c != 0.5 {
+		t.Error(c)
+	}
+	if m, err := d.TrimmedMean(0.1, 0.9); err != nil || m != 5.5 {
+		t.Error(m, err)
+	}
+	for _, q := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := d.Quantile(q); !errors.Is(err, ErrQuantile) {
+			t.Error(q, err)
+		}
+	}
+	if _, err := d.TrimmedMean(0.5, 0.5); !errors.Is(err, ErrQuantile) {
+		t.Error(err)
+	}
+	d.Clear()
+	if d.N != 0 || len(d.Centroids()) != 0 {
+		t.Error("Clear did not reset the TDigest")
+	}
+}
+
+func TestTDigestAccuracy(t *testing.T) {
+	values := stream(1, 1000000)
+	d, _ := New(DefaultCompression)
+	for _, v := range values {
+		d.Add(v)
+	}
+	sort.Float64s(values)
+	if d.N != float64(len(values)) || d.Min != values[0] ||
+		d.Max != values[len(values)-1] {
+		t.Error(d.N, d.Min, d.Max)
+	}
+	// DefaultCompression promises about 100 centroids, the median within
+	// 1% and p99.9 within 0.01% of the rank.
+	if n := len(d.Centroids()); n < DefaultCompression/2 ||
+		n > 3*DefaultCompression/2 {
+		t.Errorf("%d centroids", n)
+	}
+	// The rank error shrinks towards the tails.
+	for _, c := range []struct{ q, eps float64 }{
+		{0.001, 0.0001}, {0.01, 0.0005}, {0.5, 0.01}, {0.99, 0.0005},
+		{0.999, 0.0001},
+	} {
+		x, _ := d.Quantile(c.q)
+		if r := rankOf(values, x); math.Abs(r-c.q) > c.eps {
+			t.Errorf("quantile %v has rank %v", c.q, r)
+		}
+		if r := d.CDF(values[int(c.q*float64(len(values)))]); math.Abs(
+			r-c.q) > c.eps {
+			t.Errorf("CDF at quantile %v is %v", c.q, r)
+		}
+	}
+
+	var sum float64
+	for _, v := range values[10000:990000] {
+		sum += v
+	}
+	want := sum / 980000
+	if m, _ := d.TrimmedMean(0.01, 0.99); math.Abs(m-want) > 0.001*want {
+		t.Errorf("trimmed mean %v (want %v)", m, want)
+	}
+}
+
+func TestTDigestWeighted(t *testing.T) {
+	d, _ := New(DefaultCompression)
+	u, _ := New(DefaultCompression)
+	for i := 0; i < 1000; i++ {
+		d.AddWeighted(float64(i), 10)
+		for j := 0; j < 10; j++ {
+			u.Add(float64(i))
+		}
+	}
+	if d.N != u.N {
+		t.Error(d.N, u.N)
+	}
+	for _, q := range []float64{0.1, 0.5, 0.9} {
+		x, _ := d.Quantile(q)
+		y, _ := u.Quantile(q)
+		if math.Abs(x-y) > 10 {
+			t.Error(q, x, y)
+		}
+	}
+}
+
+func TestTDigestMerge(t *testing.T) {
+	values := stream(1, 200000)
+	// Per-host digests merged into one.
+	hosts := make([]*TDigest, 10)
+	for i := range hosts {
+		hosts[i], _ = New(DefaultCompression)
+	}
+	for i, v := range values {
+		hosts[i%len(hosts)].Add(v)
+	}
+	all, _ := New(DefaultCompression)
+	for _, h := range hosts {
+		if err := all.Merge(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Float64s(values)
+	if all.N != float64(len(values)) || all.Min != values[0] ||
+		all.Max != values[len(values)-1] {
+		t.Error(all.N, all.Min, all.Max)
+	}
+	for _, c := range []struct{ q, eps float64 }{
+		{0.5, 0.01}, {0.99, 0.001}, {0.999, 0.0002},
+	} {
+		x, _ := all.Quantile(c.q)
+		if r := rankOf(values, x); math.Abs(r-c.q) > c.eps {
+			t.Errorf("quantile %v has rank %v", c.q, r)
+		}
+	}
+
+	if err := all.Merge(all); err != nil {
+		t.Fatal(err)
+	}
+	if all.N != 2*float64(len(values)) {
+		t.Error(all.N)
+	}
+}
+
+func TestTDigestSerialization(t *testing.T) {
+	d, _ := New(DefaultCompression)
+	for _, v := range stream(1, 10000) {
+		d.Add(v)
+	}
+	buf := make([]byte, d.ByteSize())
+	if err := d.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	e, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Compression != d.Compression || e.N != d.N || e.Min != d.Min ||
+		e.Max != d.Max {
+		t.Error("Did not get back the same TDigest")
+	}
+	for _, q := range []float64{0.001, 0.5, 0.999} {
+		x, _ := d.Quantile(q)
+		y, _ := e.Quantile(q)
+		if x != y {
+			t.Error(q, x, y)
+		}
+	}
+
+	if err := d.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	// Swapping the first two centroids breaks their order.
+	bad := append([]byte(nil), buf...)
+	copy(bad[headerSize:], buf[headerSize+16:headerSize+32])
+	copy(bad[headerSize+16:], buf[headerSize:headerSize+16])
+	if _, err := Deserialize(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	bad = append([]byte(nil), buf...)
+	bad[7] = 0xff
+	if _, err := Deserialize(bad); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	d, _ := New(MinCompression)
+	for _, v := range stream(1, 100) {
+		d.Add(v)
+	}
+	buf := make([]byte, d.ByteSize())
+	d.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:headerSize])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		out := make([]byte, d.ByteSize())
+		if err := d.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+		if d.N > 0 {
+			x, err := d.Quantile(0.5)
+			if err != nil || x < d.Min || x > d.Max {
+				t.Error(x, err)
+			}
+			if c := d.CDF(x); !(c >= 0 && c <= 1) {
+				t.Error(c)
+			}
+		}
+		d.Add(1)
+	})
+}
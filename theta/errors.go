@@ -0,0 +1,28 @@
+package theta
+
+import (
+	"errors"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrK is returned when k is not positive.
+	ErrK = errors.New("theta: k must be positive")
+	// ErrTooFewSketches is returned when a set operation is given fewer
+	// than 2 sketches.
+	ErrTooFewSketches = errors.New("theta: less than 2 sketches were given")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+func shortBuffer(need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: "Theta", Need: need, Have: have}
+}
+
+func corrupt(reason string) error {
+	return &datasketch.CorruptError{Sketch: "Theta", Reason: reason}
+}
@@ -0,0 +1,37 @@
+package theta
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(Sketch)
+	_ driver.Valuer = new(Sketch)
+)
+
+// Value implements driver.Valuer so a Sketch can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized Sketch preceded by
+// a tag identifying it as a Theta sketch.
+func (s *Sketch) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.Theta, s)
+}
+
+// Scan implements sql.Scanner, restoring a Sketch written by Value. Blobs
+// holding any other kind of sketch are rejected. The hash function set on
+// s, if any, is kept.
+func (s *Sketch) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.Theta, src)
+	if err != nil {
+		return err
+	}
+	other, err := Deserialize(buffer, WithHashFunc(s.hash))
+	if err != nil {
+		return err
+	}
+	*s = *other
+	return nil
+}
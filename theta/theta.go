@@ -0,0 +1,350 @@
+// Package theta implements the Theta sketch, a sample of the hashes of a
+// set made of the hashes below a threshold theta. Sketches of different
+// sets combine with union, intersection and difference into sketches of
+// the resulting sets, which can be combined and serialized in turn.
+//
+// Theta Sketch Framework:
+// https://datasketches.apache.org/docs/Theta/ThetaSketchFramework.html
+//
+// A Framework for Estimating Unions, Intersections and Differences:
+// https://arxiv.org/abs/1508.05930
+package theta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/hashfunction/murmur3"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Hash32 is a relaxed version of hash.Hash32
+type Hash32 = datasketch.Hash32
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Digester                     = new(Sketch)
+	_ datasketch.Mergeable[*Sketch]           = new(Sketch)
+	_ datasketch.CardinalityEstimator         = new(Sketch)
+	_ datasketch.SimilarityEstimator[*Sketch] = new(Sketch)
+	_ datasketch.Serializable                 = new(Sketch)
+)
+
+// HashFunc hashes data to the 64-bit hashes a Sketch samples.
+type HashFunc func(data []byte) uint64
+
+// Sketch keeps the distinct hashes below Theta, at most K of them, in
+// increasing order. Theta starts at math.MaxUint64 and is lowered
+// whenever more than K hashes would be kept.
+type Sketch struct {
+	K      int
+	Theta  uint64
+	Hashes []uint64
+
+	hash HashFunc
+}
+
+// Option configures a Sketch created by New or Deserialize.
+type Option func(*Sketch)
+
+// WithHashFunc makes DigestBytes, DigestString and DigestUint64 hash items
+// with f instead of murmur3.Sum64WithSeed. Sketches are only comparable
+// when their items were hashed with the same function.
+func WithHashFunc(f HashFunc) Option {
+	return func(s *Sketch) {
+		s.hash = f
+	}
+}
+
+// WithKey makes DigestBytes, DigestString and DigestUint64 hash items with
+// SipHash keyed with the secret k0 and k1, so an attacker who controls the
+// items cannot choose which of them are kept. Sketches are only comparable
+// when built with the same key.
+func WithKey(k0, k1 uint64) Option {
+	return WithHashFunc(mix.Keyed64(k0, k1))
+}
+
+// New returns a Sketch keeping at most k hashes. The relative error of its
+// count is about 1/sqrt(k).
+func New(k int, opts ...Option) (*Sketch, error) {
+	if k <= 0 || k > math.MaxInt32 {
+		return nil, ErrK
+	}
+	s := &Sketch{K: k, Theta: math.MaxUint64}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Clear sets the Sketch back to its initial state.
+func (s *Sketch) Clear() {
+	s.Theta = math.MaxUint64
+	s.Hashes = s.Hashes[:0]
+}
+
+// Digest adds an item given by its 32-bit hash. The hash is spread over 64
+// bits, but items whose 32-bit hashes collide are still counted once, so
+// prefer DigestBytes or Digest64 for sets of more than a few million items.
+func (s *Sketch) Digest(item Hash32) {
+	s.Digest64(mix.Mix64(uint64(item.Sum32())))
+}
+
+// DigestBytes hashes data and adds the hash.
+func (s *Sketch) DigestBytes(data []byte) {
+	if s.hash != nil {
+		s.Digest64(s.hash(data))
+		return
+	}
+	s.Digest64(murmur3.Sum64WithSeed(data, 0))
+}
+
+// DigestString hashes the bytes of str and adds the hash.
+func (s *Sketch) DigestString(str string) {
+	if s.hash != nil {
+		s.Digest64(s.hash(unsafe.Slice(unsafe.StringData(str), len(str))))
+		return
+	}
+	s.Digest64(murmur3.Sum64String(str, 0))
+}
+
+// DigestUint64 hashes the little-endian encoding of v and adds the hash.
+func (s *Sketch) DigestUint64(v uint64) {
+	if s.hash != nil {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		s.Digest64(s.hash(b[:]))
+		return
+	}
+	s.Digest64(murmur3.Sum64Uint64(v, 0))
+}
+
+// Digest64 adds an item given by its 64-bit hash.
+func (s *Sketch) Digest64(h uint64) {
+	if h >= s.Theta {
+		return
+	}
+	n := len(s.Hashes)
+	i := sort.Search(n, func(i int) bool { return s.Hashes[i] >= h })
+	if i < n && s.Hashes[i] == h {
+		return
+	}
+	s.Hashes = append(s.Hashes, 0)
+	copy(s.Hashes[i+1:], s.Hashes[i:])
+	s.Hashes[i] = h
+	s.trim()
+}
+
+// trim lowers Theta to the smallest hash beyond the first K, and drops the
+// hashes not below it.
+func (s *Sketch) trim() {
+	if len(s.Hashes) > s.K {
+		s.Theta = s.Hashes[s.K]
+		s.Hashes = s.Hashes[:s.K]
+	}
+}
+
+// Count returns the estimated number of distinct items of the set the
+// sketch stands for: the number of hashes kept over the fraction of the
+// hash space below Theta. It is exact while Theta is math.MaxUint64.
+func (s *Sketch) Count() float64 {
+	if s.Theta == math.MaxUint64 {
+		return float64(len(s.Hashes))
+	}
+	return float64(len(s.Hashes)) / (float64(s.Theta) / (1 << 64))
+}
+
+// Merge takes another Sketch and combines it with s, making s the union of
+// both. s keeps its K.
+func (s *Sketch) Merge(other *Sketch) error {
+	theta := min64(s.Theta, other.Theta)
+	s.Hashes = merge(below(s.Hashes, theta), below(other.Hashes, theta))
+	s.Theta = theta
+	s.trim()
+	return nil
+}
+
+// Jaccard estimates the Jaccard similarity between s and other: the
+// fraction of the hashes of their union that both kept.
+func (s *Sketch) Jaccard(other *Sketch) (float64, error) {
+	u, err := Union(s, other)
+	if err != nil {
+		return 0.0, err
+	}
+	if len(u.Hashes) == 0 {
+		return 0.0, nil
+	}
+	i, err := Intersection(s, other)
+	if err != nil {
+		return 0.0, err
+	}
+	return float64(len(below(i.Hashes, u.Theta))) / float64(len(u.Hashes)), nil
+}
+
+// Union returns a new Sketch of the union of sketches. It keeps the
+// largest K of sketches.
+func Union(sketches ...*Sketch) (*Sketch, error) {
+	if len(sketches) < 2 {
+		return nil, ErrTooFewSketches
+	}
+	u := combined(sketches)
+	for _, s := range sketches {
+		u.Merge(s)
+	}
+	return u, nil
+}
+
+// Intersection returns a new Sketch of the intersection of sketches: the
+// hashes below the smallest Theta kept by every sketch. Its relative error
+// grows as the intersection gets small compared with the union.
+func Intersection(sketches ...*Sketch) (*Sketch, error) {
+	if len(sketches) < 2 {
+		return nil, ErrTooFewSketches
+	}
+	r := combined(sketches)
+	for _, s := range sketches {
+		r.Theta = min64(r.Theta, s.Theta)
+	}
+	for _, h := range below(sketches[0].Hashes, r.Theta) {
+		all := true
+		for _, s := range sketches[1:] {
+			if !contains(s.Hashes, h) {
+				all = false
+				break
+			}
+		}
+		if all {
+			r.Hashes = append(r.Hashes, h)
+		}
+	}
+	return r, nil
+}
+
+// ANotB returns a new Sketch of the items of a that are not items of b.
+func ANotB(a, b *Sketch) *Sketch {
+	r := combined([]*Sketch{a, b})
+	r.Theta = min64(a.Theta, b.Theta)
+	for _, h := range below(a.Hashes, r.Theta) {
+		if !contains(b.Hashes, h) {
+			r.Hashes = append(r.Hashes, h)
+		}
+	}
+	return r
+}
+
+// combined returns an empty Sketch with the largest K and the hash
+// function of sketches.
+func combined(sketches []*Sketch) *Sketch {
+	r := &Sketch{Theta: math.MaxUint64, hash: sketches[0].hash}
+	for _, s := range sketches {
+		if s.K > r.K {
+			r.K = s.K
+		}
+	}
+	return r
+}
+
+// below returns the prefix of the sorted hashes below theta.
+func below(hashes []uint64, theta uint64) []uint64 {
+	return hashes[:sort.Search(len(hashes), func(i int) bool {
+		return hashes[i] >= theta
+	})]
+}
+
+func contains(hashes []uint64, h uint64) bool {
+	i := sort.Search(len(hashes), func(i int) bool { return hashes[i] >= h })
+	return i < len(hashes) && hashes[i] == h
+}
+
+// merge returns the distinct hashes of the sorted a and b, in order.
+func merge(a, b []uint64) []uint64 {
+	u := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			u = append(u, a[0])
+			a = a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			u = append(u, b[0])
+			b = b[1:]
+		default:
+			u = append(u, a[0])
+			a, b = a[1:], b[1:]
+		}
+	}
+	return u
+}
+
+func min64(x, y uint64) uint64 {
+	if y < x {
+		return y
+	}
+	return x
+}
+
+// ByteSize returns the size of the serialized object.
+func (s *Sketch) ByteSize() int {
+	return 4 + 4 + 8 + 8*len(s.Hashes)
+}
+
+// Serialize the Sketch to bytes stored in buffer: K, the number of hashes
+// kept, Theta and the hashes in increasing order.
+func (s *Sketch) Serialize(buffer []byte) error {
+	if len(buffer) < s.ByteSize() {
+		return shortBuffer(s.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	b.PutUint32(buffer, uint32(s.K))
+	b.PutUint32(buffer[4:], uint32(len(s.Hashes)))
+	b.PutUint64(buffer[8:], s.Theta)
+	offset := 16
+	for _, h := range s.Hashes {
+		b.PutUint64(buffer[offset:], h)
+		offset += 8
+	}
+	return nil
+}
+
+// Deserialize reconstructs a Sketch from the buffer
+func Deserialize(buffer []byte, opts ...Option) (*Sketch, error) {
+	if len(buffer) < 16 {
+		return nil, shortBuffer(16, len(buffer))
+	}
+	b := binary.LittleEndian
+	k := b.Uint32(buffer)
+	n := b.Uint32(buffer[4:])
+	if k == 0 || k > math.MaxInt32 {
+		return nil, corrupt(fmt.Sprintf("k %d is out of range", k))
+	}
+	if n > k {
+		return nil, corrupt(fmt.Sprintf("%d hashes are more than k %d", n, k))
+	}
+	if need := 16 + 8*uint64(n); uint64(len(buffer)) < need {
+		return nil, shortBuffer(int(need), len(buffer))
+	}
+	s, err := New(int(k), opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.Theta = b.Uint64(buffer[8:])
+	if s.Theta == 0 {
+		return nil, corrupt("theta is 0")
+	}
+	s.Hashes = make([]uint64, n)
+	offset := 16
+	for i := range s.Hashes {
+		s.Hashes[i] = b.Uint64(buffer[offset:])
+		if i > 0 && s.Hashes[i] <= s.Hashes[i-1] {
+			return nil, corrupt("hashes are not in increasing order")
+		}
+		offset += 8
+	}
+	if n > 0 && s.Hashes[n-1] >= s.Theta {
+		return nil, corrupt("hashes are not below theta")
+	}
+	return s, nil
+}
@@ -0,0 +1,233 @@
+package theta
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type fakeHash32 uint32
+
+func (f fakeHash32) Sum32() uint32 { return uint32(f) }
+
+func digestRange(s *Sketch, from, to uint64) {
+	for i := from; i < to; i++ {
+		s.DigestUint64(i)
+	}
+}
+
+func TestThetaDigest(t *testing.T) {
+	s, _ := New(4)
+	for _, h := range []uint64{50, 10, 40, 10, 30, 20, 60, 5} {
+		s.Digest64(h)
+	}
+	want := []uint64{5, 10, 20, 30}
+	if len(s.Hashes) != len(want) || s.Theta != 40 {
+		t.Fatal(s.Hashes, s.Theta)
+	}
+	for i := range want {
+		if s.Hashes[i] != want[i] {
+			t.Fatal(s.Hashes)
+		}
+	}
+	s.Clear()
+	if s.Count() != 0 || s.Theta != math.MaxUint64 {
+		t.Error(s.Count(), s.Theta)
+	}
+}
+
+func TestThetaCount(t *testing.T) {
+	s, _ := New(1024)
+	for i := 0; i < 2; i++ {
+		digestRange(s, 0, 1000)
+	}
+	if s.Count() != 1000 {
+		t.Errorf("exact count %v (want 1000)", s.Count())
+	}
+	for _, n := range []uint64{10000, 1000000} {
+		s.Clear()
+		digestRange(s, 0, n)
+		if err := math.Abs(s.Count()-float64(n)) / float64(n); err > 4/math.Sqrt(1024) {
+			t.Errorf("count %v (want %v)", s.Count(), n)
+		}
+	}
+}
+
+func TestThetaSetOperations(t *testing.T) {
+	const k = 4096
+	a, _ := New(k)
+	b, _ := New(k)
+	c, _ := New(k)
+	// a holds [0, 100000), b [90000, 200000) and c [0, 95000): a and b
+	// share 10000 items, all three 5000.
+	digestRange(a, 0, 100000)
+	digestRange(b, 90000, 200000)
+	digestRange(c, 0, 95000)
+	tol := 4 / math.Sqrt(k)
+
+	u, err := Union(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := math.Abs(u.Count()-200000) / 200000; err > tol {
+		t.Errorf("union count %v (want 200000)", u.Count())
+	}
+	i, err := Intersection(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The intersection is estimated from the hashes of the union, its
+	// relative error is sqrt(|union|/|intersection|) times larger.
+	if err := math.Abs(i.Count()-10000) / 10000; err > tol*math.Sqrt(20) {
+		t.Errorf("intersection count %v (want 10000)", i.Count())
+	}
+	d := ANotB(a, b)
+	if err := math.Abs(d.Count()-90000) / 90000; err > tol*math.Sqrt(2) {
+		t.Errorf("difference count %v (want 90000)", d.Count())
+	}
+	if j, _ := a.Jaccard(b); math.Abs(j-0.05) > tol {
+		t.Errorf("Jaccard %v (want 0.05)", j)
+	}
+
+	// The results combine further.
+	i3, _ := Intersection(i, c)
+	if err := math.Abs(i3.Count()-5000) / 5000; err > tol*math.Sqrt(40) {
+		t.Errorf("intersection of 3 count %v (want 5000)", i3.Count())
+	}
+	all, _ := Union(i, d, ANotB(b, a))
+	if err := math.Abs(all.Count()-200000) / 200000; err > tol {
+		t.Errorf("union of the parts count %v (want 200000)", all.Count())
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := a.Jaccard(u); j != 1.0 {
+		t.Error(j)
+	}
+}
+
+func TestThetaExactSetOperations(t *testing.T) {
+	a, _ := New(1024)
+	b, _ := New(1024)
+	digestRange(a, 0, 300)
+	digestRange(b, 200, 500)
+	u, _ := Union(a, b)
+	i, _ := Intersection(a, b)
+	d := ANotB(a, b)
+	if u.Count() != 500 || i.Count() != 100 || d.Count() != 200 {
+		t.Error(u.Count(), i.Count(), d.Count())
+	}
+	if j, _ := a.Jaccard(b); j != 0.2 {
+		t.Error(j)
+	}
+	e, _ := New(8)
+	if j, _ := e.Jaccard(e); j != 0 {
+		t.Error(j)
+	}
+}
+
+func TestThetaError(t *testing.T) {
+	if _, err := New(0); !errors.Is(err, ErrK) {
+		t.Error(err)
+	}
+	a, _ := New(8)
+	if _, err := Union(a); !errors.Is(err, ErrTooFewSketches) {
+		t.Error(err)
+	}
+	if _, err := Intersection(a); !errors.Is(err, ErrTooFewSketches) {
+		t.Error(err)
+	}
+}
+
+func TestThetaDigestTyped(t *testing.T) {
+	a, _ := New(16)
+	b, _ := New(16)
+	a.DigestString("hello")
+	a.DigestUint64(42)
+	a.Digest(fakeHash32(7))
+	b.DigestBytes([]byte("hello"))
+	b.DigestUint64(42)
+	b.Digest(fakeHash32(7))
+	if j, _ := a.Jaccard(b); j != 1.0 {
+		t.Error(j)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		a.DigestString("hello")
+		a.DigestUint64(42)
+	}); n != 0 {
+		t.Errorf("%v allocations (want 0)", n)
+	}
+	c, _ := New(16, WithKey(1, 2))
+	c.DigestString("hello")
+	if c.Hashes[0] == a.Hashes[0] || c.Hashes[0] == a.Hashes[1] {
+		t.Error("WithKey did not change the hash")
+	}
+}
+
+func TestThetaSerialization(t *testing.T) {
+	s, _ := New(16)
+	digestRange(s, 0, 100)
+	buf := make([]byte, s.ByteSize())
+	if err := s.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Deserialize(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.K != s.K || d.Theta != s.Theta || d.Count() != s.Count() {
+		t.Error("Did not get back the same Sketch")
+	}
+	if err := s.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := Deserialize(buf[:len(buf)-1]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	theta := s.Theta
+	s.Theta = s.Hashes[len(s.Hashes)-1]
+	s.Serialize(buf)
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	s.Theta = theta
+	s.Serialize(buf)
+	copy(buf[24:32], buf[16:24])
+	if _, err := Deserialize(buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	empty, _ := New(4)
+	empty.Theta = 0
+	empty.Serialize(buf)
+	if _, err := Deserialize(buf[:16]); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserialize(f *testing.F) {
+	s, _ := New(4)
+	digestRange(s, 0, 10)
+	buf := make([]byte, s.ByteSize())
+	s.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Add(buf[:16])
+	// An empty sketch of k 4 whose Theta is 0.
+	f.Add(append([]byte{4, 0, 0, 0}, make([]byte, 12)...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := Deserialize(data)
+		if err != nil {
+			return
+		}
+		if c := s.Count(); math.IsNaN(c) {
+			t.Error("Count is NaN")
+		}
+		out := make([]byte, s.ByteSize())
+		if err := s.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package xorfilter
+
+import (
+	"errors"
+
+	"github.com/ekzhu/go-datasketch"
+)
+
+var (
+	// ErrTooManyHashes is returned when a filter is built from more
+	// hashes than it can index.
+	ErrTooManyHashes = errors.New("xorfilter: too many hashes")
+	// ErrConstruction is returned when no seed lets the hashes be
+	// assigned to distinct slots, which only happens by extreme bad luck.
+	ErrConstruction = errors.New("xorfilter: construction failed")
+
+	// Errors shared with the other sketches, see package datasketch.
+	ErrShortBuffer = datasketch.ErrShortBuffer
+	ErrCorrupt     = datasketch.ErrCorrupt
+	ErrWrongSketch = datasketch.ErrWrongSketch
+)
+
+func shortBuffer(sketch string, need, have int) error {
+	return &datasketch.ShortBufferError{Sketch: sketch, Need: need, Have: have}
+}
+
+func corrupt(sketch, reason string) error {
+	return &datasketch.CorruptError{Sketch: sketch, Reason: reason}
+}
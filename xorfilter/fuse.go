@@ -0,0 +1,165 @@
+package xorfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Serializable = new(BinaryFuse8)
+	_ datasketch.Serializable = new(BinaryFuse16)
+)
+
+// maxSegmentLength bounds the length of the segments of a BinaryFuse,
+// which barely shrinks filters beyond it.
+const maxSegmentLength = 1 << 18
+
+// BinaryFuse is a binary fuse filter. Fingerprints holds SegmentCount+2
+// segments of SegmentLength fingerprints, and a hash is in the set if its
+// fingerprint is the xor of one fingerprint in each of three consecutive
+// segments. Locating the three slots close to each other lets the
+// filter be smaller than an Xor, and faster to build.
+type BinaryFuse[T Fingerprint] struct {
+	Seed          uint64
+	SegmentLength uint32
+	SegmentCount  uint32
+	Fingerprints  []T
+}
+
+// BinaryFuse8 is a binary fuse filter with a false positive rate of 2^-8,
+// taking from about 9.4 bits per item for 100,000 items down to 9 for
+// billions.
+type BinaryFuse8 = BinaryFuse[uint8]
+
+// BinaryFuse16 is a binary fuse filter with a false positive rate of
+// 2^-16, taking twice the space of a BinaryFuse8.
+type BinaryFuse16 = BinaryFuse[uint16]
+
+// NewBinaryFuse returns the BinaryFuse filter of the set of hashes.
+// Duplicate hashes are allowed.
+func NewBinaryFuse[T Fingerprint](hashes []uint64) (*BinaryFuse[T], error) {
+	if len(hashes) > maxHashes {
+		return nil, ErrTooManyHashes
+	}
+	n := float64(len(hashes))
+	// The segment length and the size, by which the number of hashes is
+	// multiplied, are the ones found best in the paper for 3 slots.
+	f := &BinaryFuse[T]{SegmentLength: 4}
+	if len(hashes) > 1 {
+		f.SegmentLength = 1 << int(math.Floor(math.Log(n)/math.Log(3.33)+
+			2.25))
+		if f.SegmentLength > maxSegmentLength {
+			f.SegmentLength = maxSegmentLength
+		}
+	}
+	var capacity float64
+	if len(hashes) > 1 {
+		capacity = math.Round(n * math.Max(1.125,
+			0.875+0.25*math.Log(1000000)/math.Log(n)))
+	}
+	f.SegmentCount = 1
+	if segments := uint32(math.Ceil(capacity /
+		float64(f.SegmentLength))); segments > 2 {
+		f.SegmentCount = segments - 2
+	}
+	seed, fingerprints, err := build[T](hashes, f.size(), f.locate)
+	if err != nil {
+		return nil, err
+	}
+	f.Seed, f.Fingerprints = seed, fingerprints
+	return f, nil
+}
+
+// size returns the number of fingerprints of f.
+func (f *BinaryFuse[T]) size() uint32 {
+	return (f.SegmentCount + 2) * f.SegmentLength
+}
+
+// locate returns the slots of the mixed hash h, one in each of three
+// consecutive segments.
+func (f *BinaryFuse[T]) locate(h uint64) [3]uint32 {
+	hi, _ := bits.Mul64(h, uint64(f.SegmentCount*f.SegmentLength))
+	h0 := uint32(hi)
+	h1 := h0 + f.SegmentLength
+	h2 := h1 + f.SegmentLength
+	mask := f.SegmentLength - 1
+	return [3]uint32{h0, h1 ^ uint32(h>>18)&mask, h2 ^ uint32(h)&mask}
+}
+
+// Contains reports whether hash may be in the set. It is certainly not if
+// Contains returns false.
+func (f *BinaryFuse[T]) Contains(hash uint64) bool {
+	h := mix.Mix64(hash + f.Seed)
+	s := f.locate(h)
+	return fingerprint[T](h) == f.Fingerprints[s[0]]^f.Fingerprints[s[1]]^
+		f.Fingerprints[s[2]]
+}
+
+// fuseHeaderSize is the size of the serialized BinaryFuse before its
+// fingerprints.
+const fuseHeaderSize = 1 + 8 + 4 + 4
+
+// ByteSize returns the size of the BinaryFuse f in bytes
+func (f *BinaryFuse[T]) ByteSize() int {
+	return fuseHeaderSize + len(f.Fingerprints)*int(fingerprintBits[T]()/8)
+}
+
+// Serialize the BinaryFuse f into bytes and store in the buffer: the size
+// of the fingerprints in bits, Seed, SegmentLength, SegmentCount, then
+// the fingerprints in little-endian order.
+func (f *BinaryFuse[T]) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer("BinaryFuse", f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	buffer[0] = fingerprintBits[T]()
+	b.PutUint64(buffer[1:], f.Seed)
+	b.PutUint32(buffer[9:], f.SegmentLength)
+	b.PutUint32(buffer[13:], f.SegmentCount)
+	putFingerprints(buffer[fuseHeaderSize:], f.Fingerprints)
+	return nil
+}
+
+// DeserializeBinaryFuse reconstruct a BinaryFuse from the buffer. The
+// fingerprints must be of type T.
+func DeserializeBinaryFuse[T Fingerprint](buffer []byte) (*BinaryFuse[T],
+	error) {
+	const sketch = "BinaryFuse"
+	if len(buffer) < fuseHeaderSize {
+		return nil, shortBuffer(sketch, fuseHeaderSize, len(buffer))
+	}
+	if buffer[0] != fingerprintBits[T]() {
+		return nil, corrupt(sketch, fmt.Sprintf("fingerprints have %d bits, "+
+			"not %d", buffer[0], fingerprintBits[T]()))
+	}
+	b := binary.LittleEndian
+	f := &BinaryFuse[T]{
+		Seed:          b.Uint64(buffer[1:]),
+		SegmentLength: b.Uint32(buffer[9:]),
+		SegmentCount:  b.Uint32(buffer[13:]),
+	}
+	if f.SegmentLength < 4 || f.SegmentLength > maxSegmentLength ||
+		f.SegmentLength&(f.SegmentLength-1) != 0 {
+		return nil, corrupt(sketch, fmt.Sprintf("segment length %d is not "+
+			"a power of 2 in range", f.SegmentLength))
+	}
+	if f.SegmentCount == 0 ||
+		(uint64(f.SegmentCount)+2)*uint64(f.SegmentLength) > maxSlots {
+		return nil, corrupt(sketch, fmt.Sprintf("%d segments is out of range",
+			f.SegmentCount))
+	}
+	size := int(f.size())
+	need := fuseHeaderSize + size*int(fingerprintBits[T]()/8)
+	if len(buffer) < need {
+		return nil, shortBuffer(sketch, need, len(buffer))
+	}
+	f.Fingerprints = make([]T, size)
+	getFingerprints(buffer[fuseHeaderSize:], f.Fingerprints)
+	return f, nil
+}
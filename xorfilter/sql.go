@@ -0,0 +1,62 @@
+package xorfilter
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/ekzhu/go-datasketch/internal/envelope"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ sql.Scanner   = new(Xor8)
+	_ driver.Valuer = new(Xor8)
+	_ sql.Scanner   = new(BinaryFuse8)
+	_ driver.Valuer = new(BinaryFuse8)
+)
+
+// Value implements driver.Valuer so an Xor can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized filter preceded
+// by a tag identifying it as an xor filter.
+func (f *Xor[T]) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.XorFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring an Xor written by Value. Blobs
+// holding any other kind of sketch, or fingerprints of another size, are
+// rejected.
+func (f *Xor[T]) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.XorFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := DeserializeXor[T](buffer)
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
+
+// Value implements driver.Valuer so a BinaryFuse can be stored in a binary
+// (BYTEA/BLOB) column. The stored value is the serialized filter preceded
+// by a tag identifying it as a binary fuse filter.
+func (f *BinaryFuse[T]) Value() (driver.Value, error) {
+	return envelope.Wrap(envelope.BinaryFuseFilter, f)
+}
+
+// Scan implements sql.Scanner, restoring a BinaryFuse written by Value.
+// Blobs holding any other kind of sketch, or fingerprints of another
+// size, are rejected.
+func (f *BinaryFuse[T]) Scan(src interface{}) error {
+	buffer, err := envelope.Unwrap(envelope.BinaryFuseFilter, src)
+	if err != nil {
+		return err
+	}
+	other, err := DeserializeBinaryFuse[T](buffer)
+	if err != nil {
+		return err
+	}
+	*f = *other
+	return nil
+}
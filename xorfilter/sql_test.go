@@ -0,0 +1,53 @@
+package xorfilter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ekzhu/go-datasketch/hyperloglog"
+)
+
+func TestXorValueScan(t *testing.T) {
+	hashes := randomHashes(1, 100)
+	f, _ := NewXor[uint8](hashes)
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Xor8
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Seed != f.Seed || !d.Contains(hashes[0]) {
+		t.Error("Did not get back the same Xor")
+	}
+	var other Xor16
+	if err := other.Scan(v); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	var fuse BinaryFuse8
+	if err := fuse.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
+
+func TestBinaryFuseValueScan(t *testing.T) {
+	hashes := randomHashes(1, 100)
+	f, _ := NewBinaryFuse[uint16](hashes)
+	v, err := f.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d BinaryFuse16
+	if err := d.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if d.Seed != f.Seed || !d.Contains(hashes[0]) {
+		t.Error("Did not get back the same BinaryFuse")
+	}
+	h, _ := hyperloglog.New(4)
+	v, _ = h.Value()
+	if err := d.Scan(v); !errors.Is(err, ErrWrongSketch) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,126 @@
+package xorfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/ekzhu/go-datasketch"
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Make sure interfaces are correctly implemented.
+var (
+	_ datasketch.Serializable = new(Xor8)
+	_ datasketch.Serializable = new(Xor16)
+)
+
+// Xor is an xor filter. Fingerprints holds three blocks of BlockLength
+// fingerprints, and a hash is in the set if its fingerprint is the xor of
+// one fingerprint in each block.
+type Xor[T Fingerprint] struct {
+	Seed         uint64
+	BlockLength  uint32
+	Fingerprints []T
+}
+
+// Xor8 is an xor filter with a false positive rate of 2^-8, taking about
+// 9.84 bits per item.
+type Xor8 = Xor[uint8]
+
+// Xor16 is an xor filter with a false positive rate of 2^-16, taking
+// about 19.7 bits per item.
+type Xor16 = Xor[uint16]
+
+// NewXor returns the Xor filter of the set of hashes. Duplicate hashes
+// are allowed.
+func NewXor[T Fingerprint](hashes []uint64) (*Xor[T], error) {
+	if len(hashes) > maxHashes {
+		return nil, ErrTooManyHashes
+	}
+	// The 1.23 factor lets the hashes be peeled with high probability.
+	size := 32 + math.Ceil(1.23*float64(len(hashes)))
+	f := &Xor[T]{BlockLength: uint32(size) / 3}
+	seed, fingerprints, err := build[T](hashes, 3*f.BlockLength, f.locate)
+	if err != nil {
+		return nil, err
+	}
+	f.Seed, f.Fingerprints = seed, fingerprints
+	return f, nil
+}
+
+// locate returns the slot of the mixed hash h in each block.
+func (f *Xor[T]) locate(h uint64) [3]uint32 {
+	return [3]uint32{
+		reduce(uint32(h), f.BlockLength),
+		reduce(uint32(bits.RotateLeft64(h, 21)), f.BlockLength) +
+			f.BlockLength,
+		reduce(uint32(bits.RotateLeft64(h, 42)), f.BlockLength) +
+			2*f.BlockLength,
+	}
+}
+
+// reduce maps h to [0, n) fairly without a division.
+func reduce(h, n uint32) uint32 {
+	return uint32(uint64(h) * uint64(n) >> 32)
+}
+
+// Contains reports whether hash may be in the set. It is certainly not if
+// Contains returns false.
+func (f *Xor[T]) Contains(hash uint64) bool {
+	h := mix.Mix64(hash + f.Seed)
+	s := f.locate(h)
+	return fingerprint[T](h) == f.Fingerprints[s[0]]^f.Fingerprints[s[1]]^
+		f.Fingerprints[s[2]]
+}
+
+// xorHeaderSize is the size of the serialized Xor before its fingerprints.
+const xorHeaderSize = 1 + 8 + 4
+
+// ByteSize returns the size of the Xor f in bytes
+func (f *Xor[T]) ByteSize() int {
+	return xorHeaderSize + len(f.Fingerprints)*int(fingerprintBits[T]()/8)
+}
+
+// Serialize the Xor f into bytes and store in the buffer: the size of the
+// fingerprints in bits, Seed, BlockLength, then the fingerprints in
+// little-endian order.
+func (f *Xor[T]) Serialize(buffer []byte) error {
+	if len(buffer) < f.ByteSize() {
+		return shortBuffer("Xor", f.ByteSize(), len(buffer))
+	}
+	b := binary.LittleEndian
+	buffer[0] = fingerprintBits[T]()
+	b.PutUint64(buffer[1:], f.Seed)
+	b.PutUint32(buffer[9:], f.BlockLength)
+	putFingerprints(buffer[xorHeaderSize:], f.Fingerprints)
+	return nil
+}
+
+// DeserializeXor reconstruct an Xor from the buffer. The fingerprints
+// must be of type T.
+func DeserializeXor[T Fingerprint](buffer []byte) (*Xor[T], error) {
+	const sketch = "Xor"
+	if len(buffer) < xorHeaderSize {
+		return nil, shortBuffer(sketch, xorHeaderSize, len(buffer))
+	}
+	if buffer[0] != fingerprintBits[T]() {
+		return nil, corrupt(sketch, fmt.Sprintf("fingerprints have %d bits, "+
+			"not %d", buffer[0], fingerprintBits[T]()))
+	}
+	b := binary.LittleEndian
+	f := &Xor[T]{Seed: b.Uint64(buffer[1:]), BlockLength: b.Uint32(buffer[9:])}
+	if f.BlockLength == 0 || 3*uint64(f.BlockLength) > maxSlots {
+		return nil, corrupt(sketch, fmt.Sprintf("block length %d is out of "+
+			"range", f.BlockLength))
+	}
+	size := 3 * int(f.BlockLength)
+	need := xorHeaderSize + size*int(fingerprintBits[T]()/8)
+	if len(buffer) < need {
+		return nil, shortBuffer(sketch, need, len(buffer))
+	}
+	f.Fingerprints = make([]T, size)
+	getFingerprints(buffer[xorHeaderSize:], f.Fingerprints)
+	return f, nil
+}
@@ -0,0 +1,165 @@
+// Package xorfilter implements static filters, which test whether an item
+// is in a set fixed when the filter is built, with no false negatives and
+// a false positive rate of 2^-8 or 2^-16. They take less space than Bloom
+// and cuckoo filters: about 9.84 bits per item for an Xor8, and down to 9
+// for a large BinaryFuse8, against 11.5 for a Bloom filter with the same
+// false positive rate.
+//
+// Filters are built from 64-bit hashes of the items rather than the items
+// themselves, e.g. from murmur3.Sum64, and queried with the hash of the
+// item looked up.
+//
+// Xor Filters: Faster and Smaller Than Bloom and Cuckoo Filters:
+// https://arxiv.org/abs/1912.08258
+//
+// Binary Fuse Filters: Fast and Smaller Than Xor Filters:
+// https://arxiv.org/abs/2201.01174
+package xorfilter
+
+import (
+	"encoding/binary"
+	"sort"
+	"unsafe"
+
+	"github.com/ekzhu/go-datasketch/internal/mix"
+)
+
+// Fingerprint is the type of the fingerprints stored by a filter. Its
+// size sets the false positive rate of the filter.
+type Fingerprint interface {
+	uint8 | uint16
+}
+
+const (
+	// maxHashes bounds the number of hashes so that slots are indexed
+	// with 32 bits.
+	maxHashes = 1 << 30
+	// maxSlots bounds the number of slots of a deserialized filter.
+	maxSlots = 1 << 31
+	// maxIterations is the number of seeds tried before giving up.
+	maxIterations = 100
+)
+
+// fingerprint returns the fingerprint of the mixed hash h.
+func fingerprint[T Fingerprint](h uint64) T {
+	return T(h ^ h>>32)
+}
+
+// fingerprintBits returns the size of T in bits.
+func fingerprintBits[T Fingerprint]() uint8 {
+	var zero T
+	return uint8(8 * unsafe.Sizeof(zero))
+}
+
+// build returns the seed and the fingerprints of a filter of size slots,
+// in which the fingerprint of every hash, mixed with the seed, is the xor
+// of the fingerprints of the three slots returned by locate.
+//
+// It peels the hashes one by one from slots that only they use, then
+// assigns the slots in the opposite order, each hash getting the slot it
+// was peeled from.
+func build[T Fingerprint](hashes []uint64, size uint32,
+	locate func(h uint64) [3]uint32) (uint64, []T, error) {
+	count := make([]uint32, size)
+	xor := make([]uint64, size)
+	queue := make([]uint32, 0, size)
+	stack := make([]uint32, 0, len(hashes))
+	deduplicated := false
+	for i := 0; i < maxIterations; i++ {
+		seed := mix.Mix64(uint64(i) * 0x9e3779b97f4a7c15)
+		for j := range count {
+			count[j], xor[j] = 0, 0
+		}
+		for _, key := range hashes {
+			h := mix.Mix64(key + seed)
+			for _, p := range locate(h) {
+				count[p]++
+				xor[p] ^= h
+			}
+		}
+		queue, stack = queue[:0], stack[:0]
+		for p, c := range count {
+			if c == 1 {
+				queue = append(queue, uint32(p))
+			}
+		}
+		for len(queue) > 0 {
+			p := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if count[p] != 1 {
+				continue
+			}
+			// The hash left in p is peeled. xor[p] keeps it, as removing
+			// it from p below clears the count but not the xor.
+			h := xor[p]
+			stack = append(stack, p)
+			for _, q := range locate(h) {
+				count[q]--
+				if q != p {
+					xor[q] ^= h
+				}
+				if count[q] == 1 {
+					queue = append(queue, q)
+				}
+			}
+		}
+		if len(stack) == len(hashes) {
+			fingerprints := make([]T, size)
+			for j := len(stack) - 1; j >= 0; j-- {
+				p := stack[j]
+				h := xor[p]
+				s := locate(h)
+				fingerprints[p] = fingerprint[T](h) ^ fingerprints[s[0]] ^
+					fingerprints[s[1]] ^ fingerprints[s[2]]
+			}
+			return seed, fingerprints, nil
+		}
+		// Duplicate hashes can never be peeled, as they share all their
+		// slots.
+		if !deduplicated {
+			hashes = deduplicate(hashes)
+			deduplicated = true
+		}
+	}
+	return 0, nil, ErrConstruction
+}
+
+// deduplicate returns a sorted copy of hashes without duplicates.
+func deduplicate(hashes []uint64) []uint64 {
+	sorted := append([]uint64(nil), hashes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := 0
+	for i, h := range sorted {
+		if i == 0 || h != sorted[n-1] {
+			sorted[n] = h
+			n++
+		}
+	}
+	return sorted[:n]
+}
+
+// putFingerprints writes fingerprints in little-endian order.
+func putFingerprints[T Fingerprint](buffer []byte, fingerprints []T) {
+	if fingerprintBits[T]() == 8 {
+		for i, fp := range fingerprints {
+			buffer[i] = uint8(fp)
+		}
+		return
+	}
+	for i, fp := range fingerprints {
+		binary.LittleEndian.PutUint16(buffer[2*i:], uint16(fp))
+	}
+}
+
+// getFingerprints reads the fingerprints written by putFingerprints.
+func getFingerprints[T Fingerprint](buffer []byte, fingerprints []T) {
+	if fingerprintBits[T]() == 8 {
+		for i := range fingerprints {
+			fingerprints[i] = T(buffer[i])
+		}
+		return
+	}
+	for i := range fingerprints {
+		fingerprints[i] = T(binary.LittleEndian.Uint16(buffer[2*i:]))
+	}
+}
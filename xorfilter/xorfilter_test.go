@@ -0,0 +1,210 @@
+package xorfilter
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomHashes returns n random hashes.
+func randomHashes(seed int64, n int) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+	hashes := make([]uint64, n)
+	for i := range hashes {
+		hashes[i] = r.Uint64()
+	}
+	return hashes
+}
+
+// filter is implemented by all filters of the package.
+type filter interface {
+	Contains(hash uint64) bool
+	ByteSize() int
+}
+
+func TestFilters(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 10, 1000, 100000} {
+		hashes := randomHashes(int64(n), n)
+		x8, err := NewXor[uint8](hashes)
+		if err != nil {
+			t.Fatal(n, err)
+		}
+		x16, err := NewXor[uint16](hashes)
+		if err != nil {
+			t.Fatal(n, err)
+		}
+		b8, err := NewBinaryFuse[uint8](hashes)
+		if err != nil {
+			t.Fatal(n, err)
+		}
+		b16, err := NewBinaryFuse[uint16](hashes)
+		if err != nil {
+			t.Fatal(n, err)
+		}
+		for _, c := range []struct {
+			name   string
+			f      filter
+			fpRate float64
+			bits   float64
+		}{
+			{"Xor8", x8, 1.0 / 256, 8 * 1.24},
+			{"Xor16", x16, 1.0 / 65536, 16 * 1.24},
+			{"BinaryFuse8", b8, 1.0 / 256, 8 * 1.2},
+			{"BinaryFuse16", b16, 1.0 / 65536, 16 * 1.2},
+		} {
+			for _, h := range hashes {
+				if !c.f.Contains(h) {
+					t.Fatalf("%s of %d: %x is a false negative", c.name, n, h)
+				}
+			}
+			if n < 100000 {
+				continue
+			}
+			var fp int
+			for _, h := range randomHashes(-1, 1000000) {
+				if c.f.Contains(h) {
+					fp++
+				}
+			}
+			if rate := float64(fp) / 1000000; math.Abs(rate-c.fpRate) >
+				0.1*c.fpRate+1e-5 {
+				t.Errorf("%s: false positive rate %v", c.name, rate)
+			}
+			if bits := float64(8*c.f.ByteSize()) / float64(n); bits > c.bits {
+				t.Errorf("%s: %v bits per item", c.name, bits)
+			}
+		}
+	}
+}
+
+func TestFiltersDuplicates(t *testing.T) {
+	hashes := randomHashes(1, 1000)
+	hashes = append(hashes, hashes[:500]...)
+	x, err := NewXor[uint8](hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBinaryFuse[uint16](hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hashes {
+		if !x.Contains(h) || !b.Contains(h) {
+			t.Fatalf("%x is a false negative", h)
+		}
+	}
+	if b.SegmentLength != 256 || b.SegmentCount != 6 {
+		t.Error(b.SegmentLength, b.SegmentCount)
+	}
+}
+
+func TestXorSerialization(t *testing.T) {
+	hashes := randomHashes(1, 100)
+	f, _ := NewXor[uint16](hashes)
+	buf := make([]byte, f.ByteSize())
+	if err := f.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeXor[uint16](buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Seed != f.Seed || d.BlockLength != f.BlockLength ||
+		!d.Contains(hashes[0]) {
+		t.Error("Did not get back the same Xor")
+	}
+	if _, err := DeserializeXor[uint8](buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := DeserializeXor[uint16](buf[:len(buf)-1]); !errors.Is(err,
+		ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[9], buf[10], buf[11], buf[12] = 0, 0, 0, 0
+	if _, err := DeserializeXor[uint16](buf); !errors.Is(err, ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func TestBinaryFuseSerialization(t *testing.T) {
+	hashes := randomHashes(1, 100)
+	f, _ := NewBinaryFuse[uint8](hashes)
+	buf := make([]byte, f.ByteSize())
+	if err := f.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := DeserializeBinaryFuse[uint8](buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Seed != f.Seed || d.SegmentLength != f.SegmentLength ||
+		d.SegmentCount != f.SegmentCount || !d.Contains(hashes[0]) {
+		t.Error("Did not get back the same BinaryFuse")
+	}
+	if _, err := DeserializeBinaryFuse[uint16](buf); !errors.Is(err,
+		ErrCorrupt) {
+		t.Error(err)
+	}
+	if err := f.Serialize(buf[:4]); !errors.Is(err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	if _, err := DeserializeBinaryFuse[uint8](buf[:len(buf)-1]); !errors.Is(
+		err, ErrShortBuffer) {
+		t.Error(err)
+	}
+	buf[9] = 5
+	if _, err := DeserializeBinaryFuse[uint8](buf); !errors.Is(err,
+		ErrCorrupt) {
+		t.Error(err)
+	}
+}
+
+func FuzzDeserializeXor(f *testing.F) {
+	x, _ := NewXor[uint8](randomHashes(1, 10))
+	buf := make([]byte, x.ByteSize())
+	x.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		x, err := DeserializeXor[uint8](data)
+		if err != nil {
+			return
+		}
+		x.Contains(42)
+		out := make([]byte, x.ByteSize())
+		if err := x.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}
+
+func FuzzDeserializeBinaryFuse(f *testing.F) {
+	b, _ := NewBinaryFuse[uint16](randomHashes(1, 10))
+	buf := make([]byte, b.ByteSize())
+	b.Serialize(buf)
+	f.Add(buf)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := DeserializeBinaryFuse[uint16](data)
+		if err != nil {
+			return
+		}
+		for h := uint64(0); h < 100; h++ {
+			b.Contains(h * 0x9e3779b97f4a7c15)
+		}
+		out := make([]byte, b.ByteSize())
+		if err := b.Serialize(out); err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data[:len(out)]) {
+			t.Error("Serialize does not reproduce the decoded buffer")
+		}
+	})
+}